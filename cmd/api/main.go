@@ -35,6 +35,11 @@ func main() {
 		log.Fatalf("Failed to initialize router: %v", err)
 	}
 
+	// Expire, archive and evict interview sessions in the background
+	janitorCtx, stopJanitor := context.WithCancel(context.Background())
+	defer stopJanitor()
+	go interview.RunJanitor(janitorCtx, interview.JanitorConfigFromEnv())
+
 	handler := middleware.CORSLegacy(r)
 	srv := &http.Server{
 		Addr:         ":8080",
@@ -56,6 +61,7 @@ func main() {
 	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
 	<-quit
 	log.Println("Shutting down...")
+	stopJanitor()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
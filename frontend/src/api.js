@@ -120,13 +120,63 @@ export async function login(email, password) {
   }
 
   const data = await res.json();
+  if (mfaChallenge(data)) {
+    return data;
+  }
   // Store access token from response
-  if (data.access_token) {
-    setAccessToken(data.access_token);
+  const accessToken = data.data?.access_token ?? data.access_token;
+  if (accessToken) {
+    setAccessToken(accessToken);
   }
   return data;
 }
 
+// mfaChallenge returns the second factor challenge from a login response, or null
+// when the login already finished. Pass it to the login page's MFA step.
+export function mfaChallenge(data) {
+  const payload = data?.data ?? data;
+  return payload?.mfa_required ? payload : null;
+}
+
+// Post to one of the MFA login endpoints with the challenge's mfa_token
+async function mfaRequest(path, body) {
+  const res = await fetch(`${API}/api/v1/auth/mfa${path}`, {
+    method: "POST",
+    headers: {
+      "Content-Type": "application/json",
+    },
+    credentials: "include",
+    body: JSON.stringify(body),
+  });
+
+  if (!res.ok) {
+    const error = await res.json().catch(() => ({ error: "Verification failed" }));
+    throw new Error(error.error || "Verification failed");
+  }
+
+  const data = await res.json();
+  const accessToken = data.data?.access_token;
+  if (accessToken) {
+    setAccessToken(accessToken);
+  }
+  return data.data;
+}
+
+// Finish an MFA challenged login with a code from the authenticator app or a recovery code
+export function verifyMFA(mfaToken, code) {
+  return mfaRequest("/verify", { mfa_token: mfaToken, code });
+}
+
+// Start setting up an authenticator app when the challenge has mfa_setup_required
+export function setupMFA(mfaToken) {
+  return mfaRequest("/setup", { mfa_token: mfaToken });
+}
+
+// Confirm the new authenticator app and finish the login; the result has the recovery codes
+export function confirmMFASetup(mfaToken, code) {
+  return mfaRequest("/setup/confirm", { mfa_token: mfaToken, code });
+}
+
 export async function logout() {
   const res = await fetch(`${API}/api/v1/auth/logout`, {
     method: "POST",
@@ -160,6 +210,34 @@ export async function refreshToken() {
   return data;
 }
 
+// Trade the one-time code from the Google login redirect for an access token.
+// The refresh token cookie was already set by the callback. Accounts with MFA get
+// a challenge instead; see mfaChallenge.
+export async function exchangeLoginCode(code) {
+  const res = await fetch(`${API}/api/v1/auth/exchange`, {
+    method: "POST",
+    headers: {
+      "Content-Type": "application/json",
+    },
+    credentials: "include",
+    body: JSON.stringify({ code }),
+  });
+
+  if (!res.ok) {
+    throw new Error("Login failed");
+  }
+
+  const data = await res.json();
+  if (mfaChallenge(data)) {
+    return data;
+  }
+  const accessToken = data.data?.access_token ?? data.access_token;
+  if (accessToken) {
+    setAccessToken(accessToken);
+  }
+  return data;
+}
+
 export async function register(email, name, password) {
   try {
     const res = await fetch(`${API}/api/v1/auth/register`, {
@@ -1,6 +1,6 @@
 import { useNavigate, useSearchParams } from "react-router-dom";
 import { useEffect, useRef, useState } from "react";
-import { getMe } from "../api";
+import { exchangeLoginCode, getMe, mfaChallenge } from "../api";
 import ProfileDropdown from "../components/ProfileDropdown";
 import { setAccessToken } from "../utils/tokenStorage";
 
@@ -12,8 +12,35 @@ export default function HomePage() {
   const [loading, setLoading] = useState(true);
   const [mobileMenuOpen, setMobileMenuOpen] = useState(false);
 
-  // Handle Google OAuth callback - extract access_token from query parameter
+  const exchangedCodeRef = useRef(null);
+
+  // Handle Google OAuth callback - exchange the one-time auth_code for an access token
   useEffect(() => {
+    const authCode = searchParams.get("auth_code");
+    if (authCode && exchangedCodeRef.current !== authCode) {
+      // Codes are single-use; don't redeem twice when the effect re-runs
+      exchangedCodeRef.current = authCode;
+      searchParams.delete("auth_code");
+      setSearchParams(searchParams, { replace: true });
+      exchangeLoginCode(authCode)
+        .then((data) => {
+          // MFA accounts finish on the login page's code step, like a password login
+          const challenge = mfaChallenge(data);
+          if (challenge) {
+            navigate("/login", { state: { mfa: challenge } });
+            return;
+          }
+          return getMe().then((userData) => {
+            setUser(userData);
+          });
+        })
+        .catch(() => {
+          setUser(null);
+        });
+      return;
+    }
+
+    // Legacy redirect (OAUTH_LEGACY_TOKEN_REDIRECT) - extract access_token from query parameter
     const accessToken = searchParams.get("access_token");
     if (accessToken) {
       setAccessToken(accessToken);
@@ -27,7 +54,7 @@ export default function HomePage() {
         setUser(null);
       });
     }
-  }, [searchParams, setSearchParams]);
+  }, [searchParams, setSearchParams, navigate]);
 
   const startInterview = () => {
     if (user) {
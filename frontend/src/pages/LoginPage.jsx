@@ -1,6 +1,6 @@
-import { useState } from "react";
-import { useNavigate, Link } from "react-router-dom";
-import { login } from "../api";
+import { useEffect, useRef, useState } from "react";
+import { useNavigate, useLocation, Link } from "react-router-dom";
+import { login, mfaChallenge, verifyMFA, setupMFA, confirmMFASetup } from "../api";
 
 // Minimal inline SVG icons
 const MailIcon = (props) => (
@@ -41,17 +41,46 @@ const GoogleIcon = (props) => (
 
 export default function LoginPage() {
     const navigate = useNavigate();
+    const location = useLocation();
     const [email, setEmail] = useState("");
     const [password, setPassword] = useState("");
     const [show, setShow] = useState(false);
     const [loading, setLoading] = useState(false);
+    // MFA step: the login's challenge, and the new app's secret when it must be set up first
+    const [mfa, setMfa] = useState(null);
+    const [enrollment, setEnrollment] = useState(null);
+    const [code, setCode] = useState("");
+    const handledChallengeRef = useRef(null);
     const API_BASE = import.meta?.env?.VITE_API_BASE || (import.meta.env.PROD ? "" : "http://localhost:8080");
 
+    const startMFA = async (challenge) => {
+        setMfa(challenge);
+        setCode("");
+        if (challenge.mfa_setup_required) {
+            setEnrollment(await setupMFA(challenge.mfa_token));
+        }
+    };
+
+    // Google logins that need a second factor are sent here by the home page
+    useEffect(() => {
+        const challenge = location.state?.mfa;
+        // Setting up MFA twice would replace the secret, so only start once per challenge
+        if (challenge && handledChallengeRef.current !== challenge.mfa_token) {
+            handledChallengeRef.current = challenge.mfa_token;
+            navigate(location.pathname, { replace: true, state: null });
+            startMFA(challenge).catch((err) => alert(err.message || "Login failed"));
+        }
+    }, [location, navigate]);
+
     const onSubmit = async (e) => {
         e.preventDefault();
         setLoading(true);
         try {
-            await login(email, password);
+            const challenge = mfaChallenge(await login(email, password));
+            if (challenge) {
+                await startMFA(challenge);
+                return;
+            }
             navigate("/");
         } catch (err) {
             alert(err.message || "Login failed");
@@ -60,6 +89,24 @@ export default function LoginPage() {
         }
     };
 
+    const onSubmitCode = async (e) => {
+        e.preventDefault();
+        setLoading(true);
+        try {
+            if (mfa.mfa_setup_required) {
+                const result = await confirmMFASetup(mfa.mfa_token, code);
+                alert(`Save these recovery codes somewhere safe, they are only shown once:\n\n${result.recovery_codes.join("\n")}`);
+            } else {
+                await verifyMFA(mfa.mfa_token, code);
+            }
+            navigate("/");
+        } catch (err) {
+            alert(err.message || "Verification failed");
+        } finally {
+            setLoading(false);
+        }
+    };
+
     const googleLogin = () => {
         window.location.href = `${API_BASE}/auth/google`;
     };
@@ -77,6 +124,54 @@ export default function LoginPage() {
                         </span>
                     </div>
 
+                    {mfa ? (
+                    <form onSubmit={onSubmitCode} className="space-y-4">
+                        <p className="text-xs sm:text-sm text-gray-600 mb-4 sm:mb-6 text-center">
+                            {mfa.mfa_setup_required
+                                ? "Your account must use two-factor authentication. Add this key to your authenticator app, then enter the code it shows."
+                                : "Enter the code from your authenticator app, or one of your recovery codes"}
+                        </p>
+
+                        {enrollment && (
+                            <div className="rounded-xl bg-gray-50 border border-gray-300 p-3 text-center">
+                                <span className="text-xs text-gray-500">Setup key</span>
+                                <p className="font-mono text-sm text-gray-900 break-all select-all">{enrollment.secret}</p>
+                                <a href={enrollment.otpauth_uri} className="text-xs text-indigo-600 hover:underline">Open in authenticator app</a>
+                            </div>
+                        )}
+
+                        {/* Code */}
+                        <label className="block">
+                            <span className="text-sm text-gray-700 font-medium">Verification code</span>
+                            <input
+                                type="text"
+                                autoComplete="one-time-code"
+                                autoFocus
+                                value={code}
+                                onChange={(e) => setCode(e.target.value)}
+                                placeholder="123456"
+                                className="mt-1.5 w-full bg-gray-50 border border-gray-300 rounded-xl py-3 sm:py-2.5 px-3 outline-none text-sm placeholder:text-gray-400 focus:ring-2 focus:ring-indigo-500 focus:border-indigo-500 text-gray-900 min-h-[44px]"
+                            />
+                        </label>
+
+                        <button
+                            type="submit"
+                            disabled={loading || !code}
+                            className="w-full rounded-xl py-3 sm:py-2.5 text-sm font-semibold bg-gradient-to-r from-indigo-600 to-purple-600 text-white hover:shadow-lg disabled:opacity-60 transition-all min-h-[44px]"
+                        >
+                            {loading ? "Verifying…" : "Verify"}
+                        </button>
+
+                        <button
+                            type="button"
+                            onClick={() => { setMfa(null); setEnrollment(null); }}
+                            className="w-full text-xs text-gray-500 hover:text-indigo-600 transition-colors"
+                        >
+                            Back to sign in
+                        </button>
+                    </form>
+                    ) : (
+                    <>
                     <p className="text-xs sm:text-sm text-gray-600 mb-4 sm:mb-6 text-center">
                         Sign in to continue to your interview practice
                     </p>
@@ -154,6 +249,8 @@ export default function LoginPage() {
                             <span>Log in with Google</span>
                         </button>
                     </form>
+                    </>
+                    )}
                 </div>
 
                 {/* Footer */}
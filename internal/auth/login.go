@@ -0,0 +1,236 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"altoai_mvp/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/oauth2"
+)
+
+var sharedAuthService services.AuthService
+
+// SetAuthService sets the service provider logins find users and issue tokens with
+func SetAuthService(svc services.AuthService) {
+	sharedAuthService = svc
+}
+
+// setStateCookie stores the signed login state; an empty value clears it
+func setStateCookie(c *gin.Context, value string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     stateCookieName,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   os.Getenv("GIN_MODE") == "release",
+		// Lax so the cookie comes back on the provider's top-level redirect to the callback
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// GET /api/v1/auth/providers
+func HandleListProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": Providers()})
+}
+
+// GET /auth/:provider?redirect_to=/path
+func HandleLogin(c *gin.Context) {
+	p, ok := getProvider(c.Param("provider"))
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "unknown login provider"})
+		return
+	}
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "JWT_SECRET not set"})
+		return
+	}
+	conf, err := p.oauthConfig(c.Request.Context())
+	if err != nil {
+		log.Printf("oidc: %v", err)
+		c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": "login provider unavailable"})
+		return
+	}
+
+	// A fresh state, nonce and PKCE verifier per login, bound to this browser by a signed cookie
+	state, err := randomToken(32)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "could not start login"})
+		return
+	}
+	nonce, err := randomToken(32)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "could not start login"})
+		return
+	}
+	verifier := oauth2.GenerateVerifier()
+	cookie, err := encodeState(loginState{
+		Provider:   p.cfg.Name,
+		State:      state,
+		Nonce:      nonce,
+		Verifier:   verifier,
+		RedirectTo: safeRedirect(c.Query("redirect_to")),
+		ExpiresAt:  time.Now().Add(stateTTL).Unix(),
+	}, secret)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "could not start login"})
+		return
+	}
+	setStateCookie(c, cookie, int(stateTTL/time.Second))
+
+	url := conf.AuthCodeURL(state,
+		oauth2.AccessTypeOffline,
+		oauth2.S256ChallengeOption(verifier),
+		oauth2.SetAuthURLParam("nonce", nonce),
+	)
+	c.Redirect(http.StatusFound, url)
+}
+
+// GET /auth/:provider/callback?code=...&state=...
+func HandleCallback(c *gin.Context) {
+	p, ok := getProvider(c.Param("provider"))
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "unknown login provider"})
+		return
+	}
+
+	// The state must match the one this browser started the login with, at this provider
+	cookie, err := c.Cookie(stateCookieName)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "missing login state"})
+		return
+	}
+	setStateCookie(c, "", -1) // single use
+	ls, err := decodeState(cookie, os.Getenv("JWT_SECRET"), time.Now())
+	if err != nil || ls.Provider != p.cfg.Name || subtle.ConstantTimeCompare([]byte(ls.State), []byte(c.Query("state"))) != 1 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": errInvalidState.Error()})
+		return
+	}
+
+	code := c.Query("code")
+	if code == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "missing code"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	conf, err := p.oauthConfig(ctx)
+	if err != nil {
+		log.Printf("oidc: %v", err)
+		c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": "login provider unavailable"})
+		return
+	}
+	tok, err := conf.Exchange(ctx, code, oauth2.VerifierOption(ls.Verifier))
+	if err != nil {
+		log.Printf("%s token exchange failed: %v", p.cfg.Name, err)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token exchange failed"})
+		return
+	}
+
+	rawIDToken, _ := tok.Extra("id_token").(string)
+	if rawIDToken == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "provider returned no ID token"})
+		return
+	}
+	claims, err := p.verifyIDToken(ctx, rawIDToken, ls.Nonce)
+	if err != nil {
+		log.Printf("%s ID token rejected: %v", p.cfg.Name, err)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid ID token"})
+		return
+	}
+	if _, ok := claims[p.cfg.Claims.Email]; !ok {
+		if err := p.fetchUserinfo(ctx, conf, tok, claims); err != nil {
+			log.Printf("%s userinfo failed: %v", p.cfg.Name, err)
+		}
+	}
+
+	identity := p.identity(claims)
+	if identity.Subject == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "provider returned no account ID"})
+		return
+	}
+
+	authService := sharedAuthService
+	if authService == nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "login not configured"})
+		return
+	}
+
+	// Find or link the user; the tokens issued for them start a new refresh token family
+	// for this device
+	user, err := authService.LoginWithIdentity(ctx, identity)
+	if errors.Is(err, services.ErrUnverifiedProviderEmail) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("%s login failed: %v", p.cfg.Name, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to sign in"})
+		return
+	}
+	ctx = services.WithClientInfo(ctx, services.ClientInfo{
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	})
+
+	// Get frontend URL from environment or use default
+	frontendURL := os.Getenv("FRONTEND_URL")
+	if frontendURL == "" {
+		// Default to same origin in production, or dev server in development
+		// Check if we're in production (no dev server running)
+		if os.Getenv("GIN_MODE") == "release" {
+			frontendURL = "http://localhost:3000" // Docker default
+		} else {
+			frontendURL = "http://localhost:5173" // Vite dev server
+		}
+	}
+
+	// Logins return to the page they started from
+	target, err := url.Parse(frontendURL + ls.RedirectTo)
+	if err != nil {
+		target, _ = url.Parse(frontendURL + "/")
+	}
+	query := target.Query()
+
+	// Redirect with a one-time code, which the frontend exchanges for tokens so no token
+	// lands in history, logs or Referer. Users with MFA get their challenge from the exchange
+	// and finish signing in on that page with their second factor.
+	legacy := legacyTokenRedirect()
+	if legacy {
+		// The legacy redirect has no way to ask for a second factor
+		var challenge *services.MFAChallengeError
+		if err := authService.MFAChallenge(ctx, user); errors.As(err, &challenge) {
+			legacy = false
+		} else if err != nil {
+			log.Printf("%s login failed: %v", p.cfg.Name, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to sign in"})
+			return
+		}
+	}
+	if legacy {
+		accessToken, refreshToken, err := authService.IssueTokens(ctx, user, identity.Picture)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "could not issue tokens"})
+			return
+		}
+		setRefreshCookie(c, refreshToken)
+		query.Set("access_token", accessToken)
+	} else {
+		loginCode, err := authService.IssueLoginCode(ctx, user, identity.Picture)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "could not issue login code"})
+			return
+		}
+		query.Set("auth_code", loginCode)
+	}
+	target.RawQuery = query.Encode()
+	c.Redirect(http.StatusFound, target.String())
+}
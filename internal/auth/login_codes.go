@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"altoai_mvp/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// legacyTokenRedirect reports whether OAuth callbacks still put the access token in the
+// redirect URL (OAUTH_LEGACY_TOKEN_REDIRECT=true), for frontends not yet using /auth/exchange
+func legacyTokenRedirect() bool {
+	return strings.EqualFold(os.Getenv("OAUTH_LEGACY_TOKEN_REDIRECT"), "true")
+}
+
+// setRefreshCookie sets the refresh token cookie (HttpOnly, Secure in release mode)
+func setRefreshCookie(c *gin.Context, refreshToken string) {
+	isSecure := os.Getenv("GIN_MODE") == "release"
+	c.SetCookie("refresh_token", refreshToken, 30*24*60*60, "/", os.Getenv("COOKIE_DOMAIN"), isSecure, true)
+}
+
+type exchangeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// POST /api/v1/auth/exchange {"code": "..."}
+// Trades the one-time code from an OAuth redirect for the access token and sets the
+// refresh token cookie. Users with MFA get the same mfa_required challenge as a password
+// login instead.
+func HandleCodeExchange(c *gin.Context) {
+	var req exchangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "missing code"})
+		return
+	}
+
+	authService := sharedAuthService
+	if authService == nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "login not configured"})
+		return
+	}
+	ctx := services.WithClientInfo(c.Request.Context(), services.ClientInfo{
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	})
+
+	accessToken, refreshToken, _, err := authService.ExchangeLoginCode(ctx, req.Code)
+	c.Header("Cache-Control", "no-store")
+	var challenge *services.MFAChallengeError
+	if errors.As(err, &challenge) {
+		c.JSON(http.StatusOK, gin.H{"data": gin.H{
+			"mfa_required":       true,
+			"mfa_setup_required": challenge.SetupRequired,
+			"mfa_token":          challenge.Token,
+			"expires_at":         challenge.ExpiresAt,
+		}})
+		return
+	}
+	if errors.Is(err, services.ErrLoginCodeInvalid) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("login code exchange failed: %v", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to sign in"})
+		return
+	}
+
+	setRefreshCookie(c, refreshToken)
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"access_token": accessToken}})
+}
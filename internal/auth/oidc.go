@@ -0,0 +1,411 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"altoai_mvp/internal/services"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+// ClaimMapping names the ID token (or userinfo) claims that hold each profile field.
+// Empty fields use the standard OIDC claim names.
+type ClaimMapping struct {
+	Subject       string
+	Email         string
+	EmailVerified string
+	Name          string
+	Picture       string
+}
+
+func (m ClaimMapping) withDefaults() ClaimMapping {
+	if m.Subject == "" {
+		m.Subject = "sub"
+	}
+	if m.Email == "" {
+		m.Email = "email"
+	}
+	if m.EmailVerified == "" {
+		m.EmailVerified = "email_verified"
+	}
+	if m.Name == "" {
+		m.Name = "name"
+	}
+	if m.Picture == "" {
+		m.Picture = "picture"
+	}
+	return m
+}
+
+// ProviderConfig configures one OpenID Connect login provider
+type ProviderConfig struct {
+	Name         string // URL segment: /auth/<name>
+	DisplayName  string
+	Issuer       string // discovery is fetched from <Issuer>/.well-known/openid-configuration
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string // defaults to openid, email and profile
+	Claims       ClaimMapping
+	// ExtraIssuers are other iss values the provider signs ID tokens with
+	// (Google uses both "https://accounts.google.com" and "accounts.google.com")
+	ExtraIssuers []string
+	// TrustEmail treats every email from this provider as verified, for identity providers
+	// that own the domain (university SSO) but don't send email_verified
+	TrustEmail bool
+}
+
+// providerMetadata is the part of the OIDC discovery document we use
+type providerMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// jwksRefreshInterval limits how often an unknown key ID triggers a JWKS refetch
+const jwksRefreshInterval = time.Minute
+
+// idTokenAlgorithms are the signing algorithms accepted on ID tokens; never "none" or HMAC
+var idTokenAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// Provider is a configured OIDC provider. Discovery and signing keys are fetched
+// on first use and cached.
+type Provider struct {
+	cfg    ProviderConfig
+	client *http.Client
+
+	mu          sync.Mutex
+	meta        *providerMetadata
+	keys        map[string]crypto.PublicKey
+	keysFetched time.Time
+}
+
+func newProvider(cfg ProviderConfig) *Provider {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	if cfg.DisplayName == "" {
+		cfg.DisplayName = cfg.Name
+	}
+	cfg.Issuer = strings.TrimSuffix(cfg.Issuer, "/")
+	cfg.Claims = cfg.Claims.withDefaults()
+	return &Provider{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+var (
+	providers   = map[string]*Provider{}
+	providersMu sync.RWMutex
+)
+
+// RegisterProvider adds or replaces a login provider
+func RegisterProvider(cfg ProviderConfig) error {
+	if cfg.Name == "" || cfg.Issuer == "" || cfg.ClientID == "" {
+		return errors.New("oidc provider needs a name, issuer and client ID")
+	}
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[cfg.Name] = newProvider(cfg)
+	return nil
+}
+
+func getProvider(name string) (*Provider, bool) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	p, ok := providers[name]
+	return p, ok
+}
+
+// ProviderInfo is what the frontend needs to render a login button
+type ProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	LoginURL    string `json:"login_url"`
+}
+
+// Providers lists the registered login providers by name
+func Providers() []ProviderInfo {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	out := make([]ProviderInfo, 0, len(providers))
+	for _, p := range providers {
+		out = append(out, ProviderInfo{Name: p.cfg.Name, DisplayName: p.cfg.DisplayName, LoginURL: "/auth/" + p.cfg.Name})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// metadata fetches and caches the provider's discovery document
+func (p *Provider) metadata(ctx context.Context) (*providerMetadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil {
+		return p.meta, nil
+	}
+
+	var meta providerMetadata
+	if err := p.getJSON(ctx, p.cfg.Issuer+"/.well-known/openid-configuration", &meta); err != nil {
+		return nil, fmt.Errorf("oidc discovery for %s: %w", p.cfg.Name, err)
+	}
+	if strings.TrimSuffix(meta.Issuer, "/") != p.cfg.Issuer {
+		return nil, fmt.Errorf("oidc discovery for %s: issuer %q does not match %q", p.cfg.Name, meta.Issuer, p.cfg.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc discovery for %s: missing endpoints", p.cfg.Name)
+	}
+	p.meta = &meta
+	return p.meta, nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, url string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// oauthConfig builds the OAuth2 client for the provider's discovered endpoints
+func (p *Provider) oauthConfig(ctx context.Context) (*oauth2.Config, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		RedirectURL:  p.cfg.RedirectURL,
+		Scopes:       p.cfg.Scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  meta.AuthorizationEndpoint,
+			TokenURL: meta.TokenEndpoint,
+		},
+	}, nil
+}
+
+// signingKey returns the provider key with the given ID, refetching the JWKS when
+// the key is unknown (providers rotate keys) at most once per jwksRefreshInterval
+func (p *Provider) signingKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetched) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	var set jsonWebKeySet
+	if err := p.getJSON(ctx, meta.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("fetch jwks for %s: %w", p.cfg.Name, err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+	p.keys = keys
+	p.keysFetched = time.Now()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKey finds kid in the cached keys; a token without kid matches a single-key set
+func (p *Provider) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// verifyIDToken checks the ID token's signature against the provider's JWKS, its issuer,
+// audience, expiry and the nonce sent with this login, and returns its claims
+func (p *Provider) verifyIDToken(ctx context.Context, raw, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.signingKey(ctx, kid)
+	},
+		jwt.WithValidMethods(idTokenAlgorithms),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	iss, _ := claims.GetIssuer()
+	if strings.TrimSuffix(iss, "/") != p.cfg.Issuer && !slices.Contains(p.cfg.ExtraIssuers, iss) {
+		return nil, fmt.Errorf("unexpected issuer %q", iss)
+	}
+	if got, _ := claims["nonce"].(string); nonce == "" || got != nonce {
+		return nil, errors.New("nonce mismatch")
+	}
+	return claims, nil
+}
+
+// fetchUserinfo fills in profile claims the ID token left out from the userinfo endpoint.
+// The userinfo subject must match the ID token's.
+func (p *Provider) fetchUserinfo(ctx context.Context, conf *oauth2.Config, tok *oauth2.Token, claims jwt.MapClaims) error {
+	meta, err := p.metadata(ctx)
+	if err != nil || meta.UserinfoEndpoint == "" {
+		return err
+	}
+
+	resp, err := conf.Client(ctx, tok).Get(meta.UserinfoEndpoint)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("userinfo: %s", resp.Status)
+	}
+
+	info := map[string]any{}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return err
+	}
+	if sub := p.cfg.Claims.Subject; info[sub] != claims[sub] {
+		return errors.New("userinfo subject does not match the ID token")
+	}
+	for k, v := range info {
+		if _, ok := claims[k]; !ok {
+			claims[k] = v
+		}
+	}
+	return nil
+}
+
+// identity maps the provider's claims onto an ExternalIdentity
+func (p *Provider) identity(claims jwt.MapClaims) services.ExternalIdentity {
+	m := p.cfg.Claims
+	return services.ExternalIdentity{
+		Provider:      p.cfg.Name,
+		Subject:       stringClaim(claims, m.Subject),
+		Email:         strings.ToLower(stringClaim(claims, m.Email)),
+		EmailVerified: p.cfg.TrustEmail || boolClaim(claims, m.EmailVerified),
+		Name:          stringClaim(claims, m.Name),
+		Picture:       stringClaim(claims, m.Picture),
+	}
+}
+
+func stringClaim(claims jwt.MapClaims, name string) string {
+	switch v := claims[name].(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	}
+	return ""
+}
+
+// boolClaim accepts true and "true"; some providers send email_verified as a string
+func boolClaim(claims jwt.MapClaims, name string) bool {
+	switch v := claims[name].(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(v, "true")
+	}
+	return false
+}
+
+// LoadProvidersFromEnv registers Google (from GOOGLE_CLIENT_ID and friends) and every
+// provider named in OIDC_PROVIDERS, configured by OIDC_<NAME>_* variables
+func LoadProvidersFromEnv() error {
+	if os.Getenv("GOOGLE_CLIENT_ID") != "" {
+		if err := RegisterProvider(ProviderConfig{
+			Name:         "google",
+			DisplayName:  "Google",
+			Issuer:       "https://accounts.google.com",
+			ExtraIssuers: []string{"accounts.google.com"},
+			ClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
+			ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
+			RedirectURL:  envOr("GOOGLE_REDIRECT_URL", defaultRedirectURL("google")),
+		}); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		cfg := ProviderConfig{
+			Name:         name,
+			DisplayName:  os.Getenv(prefix + "DISPLAY_NAME"),
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  envOr(prefix+"REDIRECT_URL", defaultRedirectURL(name)),
+			Scopes:       strings.Fields(strings.ReplaceAll(os.Getenv(prefix+"SCOPES"), ",", " ")),
+			Claims: ClaimMapping{
+				Subject:       os.Getenv(prefix + "CLAIM_SUBJECT"),
+				Email:         os.Getenv(prefix + "CLAIM_EMAIL"),
+				EmailVerified: os.Getenv(prefix + "CLAIM_EMAIL_VERIFIED"),
+				Name:          os.Getenv(prefix + "CLAIM_NAME"),
+				Picture:       os.Getenv(prefix + "CLAIM_PICTURE"),
+			},
+			TrustEmail: strings.EqualFold(os.Getenv(prefix+"TRUST_EMAIL"), "true"),
+		}
+		if err := RegisterProvider(cfg); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// defaultRedirectURL is the provider's callback on this API
+func defaultRedirectURL(name string) string {
+	if os.Getenv("GIN_MODE") == "release" {
+		return "http://localhost:3000/auth/" + name + "/callback" // Docker default
+	}
+	return "http://localhost:8080/auth/" + name + "/callback" // Local dev
+}
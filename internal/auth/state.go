@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// stateCookieName holds the signed login state between /auth/<provider> and its callback
+const stateCookieName = "oauth_state"
+
+// stateTTL bounds how long a user can take on the provider's consent screen
+const stateTTL = 10 * time.Minute
+
+var errInvalidState = errors.New("invalid or expired login state")
+
+// loginState is what a login attempt must present again on callback
+type loginState struct {
+	Provider   string `json:"p"`
+	State      string `json:"s"`
+	Nonce      string `json:"n"` // echoed back in the ID token
+	Verifier   string `json:"v"` // PKCE code verifier
+	RedirectTo string `json:"r"` // frontend path to return to
+	ExpiresAt  int64  `json:"e"`
+}
+
+// randomToken returns n random bytes, base64url encoded
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// encodeState serialises and signs s with secret as payload.signature
+func encodeState(s loginState, secret string) (string, error) {
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(raw)
+	return payload + "." + signState(payload, secret), nil
+}
+
+// decodeState verifies the signature and expiry of a value produced by encodeState
+func decodeState(value, secret string, now time.Time) (loginState, error) {
+	payload, sig, ok := strings.Cut(value, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(signState(payload, secret))) {
+		return loginState{}, errInvalidState
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return loginState{}, errInvalidState
+	}
+	var s loginState
+	if err := json.Unmarshal(raw, &s); err != nil || now.Unix() > s.ExpiresAt {
+		return loginState{}, errInvalidState
+	}
+	return s, nil
+}
+
+func signState(payload, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("oauth-state:" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// safeRedirect returns raw if it is a path on the frontend ("/practice?tab=due"),
+// and "/" for anything that could leave the site (absolute or scheme-relative URLs)
+func safeRedirect(raw string) string {
+	if raw == "" || !strings.HasPrefix(raw, "/") || strings.HasPrefix(raw, "//") || strings.ContainsAny(raw, "\\\r\n") {
+		return "/"
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "" || u.Host != "" {
+		return "/"
+	}
+	return raw
+}
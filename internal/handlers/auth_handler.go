@@ -1,12 +1,19 @@
 package handlers
 
 import (
+	"altoai_mvp/internal/middleware"
 	"altoai_mvp/internal/models"
+	"altoai_mvp/internal/repository"
 	"altoai_mvp/internal/services"
 	errs "altoai_mvp/pkg/errors"
 	"altoai_mvp/pkg/response"
+	"context"
+	"errors"
+	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -19,26 +26,55 @@ func NewAuthHandler(authSvc services.AuthService) *AuthHandler {
 	return &AuthHandler{authSvc: authSvc}
 }
 
-func (h *AuthHandler) Login(c *gin.Context) {
-	var dto models.LoginDTO
-	if err := c.ShouldBindJSON(&dto); err != nil {
-		response.ValidationError(c, errs.FromBinding(err))
-		return
+// clientContext returns the request context carrying the caller's device details,
+// recorded on the refresh tokens issued for this request
+func clientContext(c *gin.Context) context.Context {
+	return services.WithClientInfo(c.Request.Context(), services.ClientInfo{
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	})
+}
+
+// lockedOut answers 429 with Retry-After if err is a lockout after repeated failures
+func lockedOut(c *gin.Context, err error) bool {
+	var lockout *services.LockoutError
+	if !errors.As(err, &lockout) {
+		return false
 	}
+	c.Header("Retry-After", strconv.Itoa(int(lockout.RetryAfter(time.Now()).Seconds())))
+	response.Error(c, http.StatusTooManyRequests, lockout.Error())
+	return true
+}
 
-	accessToken, refreshToken, user, err := h.authSvc.Login(c.Request.Context(), dto)
-	if err != nil {
-		response.Error(c, http.StatusUnauthorized, err.Error())
-		return
+// weakPassword answers with the policy problems as a validation error on field
+func weakPassword(c *gin.Context, field string, err error) bool {
+	var policy *services.PasswordPolicyError
+	if !errors.As(err, &policy) {
+		return false
 	}
+	response.ValidationError(c, map[string]string{field: policy.Error()})
+	return true
+}
 
-	// Set refresh token cookie (HttpOnly, Secure)
-	cookieDomain := os.Getenv("COOKIE_DOMAIN")
-	if cookieDomain == "" {
-		cookieDomain = ""
+// mfaChallenged answers with the login's MFA challenge if err asks for a second factor
+func mfaChallenged(c *gin.Context, err error) bool {
+	var challenge *services.MFAChallengeError
+	if !errors.As(err, &challenge) {
+		return false
 	}
-	// Refresh token expires in 30 days (720 hours)
-	c.SetCookie("refresh_token", refreshToken, 30*24*60*60, "/", cookieDomain, os.Getenv("GIN_MODE") == "release", true)
+	response.OK(c, gin.H{
+		"mfa_required":       true,
+		"mfa_setup_required": challenge.SetupRequired,
+		"mfa_token":          challenge.Token,
+		"expires_at":         challenge.ExpiresAt,
+	})
+	return true
+}
+
+// loggedIn sets the refresh token cookie and returns the access token, ending every login
+func loggedIn(c *gin.Context, accessToken, refreshToken string, user *models.User) {
+	// Set refresh token cookie (HttpOnly, Secure); it expires in 30 days (720 hours)
+	c.SetCookie("refresh_token", refreshToken, 30*24*60*60, "/", os.Getenv("COOKIE_DOMAIN"), os.Getenv("GIN_MODE") == "release", true)
 
 	// Return access token in JSON response
 	response.OK(c, gin.H{
@@ -50,6 +86,25 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	})
 }
 
+func (h *AuthHandler) Login(c *gin.Context) {
+	var dto models.LoginDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		response.ValidationError(c, errs.FromBinding(err))
+		return
+	}
+
+	accessToken, refreshToken, user, err := h.authSvc.Login(clientContext(c), dto)
+	if lockedOut(c, err) || mfaChallenged(c, err) {
+		return
+	}
+	if err != nil {
+		response.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	loggedIn(c, accessToken, refreshToken, user)
+}
+
 func (h *AuthHandler) Register(c *gin.Context) {
 	var dto models.CreateUserDTO
 	if err := c.ShouldBindJSON(&dto); err != nil {
@@ -58,6 +113,9 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	}
 
 	err := h.authSvc.Register(c.Request.Context(), dto)
+	if weakPassword(c, "password", err) {
+		return
+	}
 	if err != nil {
 		response.Error(c, http.StatusBadRequest, err.Error())
 		return
@@ -75,28 +133,16 @@ func (h *AuthHandler) VerifyEmail(c *gin.Context) {
 		return
 	}
 
-	accessToken, refreshToken, user, err := h.authSvc.VerifyEmail(c.Request.Context(), dto)
+	accessToken, refreshToken, user, err := h.authSvc.VerifyEmail(clientContext(c), dto)
+	if lockedOut(c, err) || mfaChallenged(c, err) {
+		return
+	}
 	if err != nil {
 		response.Error(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// Set refresh token cookie (HttpOnly, Secure)
-	cookieDomain := os.Getenv("COOKIE_DOMAIN")
-	if cookieDomain == "" {
-		cookieDomain = ""
-	}
-	// Refresh token expires in 30 days (720 hours)
-	c.SetCookie("refresh_token", refreshToken, 30*24*60*60, "/", cookieDomain, os.Getenv("GIN_MODE") == "release", true)
-
-	// Return access token in JSON response
-	response.OK(c, gin.H{
-		"access_token": accessToken,
-		"user": gin.H{
-			"email": user.Email,
-			"name":  user.Name,
-		},
-	})
+	loggedIn(c, accessToken, refreshToken, user)
 }
 
 func (h *AuthHandler) ResendVerificationCode(c *gin.Context) {
@@ -144,7 +190,10 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 		return
 	}
 
-	err := h.authSvc.ResetPassword(c.Request.Context(), dto)
+	err := h.authSvc.ResetPassword(clientContext(c), dto)
+	if lockedOut(c, err) || weakPassword(c, "password", err) {
+		return
+	}
 	if err != nil {
 		response.Error(c, http.StatusBadRequest, err.Error())
 		return
@@ -155,7 +204,52 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 	})
 }
 
+func (h *AuthHandler) RequestMagicLink(c *gin.Context) {
+	var dto models.MagicLinkDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		response.ValidationError(c, errs.FromBinding(err))
+		return
+	}
+
+	err := h.authSvc.RequestMagicLink(c.Request.Context(), dto)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Don't reveal if user exists or not
+	response.OK(c, gin.H{
+		"message": "If an account exists with this email, a sign-in link has been sent.",
+	})
+}
+
+func (h *AuthHandler) MagicLinkLogin(c *gin.Context) {
+	var dto models.MagicLinkLoginDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		response.ValidationError(c, errs.FromBinding(err))
+		return
+	}
+
+	accessToken, refreshToken, user, err := h.authSvc.LoginWithMagicLink(clientContext(c), dto)
+	if mfaChallenged(c, err) {
+		return
+	}
+	if err != nil {
+		response.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	loggedIn(c, accessToken, refreshToken, user)
+}
+
 func (h *AuthHandler) Logout(c *gin.Context) {
+	// Revoke the refresh token family so copies of the cookie stop working too
+	if refreshToken, err := c.Cookie("refresh_token"); err == nil && refreshToken != "" {
+		if err := h.authSvc.Logout(c.Request.Context(), refreshToken); err != nil {
+			log.Printf("Failed to revoke refresh token on logout: %v", err)
+		}
+	}
+
 	// Clear the refresh token cookie
 	cookieDomain := os.Getenv("COOKIE_DOMAIN")
 	if cookieDomain == "" {
@@ -173,7 +267,7 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 		return
 	}
 
-	newAccessToken, newRefreshToken, err := h.authSvc.RefreshToken(c.Request.Context(), refreshToken)
+	newAccessToken, newRefreshToken, err := h.authSvc.RefreshToken(clientContext(c), refreshToken)
 	if err != nil {
 		response.Error(c, http.StatusUnauthorized, err.Error())
 		return
@@ -192,3 +286,143 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 		"access_token": newAccessToken,
 	})
 }
+
+// Sessions lists the devices the caller is signed in on.
+// GET /api/v1/auth/sessions
+func (h *AuthHandler) Sessions(c *gin.Context) {
+	claims := c.MustGet("user").(*middleware.MyClaims)
+	current, _ := c.Cookie("refresh_token")
+
+	sessions, err := h.authSvc.ListSessions(c.Request.Context(), claims.Subject, current)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to list sessions")
+		return
+	}
+	if sessions == nil {
+		sessions = []models.DeviceSession{}
+	}
+	response.OK(c, sessions)
+}
+
+// RevokeSession signs out one device.
+// DELETE /api/v1/auth/sessions/:id
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	claims := c.MustGet("user").(*middleware.MyClaims)
+
+	err := h.authSvc.RevokeSession(c.Request.Context(), claims.Subject, c.Param("id"))
+	if err == repository.ErrNotFound {
+		response.Error(c, http.StatusNotFound, "session not found")
+		return
+	}
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to revoke session")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ChangePassword sets a new password, signs out every other device and returns new tokens
+// for this one. current_password is required unless the account has no password yet.
+// POST /api/v1/auth/change-password {"current_password": "...", "new_password": "..."}
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	claims := c.MustGet("user").(*middleware.MyClaims)
+	var dto models.ChangePasswordDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		response.ValidationError(c, errs.FromBinding(err))
+		return
+	}
+
+	accessToken, refreshToken, user, err := h.authSvc.ChangePassword(clientContext(c), claims.Subject, dto)
+	if lockedOut(c, err) || weakPassword(c, "new_password", err) {
+		return
+	}
+	switch err {
+	case nil:
+		loggedIn(c, accessToken, refreshToken, user)
+	case services.ErrWrongPassword:
+		response.Error(c, http.StatusUnauthorized, err.Error())
+	case services.ErrSamePassword:
+		response.ValidationError(c, map[string]string{"new_password": err.Error()})
+	default:
+		log.Printf("Failed to change password: %v", err)
+		response.Error(c, http.StatusInternalServerError, "failed to change password")
+	}
+}
+
+// RequestEmailChange emails a code to the new address. The email only changes once it is confirmed.
+// POST /api/v1/auth/change-email {"new_email": "...", "current_password": "..."}
+func (h *AuthHandler) RequestEmailChange(c *gin.Context) {
+	claims := c.MustGet("user").(*middleware.MyClaims)
+	var dto models.ChangeEmailDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		response.ValidationError(c, errs.FromBinding(err))
+		return
+	}
+
+	err := h.authSvc.RequestEmailChange(clientContext(c), claims.Subject, dto)
+	if lockedOut(c, err) {
+		return
+	}
+	switch err {
+	case nil:
+		response.OK(c, gin.H{"message": "We sent a confirmation code to your new email address."})
+	case services.ErrWrongPassword:
+		response.Error(c, http.StatusUnauthorized, err.Error())
+	case services.ErrSameEmail:
+		response.ValidationError(c, map[string]string{"new_email": err.Error()})
+	case services.ErrEmailTaken:
+		response.Error(c, http.StatusConflict, err.Error())
+	default:
+		log.Printf("Failed to start email change: %v", err)
+		response.Error(c, http.StatusInternalServerError, "failed to send confirmation code")
+	}
+}
+
+// ConfirmEmailChange moves the account to the new email and replaces this device's tokens
+// with ones carrying it.
+// POST /api/v1/auth/change-email/confirm {"code": "123456"}
+func (h *AuthHandler) ConfirmEmailChange(c *gin.Context) {
+	claims := c.MustGet("user").(*middleware.MyClaims)
+	var dto models.ConfirmEmailChangeDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		response.ValidationError(c, errs.FromBinding(err))
+		return
+	}
+
+	ctx := clientContext(c)
+	accessToken, refreshToken, user, err := h.authSvc.ConfirmEmailChange(ctx, claims.Subject, dto)
+	if lockedOut(c, err) {
+		return
+	}
+	switch err {
+	case nil:
+	case services.ErrEmailTaken:
+		response.Error(c, http.StatusConflict, err.Error())
+		return
+	default:
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// The new tokens start a new session; retire the one they replace
+	if old, err := c.Cookie("refresh_token"); err == nil && old != "" {
+		if err := h.authSvc.Logout(ctx, old); err != nil {
+			log.Printf("Failed to revoke refresh token after email change: %v", err)
+		}
+	}
+	loggedIn(c, accessToken, refreshToken, user)
+}
+
+// LogoutAll signs out every device, including access tokens already issued.
+// POST /api/v1/auth/logout-all
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	claims := c.MustGet("user").(*middleware.MyClaims)
+
+	if err := h.authSvc.LogoutAll(c.Request.Context(), claims.Subject); err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to log out everywhere")
+		return
+	}
+
+	c.SetCookie("refresh_token", "", -1, "/", os.Getenv("COOKIE_DOMAIN"), os.Getenv("GIN_MODE") == "release", true)
+	c.Status(http.StatusNoContent)
+}
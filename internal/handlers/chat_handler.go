@@ -1,11 +1,14 @@
 package handlers
 
 import (
-	"altoai_mvp/interview"
 	"altoai_mvp/internal/middleware"
 	"altoai_mvp/internal/models"
+	"altoai_mvp/internal/repository"
 	"altoai_mvp/internal/services"
+	"altoai_mvp/interview"
 	"altoai_mvp/pkg/response"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -13,14 +16,22 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// Context keys for the idempotency key of the request being handled
+const (
+	ctxIdempotencyKey = "idempotency_key"
+	ctxRequestHash    = "idempotency_request_hash"
 )
 
 type ChatHandler struct {
-	userSvc services.UserService
+	userSvc   services.UserService
+	cohortSvc services.CohortService
 }
 
-func NewChatHandler(userSvc services.UserService) *ChatHandler {
-	return &ChatHandler{userSvc: userSvc}
+func NewChatHandler(userSvc services.UserService, cohortSvc services.CohortService) *ChatHandler {
+	return &ChatHandler{userSvc: userSvc, cohortSvc: cohortSvc}
 }
 
 type ChatRequest struct {
@@ -30,6 +41,21 @@ type ChatRequest struct {
 	} `json:"messages"`
 	SessionID string `json:"session_id,omitempty"` // Optional: for continuing existing interview
 	Level     string `json:"level,omitempty"`      // Optional: difficulty level (easy, medium, hard)
+	Seed      *int64 `json:"seed,omitempty"`       // Optional: replay a question selection (shared drills, bug reports)
+	Mode      string `json:"mode,omitempty"`       // Optional: "practice" starts a spaced-repetition drill
+	CohortID  string `json:"cohort_id,omitempty"`  // Optional: start the practice assigned to one of the user's cohorts
+	// Optional: enables timed mode for a new session; send {} for the default limits
+	TimeLimits *interview.TimeLimits `json:"time_limits,omitempty"`
+	// Optional: "skip" the current question, or "retry" an answered one (QuestionID).
+	// A retry only replaces the graded answer when Replace is set.
+	Action string `json:"action,omitempty"`
+	// Optional: for answers and skips, the question being answered; a mismatch with the
+	// session's current question (a stale tab or double submit) is rejected with 409
+	QuestionID string `json:"question_id,omitempty"`
+	Replace    bool   `json:"replace,omitempty"`
+	// Optional: client-generated ID for this answer; resending it returns the recorded result.
+	// Used as the idempotency key when the Idempotency-Key header is absent.
+	AnswerID string `json:"answer_id,omitempty"`
 }
 
 type ChatResponse struct {
@@ -43,15 +69,39 @@ type ChatResponse struct {
 	Grade           string                      `json:"grade,omitempty"`            // Letter grade (A-F) for the answer
 	Suggestions     []string                    `json:"suggestions,omitempty"`      // Improvement suggestions
 	ImprovedVersion string                      `json:"improved_version,omitempty"` // Suggested improved answer
+	Seed            *int64                      `json:"seed,omitempty"`             // Selection seed, returned for new sessions that can be replayed
+	BankVersion     string                      `json:"bank_version,omitempty"`     // Question bank the seed applies to
+	// Timed mode: deadlines for the question being returned, and verdict on the answer just sent
+	QuestionDeadline *time.Time `json:"question_deadline,omitempty"`
+	SessionDeadline  *time.Time `json:"session_deadline,omitempty"`
+	LatencyMs        int64      `json:"latency_ms,omitempty"`
+	Late             bool       `json:"late,omitempty"`
+	Skipped          bool       `json:"skipped,omitempty"`
+	TimeUp           bool       `json:"time_up,omitempty"`
+	// Retry: comparison with the previous attempt at the same question
+	Improvement *interview.AttemptImprovement `json:"improvement,omitempty"`
+	// Pause/resume: session state and progress through the selected questions
+	Status         interview.SessionStatus `json:"status,omitempty"`
+	QuestionNumber int                     `json:"question_number,omitempty"`
+	TotalQuestions int                     `json:"total_questions,omitempty"`
 }
 
 func (h *ChatHandler) Chat(c *gin.Context) {
 	var req ChatRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	// Keep the raw body around to fingerprint requests sent with an idempotency key
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
 		response.Error(c, http.StatusBadRequest, "invalid request body")
 		return
 	}
 
+	userID := h.currentUserID(c)
+
+	// Requests for the same session are handled one at a time
+	if req.SessionID != "" {
+		unlock := interview.LockSession(req.SessionID)
+		defer unlock()
+	}
+
 	// Get or create session
 	var session *interview.Session
 	var isNewSession bool
@@ -61,16 +111,27 @@ func (h *ChatHandler) Chat(c *gin.Context) {
 		if s, ok := interview.GetSession(req.SessionID); ok {
 			session = s
 			isNewSession = false
-		} else {
-			// Session not found, create new one with level
-			session = interview.NewSessionWithLevel("", req.Level)
-			interview.SaveSession(session)
-			isNewSession = true
 		}
-	} else {
-		// No session ID provided, create new session with level
-		session = interview.NewSessionWithLevel("", req.Level)
-		interview.SaveSession(session)
+	}
+	if session == nil {
+		// No usable session ID provided, create new session with level
+		var err error
+		session, err = h.newSession(c, userID, req)
+		if errors.Is(err, repository.ErrNotFound) {
+			response.Error(c, http.StatusNotFound, "cohort not found")
+			return
+		}
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "failed to start session")
+			return
+		}
+		if session == nil {
+			response.Error(c, http.StatusNotFound, "no questions are due for review")
+			return
+		}
+		if !h.save(c, session) {
+			return
+		}
 		isNewSession = true
 	}
 
@@ -79,6 +140,65 @@ func (h *ChatHandler) Chat(c *gin.Context) {
 		log.Printf("Creating session with level: %s, selected questions: %d", req.Level, len(session.SelectedQuestions))
 	}
 
+	if !isNewSession {
+		if session.UserID != "" && session.UserID != userID {
+			response.Error(c, http.StatusForbidden, "session belongs to another user")
+			return
+		}
+
+		// A retried request gets the stored response instead of being processed again
+		if key := idempotencyKey(c, req); key != "" {
+			hash := requestHash(c)
+			stored, err := interview.StoredResponse(session, key, hash, time.Now())
+			if err != nil {
+				response.Error(c, http.StatusUnprocessableEntity, err.Error())
+				return
+			}
+			if stored != nil {
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(http.StatusOK, "application/json; charset=utf-8", stored)
+				return
+			}
+			c.Set(ctxIdempotencyKey, key)
+			c.Set(ctxRequestHash, hash)
+		}
+		if interview.ExpireIfIdle(session, time.Now(), interview.IdleTimeout()) && !h.save(c, session) {
+			return
+		}
+		switch session.Status {
+		case interview.SessionStatusAborted:
+			response.Error(c, http.StatusGone, "session expired after being idle; start a new interview")
+			return
+		case interview.SessionStatusPaused:
+			response.Error(c, http.StatusConflict, "session is paused; resume it first")
+			return
+		}
+
+		if req.Action != "retry" {
+			// A resubmitted answer (double click, client retry) gets the recorded result back
+			if ans := interview.FindAnswerByID(session, req.AnswerID); ans != nil {
+				h.replay(c, session, ans)
+				return
+			}
+			// An answer aimed at a question the session has moved past is stale
+			submitting := req.Action == "skip" || len(req.Messages) > 0
+			if submitting && req.QuestionID != "" && req.QuestionID != session.CurrentQuestion &&
+				session.Status == interview.SessionStatusActive {
+				response.Error(c, http.StatusConflict, "session has moved on to another question; reload it")
+				return
+			}
+		}
+
+		switch req.Action {
+		case "skip":
+			h.skip(c, session, req.AnswerID)
+			return
+		case "retry":
+			h.retry(c, session, req)
+			return
+		}
+	}
+
 	// If session is finished, return completion message
 	if session.Status == interview.SessionStatusFinished {
 		completionMsg := buildCompletionMessage(session)
@@ -101,64 +221,53 @@ func (h *ChatHandler) Chat(c *gin.Context) {
 		currentQ := session.SelectedQuestions[0]
 
 		response.OK(c, ChatResponse{
-			Content:      currentQ.Text,
-			SessionID:    session.ID,
-			QuestionID:   currentQ.ID,
-			Finished:     false,
-			IsNewSession: isNewSession,
+			Content:          currentQ.Text,
+			SessionID:        session.ID,
+			QuestionID:       currentQ.ID,
+			Finished:         false,
+			IsNewSession:     isNewSession,
+			Seed:             session.Seed,
+			BankVersion:      session.BankVersion,
+			QuestionDeadline: session.QuestionDeadline,
+			SessionDeadline:  session.SessionDeadline,
 		})
 		return
 	}
 
 	// If no messages provided, return current question
 	if len(req.Messages) == 0 {
-		var currentQ *interview.Question
-		for i, q := range session.SelectedQuestions {
-			if q.ID == session.CurrentQuestion {
-				currentQ = &session.SelectedQuestions[i]
-				break
-			}
-		}
+		currentQ := interview.CurrentQuestion(session)
 		if currentQ == nil {
 			response.Error(c, http.StatusInternalServerError, "current question not found")
 			return
 		}
 
 		response.OK(c, ChatResponse{
-			Content:    currentQ.Text,
-			SessionID:  session.ID,
-			QuestionID: currentQ.ID,
-			Finished:   false,
-			Scores:     &session.Scores,
+			Content:          currentQ.Text,
+			SessionID:        session.ID,
+			QuestionID:       currentQ.ID,
+			Finished:         false,
+			Scores:           &session.Scores,
+			QuestionDeadline: session.QuestionDeadline,
+			SessionDeadline:  session.SessionDeadline,
 		})
 		return
 	}
 
 	// Find the last user message
-	var lastUserMessage string
-	for i := len(req.Messages) - 1; i >= 0; i-- {
-		if req.Messages[i].Role == "user" {
-			lastUserMessage = req.Messages[i].Content
-			break
-		}
-	}
-
+	lastUserMessage := latestUserMessage(req)
 	if lastUserMessage == "" {
 		response.Error(c, http.StatusBadRequest, "no user message found")
 		return
 	}
 
 	// Get current question
-	var currentQ *interview.Question
-	for i, q := range session.SelectedQuestions {
-		if q.ID == session.CurrentQuestion {
-			currentQ = &session.SelectedQuestions[i]
-			break
-		}
-	}
+	currentQ := interview.CurrentQuestion(session)
 	if currentQ == nil {
 		session.Status = interview.SessionStatusFinished
-		interview.SaveSession(session)
+		if !h.save(c, session) {
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "current question not found")
 		return
 	}
@@ -174,70 +283,54 @@ func (h *ChatHandler) Chat(c *gin.Context) {
 
 	// If we've already answered this question, just return the next question
 	if alreadyAnswered {
-		// Move to next question
-		session.QuestionIndex++
-		if session.QuestionIndex >= len(session.SelectedQuestions) {
-			session.Status = interview.SessionStatusFinished
-
-			// Generate session summary before completing
-			summary, err := interview.GenerateSessionSummary(session)
-			if err == nil && summary != nil {
-				session.Summary = summary
-			}
-
-			interview.SaveSession(session)
+		h.advance(c, session, ChatResponse{})
+		return
+	}
 
-			completionMsg := buildCompletionMessage(session)
-			response.OK(c, ChatResponse{
-				Content:   completionMsg,
-				SessionID: session.ID,
-				Finished:  true,
-				Scores:    &session.Scores,
-			})
-			return
-		}
+	// Enforce timed-mode deadlines server-side
+	now := time.Now()
+	timing := interview.CheckTiming(session, now)
+	if timing.TimeUp {
+		h.finishSession(c, session, ChatResponse{TimeUp: true})
+		return
+	}
 
-		// Update session with next question
-		nextQ := session.SelectedQuestions[session.QuestionIndex]
-		session.CurrentQuestion = nextQ.ID
-		interview.SaveSession(session)
+	// Record the answer
+	answer := interview.Answer{
+		QuestionID:   currentQ.ID,
+		QuestionText: currentQ.Text,
+		Text:         lastUserMessage,
+		CreatedAt:    now,
+		AnswerID:     req.AnswerID,
+		LatencyMs:    timing.Latency.Milliseconds(),
+		Late:         timing.Late,
+	}
 
-		response.OK(c, ChatResponse{
-			Content:    nextQ.Text,
-			SessionID:  session.ID,
-			QuestionID: nextQ.ID,
-			Finished:   false,
-			Scores:     &session.Scores,
-		})
+	// Late answers under the skip policy are recorded but never graded
+	if timing.Late && session.TimeLimits.LatePolicy == interview.LatePolicySkip {
+		answer.Skipped = true
+		answer.Attempts = []interview.Attempt{interview.AttemptFromAnswer(answer)}
+		session.Answers = append(session.Answers, answer)
+		h.advance(c, session, ChatResponse{LatencyMs: answer.LatencyMs, Late: true, Skipped: true})
 		return
 	}
 
 	// Save college/major to database if these questions are answered
 	if currentQ.ID == "q0_college" || currentQ.ID == "q0_major" {
-		claims := c.MustGet("user").(*middleware.MyClaims)
-		user, err := h.userSvc.GetByEmail(c.Request.Context(), claims.Email)
-		if err == nil {
+		if userID != "" {
 			updateDTO := models.UpdateUserDTO{}
 			if currentQ.ID == "q0_college" {
 				updateDTO.College = &lastUserMessage
 			} else if currentQ.ID == "q0_major" {
 				updateDTO.Major = &lastUserMessage
 			}
-			_, err = h.userSvc.Update(c.Request.Context(), user.ID, updateDTO)
+			_, err := h.userSvc.Update(c.Request.Context(), userID, updateDTO)
 			if err != nil {
 				log.Printf("Failed to save %s to database: %v", currentQ.ID, err)
 			}
 		}
 	}
 
-	// Record the answer
-	answer := interview.Answer{
-		QuestionID:   currentQ.ID,
-		QuestionText: currentQ.Text,
-		Text:         lastUserMessage,
-		CreatedAt:    time.Now(),
-	}
-
 	// Call new analyzer for detailed feedback with session context
 	analysis, err := interview.AnalyzeAnswer(session, *currentQ, lastUserMessage)
 	if err != nil {
@@ -252,60 +345,383 @@ func (h *ChatHandler) Chat(c *gin.Context) {
 
 	// Attach analysis to answer
 	if analysis != nil {
+		// Penalise late answers and add latency advice before scoring
+		interview.ApplyTimingFeedback(analysis, session.TimeLimits, timing)
 		answer.Analysis = analysis
 		// Also create EvalResult for backward compatibility with scoring system
 		eval := interview.ConvertAnalysisToEval(analysis, *currentQ)
 		answer.Eval = eval
 		// Update scores using the converted eval
 		interview.ApplyEval(session, eval)
+		// Reschedule this question in the user's spaced-repetition queue
+		if _, err := interview.RecordReview(session.UserID, *currentQ, analysis.Scores.TotalScore, answer.CreatedAt); err != nil {
+			log.Printf("Error scheduling review for %s: %v", session.UserID, err)
+		}
 	}
 
+	answer.Attempts = []interview.Attempt{interview.AttemptFromAnswer(answer)}
 	session.Answers = append(session.Answers, answer)
 
-	// Move to next question
-	session.QuestionIndex++
-	if session.QuestionIndex >= len(session.SelectedQuestions) {
-		// All questions answered
-		session.Status = interview.SessionStatusFinished
+	h.advance(c, session, ChatResponse{
+		Analysis:        analysis,
+		Grade:           getGradeFromAnalysis(analysis),
+		Suggestions:     getSuggestionsFromAnalysis(analysis),
+		ImprovedVersion: getImprovedVersionFromAnalysis(analysis),
+		LatencyMs:       answer.LatencyMs,
+		Late:            answer.Late,
+	})
+}
+
+// skip records the current question as skipped and moves on
+func (h *ChatHandler) skip(c *gin.Context, session *interview.Session, answerID string) {
+	if session.Status != interview.SessionStatusActive {
+		response.Error(c, http.StatusConflict, "session is not active")
+		return
+	}
+	currentQ := interview.CurrentQuestion(session)
+	if currentQ == nil {
+		response.Error(c, http.StatusInternalServerError, "current question not found")
+		return
+	}
+	if interview.FindAnswer(session, currentQ.ID) == nil {
+		interview.SkipQuestion(session, *currentQ, time.Now())
+		session.Answers[len(session.Answers)-1].AnswerID = answerID
+	}
+	h.advance(c, session, ChatResponse{Skipped: true})
+}
+
+// retry grades another attempt at an answered (or skipped) question without moving the
+// session forward, and reports how it compares with the previous attempt
+func (h *ChatHandler) retry(c *gin.Context, session *interview.Session, req ChatRequest) {
+	text := latestUserMessage(req)
+	if text == "" {
+		response.Error(c, http.StatusBadRequest, "no user message found")
+		return
+	}
+
+	questionID := req.QuestionID
+	if questionID == "" {
+		questionID = session.CurrentQuestion
+	}
+	var question *interview.Question
+	for i, q := range session.SelectedQuestions {
+		if q.ID == questionID {
+			question = &session.SelectedQuestions[i]
+			break
+		}
+	}
+	previous := interview.FindAnswer(session, questionID)
+	if question == nil || previous == nil {
+		response.Error(c, http.StatusBadRequest, "question has not been answered yet")
+		return
+	}
 
-		// Generate session summary before completing
-		summary, err := interview.GenerateSessionSummary(session)
-		if err == nil && summary != nil {
+	// Timed sessions hold retries to the question's original deadline and late policy
+	now := time.Now()
+	timing := interview.CheckRetryTiming(session, previous, now)
+	attempt := interview.Attempt{Text: text, CreatedAt: now, LatencyMs: timing.Latency.Milliseconds(), Late: timing.Late}
+	analysis, err := interview.AnalyzeAnswer(session, *question, text)
+	if err != nil {
+		log.Printf("Error analyzing retry: %v", err)
+		analysis = nil
+	}
+	if analysis != nil {
+		interview.ApplyTimingFeedback(analysis, session.TimeLimits, timing)
+		attempt.Analysis = analysis
+		attempt.Eval = interview.ConvertAnalysisToEval(analysis, *question)
+		if _, err := interview.RecordReview(session.UserID, *question, analysis.Scores.TotalScore, attempt.CreatedAt); err != nil {
+			log.Printf("Error scheduling review for %s: %v", session.UserID, err)
+		}
+	}
+
+	improvement, err := interview.RecordRetry(session, questionID, attempt, req.Replace)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// A revised answer in a finished session changes its summary
+	if improvement.Counted && session.Status == interview.SessionStatusFinished {
+		if summary, err := interview.GenerateSessionSummary(session); err == nil && summary != nil {
 			session.Summary = summary
 		}
+	}
+	// Hand the conversation back to wherever the session was
+	h.commit(c, session, withSessionState(session, ChatResponse{
+		Analysis:    analysis,
+		Grade:       getGradeFromAnalysis(analysis),
+		Suggestions: getSuggestionsFromAnalysis(analysis),
+		Improvement: improvement,
+		LatencyMs:   attempt.LatencyMs,
+		Late:        attempt.Late,
+	}))
+}
+
+// replay answers a resubmitted answer ID with what was recorded the first time,
+// without grading it again or moving the session on
+func (h *ChatHandler) replay(c *gin.Context, session *interview.Session, ans *interview.Answer) {
+	response.OK(c, withSessionState(session, ChatResponse{
+		Analysis:    ans.Analysis,
+		Grade:       getGradeFromAnalysis(ans.Analysis),
+		Suggestions: getSuggestionsFromAnalysis(ans.Analysis),
+		LatencyMs:   ans.LatencyMs,
+		Late:        ans.Late,
+		Skipped:     ans.Skipped,
+	}))
+}
 
-		interview.SaveSession(session)
+// withSessionState fills resp with the session's current question, or its completion
+// message once finished
+func withSessionState(session *interview.Session, resp ChatResponse) ChatResponse {
+	resp.SessionID = session.ID
+	resp.Scores = &session.Scores
+	if session.Status == interview.SessionStatusActive {
+		if currentQ := interview.CurrentQuestion(session); currentQ != nil {
+			resp.Content = currentQ.Text
+			resp.QuestionID = currentQ.ID
+			resp.QuestionDeadline = session.QuestionDeadline
+			resp.SessionDeadline = session.SessionDeadline
+		}
+	} else {
+		resp.Content = buildCompletionMessage(session)
+		resp.Finished = true
+	}
+	return resp
+}
 
-		completionMsg := buildCompletionMessage(session)
-		response.OK(c, ChatResponse{
-			Content:   completionMsg,
-			SessionID: session.ID,
-			Finished:  true,
-			Scores:    &session.Scores,
-			Analysis:  analysis,
-			Grade:     getGradeFromAnalysis(analysis),
-		})
+// commit saves the session and responds with resp. When the request carries an idempotency
+// key, the response is stored with the session in the same save so retries get it back.
+func (h *ChatHandler) commit(c *gin.Context, session *interview.Session, resp ChatResponse) {
+	if key := c.GetString(ctxIdempotencyKey); key != "" {
+		body, err := json.Marshal(gin.H{"data": resp})
+		if err == nil {
+			interview.RememberResponse(session, key, c.GetString(ctxRequestHash), body, time.Now())
+		} else {
+			log.Printf("Failed to store idempotent response for session %s: %v", session.ID, err)
+		}
+	}
+	if !h.save(c, session) {
+		return
+	}
+	response.OK(c, resp)
+}
+
+// idempotencyKey returns the request's Idempotency-Key header, falling back to its answer ID
+func idempotencyKey(c *gin.Context, req ChatRequest) string {
+	if key := strings.TrimSpace(c.GetHeader("Idempotency-Key")); key != "" {
+		return key
+	}
+	return req.AnswerID
+}
+
+// requestHash fingerprints the raw request body kept by ShouldBindBodyWith
+func requestHash(c *gin.Context) string {
+	body, _ := c.Get(gin.BodyBytesKey)
+	raw, _ := body.([]byte)
+	return interview.RequestHash(raw)
+}
+
+// save stores the session, answering 409 when another request saved it first
+func (h *ChatHandler) save(c *gin.Context, session *interview.Session) bool {
+	if err := interview.SaveSession(session); err != nil {
+		response.Error(c, http.StatusConflict, "session was changed by another request; reload it and try again")
+		return false
+	}
+	return true
+}
+
+// latestUserMessage returns the content of the latest user message in the request
+func latestUserMessage(req ChatRequest) string {
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if req.Messages[i].Role == "user" {
+			return req.Messages[i].Content
+		}
+	}
+	return ""
+}
+
+// advance moves the session to its next question (or finishes it), saves it and responds.
+// resp carries feedback about the answer just recorded.
+func (h *ChatHandler) advance(c *gin.Context, session *interview.Session, resp ChatResponse) {
+	nextQ := interview.AdvanceQuestion(session, time.Now())
+	if nextQ == nil {
+		// All questions answered
+		h.finishSession(c, session, resp)
 		return
 	}
 
 	// Update session with next question
-	nextQ := session.SelectedQuestions[session.QuestionIndex]
-	session.CurrentQuestion = nextQ.ID
-	interview.SaveSession(session)
+	resp.Content = nextQ.Text
+	resp.SessionID = session.ID
+	resp.QuestionID = nextQ.ID
+	resp.Finished = false
+	resp.Scores = &session.Scores
+	resp.QuestionDeadline = session.QuestionDeadline
+	resp.SessionDeadline = session.SessionDeadline
+	h.commit(c, session, resp)
+}
+
+// finishSession marks the session finished, generates its summary, saves it and responds
+func (h *ChatHandler) finishSession(c *gin.Context, session *interview.Session, resp ChatResponse) {
+	session.Status = interview.SessionStatusFinished
 
+	// Generate session summary before completing
+	summary, err := interview.GenerateSessionSummary(session)
+	if err == nil && summary != nil {
+		session.Summary = summary
+	}
+
+	resp.Content = buildCompletionMessage(session)
+	resp.SessionID = session.ID
+	resp.Finished = true
+	resp.Scores = &session.Scores
+	// Suggestions belong to the next question's prompt; the summary replaces them
+	resp.Suggestions = nil
+	resp.ImprovedVersion = ""
+	h.commit(c, session, resp)
+}
+
+// Pause stops an active interview so it can be resumed later, possibly on another device.
+// POST /api/v1/interviews/:id/pause
+func (h *ChatHandler) Pause(c *gin.Context) {
+	unlock := interview.LockSession(c.Param("id"))
+	defer unlock()
+
+	session, ok := h.ownedSession(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	if err := interview.PauseSession(session, time.Now()); err != nil {
+		response.Error(c, http.StatusConflict, err.Error())
+		return
+	}
+	if !h.save(c, session) {
+		return
+	}
 	response.OK(c, ChatResponse{
-		Content:         nextQ.Text,
-		SessionID:       session.ID,
-		QuestionID:      nextQ.ID,
-		Finished:        false,
-		Scores:          &session.Scores,
-		Analysis:        analysis,
-		Grade:           getGradeFromAnalysis(analysis),
-		Suggestions:     getSuggestionsFromAnalysis(analysis),
-		ImprovedVersion: getImprovedVersionFromAnalysis(analysis),
+		SessionID:      session.ID,
+		QuestionID:     session.CurrentQuestion,
+		Status:         session.Status,
+		QuestionNumber: session.QuestionIndex + 1,
+		TotalQuestions: len(session.SelectedQuestions),
+		Scores:         &session.Scores,
+	})
+}
+
+type ResumeRequest struct {
+	SessionID string `json:"session_id,omitempty"` // Optional: defaults to the caller's latest unfinished interview
+}
+
+// Resume reactivates a paused interview and returns its current question.
+// Without a session ID it picks the caller's most recent active or paused interview.
+// POST /api/v1/interviews/resume
+func (h *ChatHandler) Resume(c *gin.Context) {
+	var req ResumeRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		response.Error(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	var session *interview.Session
+	if req.SessionID != "" {
+		unlock := interview.LockSession(req.SessionID)
+		defer unlock()
+		s, ok := h.ownedSession(c, req.SessionID)
+		if !ok {
+			return
+		}
+		session = s
+	} else {
+		userID := h.currentUserID(c)
+		s, ok := interview.LatestOpenSession(userID, time.Now())
+		if userID == "" || !ok {
+			response.Error(c, http.StatusNotFound, "no unfinished interview found")
+			return
+		}
+		session = s
+	}
+
+	if err := interview.ResumeSession(session, time.Now()); err != nil {
+		response.Error(c, http.StatusConflict, err.Error())
+		return
+	}
+	if !h.save(c, session) {
+		return
+	}
+
+	currentQ := interview.CurrentQuestion(session)
+	if currentQ == nil {
+		response.Error(c, http.StatusInternalServerError, "current question not found")
+		return
+	}
+	response.OK(c, ChatResponse{
+		Content:          currentQ.Text,
+		SessionID:        session.ID,
+		QuestionID:       currentQ.ID,
+		Scores:           &session.Scores,
+		Status:           session.Status,
+		QuestionNumber:   session.QuestionIndex + 1,
+		TotalQuestions:   len(session.SelectedQuestions),
+		QuestionDeadline: session.QuestionDeadline,
+		SessionDeadline:  session.SessionDeadline,
 	})
 }
 
+// ownedSession loads a session the caller owns, expiring it if it sat idle too long.
+// It writes the error response and returns false when the session can't be used.
+func (h *ChatHandler) ownedSession(c *gin.Context, id string) (*interview.Session, bool) {
+	session, ok := interview.GetSession(id)
+	if !ok || session.UserID == "" || session.UserID != h.currentUserID(c) {
+		response.Error(c, http.StatusNotFound, "session not found")
+		return nil, false
+	}
+	if interview.ExpireIfIdle(session, time.Now(), interview.IdleTimeout()) && !h.save(c, session) {
+		return nil, false
+	}
+	return session, true
+}
+
+// currentUserID resolves the authenticated user's ID, or "" if the user can't be found
+func (h *ChatHandler) currentUserID(c *gin.Context) string {
+	claims := c.MustGet("user").(*middleware.MyClaims)
+	user, err := h.userSvc.Get(c.Request.Context(), claims.Subject)
+	if err != nil {
+		return ""
+	}
+	return user.ID
+}
+
+// newSession creates a session for the request. Without an explicit seed, selection
+// is weighted by the user's practice history so returning students see fresh questions.
+// A cohort session uses the cohort's assigned level and question set.
+// Practice mode returns nil when the user has nothing due for review.
+func (h *ChatHandler) newSession(c *gin.Context, userID string, req ChatRequest) (*interview.Session, error) {
+	if req.Mode == string(interview.SessionTypePractice) {
+		return interview.NewPracticeSession(userID, interview.DefaultPracticeSize, time.Now())
+	}
+
+	opts := interview.SessionOptions{Level: req.Level}
+	if req.CohortID != "" {
+		if userID == "" {
+			return nil, repository.ErrNotFound
+		}
+		cohortOpts, err := h.cohortSvc.SessionOptions(c.Request.Context(), req.CohortID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if cohortOpts.Level == "" {
+			cohortOpts.Level = req.Level
+		}
+		opts = cohortOpts
+	}
+	opts.Seed = req.Seed
+	opts.TimeLimits = req.TimeLimits
+	if req.Seed == nil && userID != "" && opts.Questions == nil {
+		opts.History = interview.BuildQuestionHistory(userID)
+	}
+	return interview.NewSessionWithOptions(userID, opts), nil
+}
+
 // buildCompletionMessage creates a completion message based on session summary or scores
 func buildCompletionMessage(session *interview.Session) string {
 	// Use session summary if available (new grading system)
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"altoai_mvp/internal/middleware"
+	"altoai_mvp/internal/models"
+	"altoai_mvp/internal/repository"
+	"altoai_mvp/internal/services"
+	errs "altoai_mvp/pkg/errors"
+	"altoai_mvp/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CohortHandler serves cohorts: counsellors run the cohorts they belong to, admins run all of
+// them, and students see the cohorts they joined
+type CohortHandler struct {
+	userSvc   services.UserService
+	cohortSvc services.CohortService
+}
+
+func NewCohortHandler(userSvc services.UserService, cohortSvc services.CohortService) *CohortHandler {
+	return &CohortHandler{userSvc: userSvc, cohortSvc: cohortSvc}
+}
+
+// List returns every cohort to admins and the caller's own cohorts to everyone else
+func (h *CohortHandler) List(c *gin.Context) {
+	claims := c.MustGet("user").(*middleware.MyClaims)
+	var cohorts []models.Cohort
+	var err error
+	if middleware.HasPermission(middleware.ClaimsRole(claims), middleware.PermManageAllCohorts) {
+		cohorts, err = h.cohortSvc.List(c.Request.Context())
+	} else {
+		cohorts, err = h.cohortSvc.ListForUser(c.Request.Context(), claims.Subject)
+	}
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to list cohorts")
+		return
+	}
+	response.OK(c, cohorts)
+}
+
+func (h *CohortHandler) Create(c *gin.Context) {
+	var dto models.CreateCohortDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		response.ValidationError(c, errs.FromBinding(err))
+		return
+	}
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	cohort, err := h.cohortSvc.Create(c.Request.Context(), user, dto)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to create cohort")
+		return
+	}
+	response.Created(c, cohort)
+}
+
+// Get returns a cohort, including its assigned practice, to its members
+func (h *CohortHandler) Get(c *gin.Context) {
+	cohort, ok := h.cohort(c, false)
+	if !ok {
+		return
+	}
+	response.OK(c, cohort)
+}
+
+func (h *CohortHandler) Delete(c *gin.Context) {
+	cohort, ok := h.cohort(c, true)
+	if !ok {
+		return
+	}
+	if err := h.cohortSvc.Delete(c.Request.Context(), cohort.ID); err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to delete cohort")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *CohortHandler) Members(c *gin.Context) {
+	cohort, ok := h.cohort(c, true)
+	if !ok {
+		return
+	}
+	members, err := h.cohortSvc.Members(c.Request.Context(), cohort.ID)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to list members")
+		return
+	}
+	response.OK(c, members)
+}
+
+func (h *CohortHandler) RemoveMember(c *gin.Context) {
+	cohort, ok := h.cohort(c, true)
+	if !ok {
+		return
+	}
+	err := h.cohortSvc.RemoveMember(c.Request.Context(), cohort.ID, c.Param("userId"))
+	if errors.Is(err, repository.ErrNotFound) {
+		response.Error(c, http.StatusNotFound, "member not found")
+		return
+	}
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to remove member")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Invite emails an invitation to join the cohort
+func (h *CohortHandler) Invite(c *gin.Context) {
+	var dto models.CohortInviteDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		response.ValidationError(c, errs.FromBinding(err))
+		return
+	}
+	cohort, ok := h.cohort(c, true)
+	if !ok {
+		return
+	}
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	invite, err := h.cohortSvc.Invite(c.Request.Context(), cohort.ID, user, dto)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to send invite")
+		return
+	}
+	response.Created(c, invite)
+}
+
+func (h *CohortHandler) Invites(c *gin.Context) {
+	cohort, ok := h.cohort(c, true)
+	if !ok {
+		return
+	}
+	invites, err := h.cohortSvc.Invites(c.Request.Context(), cohort.ID)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to list invites")
+		return
+	}
+	response.OK(c, invites)
+}
+
+// Join accepts an emailed invite for the signed-in user
+func (h *CohortHandler) Join(c *gin.Context) {
+	var dto models.AcceptCohortInviteDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		response.ValidationError(c, errs.FromBinding(err))
+		return
+	}
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	cohort, err := h.cohortSvc.AcceptInvite(c.Request.Context(), user, dto.Code)
+	switch {
+	case err == nil:
+		response.OK(c, cohort)
+	case errors.Is(err, services.ErrInviteInvalid):
+		response.Error(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrInviteEmail):
+		response.Error(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrNotCounsellor), errors.Is(err, services.ErrNotStudent):
+		response.Error(c, http.StatusUnprocessableEntity, err.Error())
+	default:
+		response.Error(c, http.StatusInternalServerError, "failed to join cohort")
+	}
+}
+
+// SetAssignment sets the level and question set the cohort's sessions use
+func (h *CohortHandler) SetAssignment(c *gin.Context) {
+	var dto models.CohortAssignmentDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		response.ValidationError(c, errs.FromBinding(err))
+		return
+	}
+	cohort, ok := h.cohort(c, true)
+	if !ok {
+		return
+	}
+	cohort, err := h.cohortSvc.SetAssignment(c.Request.Context(), cohort.ID, dto)
+	if errors.Is(err, services.ErrUnknownCategory) {
+		response.Error(c, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to update cohort")
+		return
+	}
+	response.OK(c, cohort)
+}
+
+// Dashboard returns each student's sessions, criterion averages and latest summary
+func (h *CohortHandler) Dashboard(c *gin.Context) {
+	cohort, ok := h.cohort(c, true)
+	if !ok {
+		return
+	}
+	dash, err := h.cohortSvc.Dashboard(c.Request.Context(), cohort.ID)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to build dashboard")
+		return
+	}
+	response.OK(c, dash)
+}
+
+// cohort loads the cohort in the :id parameter if the caller may see it, or manage it when
+// manage is set: admins always, counsellors only in cohorts they counsel. Anyone else gets
+// the same 404 as for a missing cohort.
+func (h *CohortHandler) cohort(c *gin.Context, manage bool) (models.Cohort, bool) {
+	claims := c.MustGet("user").(*middleware.MyClaims)
+	role := middleware.ClaimsRole(claims)
+	ctx := c.Request.Context()
+	id := c.Param("id")
+
+	allowed := middleware.HasPermission(role, middleware.PermManageAllCohorts)
+	if !allowed {
+		member, err := h.cohortSvc.Member(ctx, id, claims.Subject)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			response.Error(c, http.StatusInternalServerError, "failed to check membership")
+			return models.Cohort{}, false
+		}
+		allowed = err == nil
+		if manage {
+			allowed = allowed && member.Role == models.CohortRoleCounsellor &&
+				middleware.HasPermission(role, middleware.PermManageCohorts)
+		}
+	}
+	if !allowed {
+		response.Error(c, http.StatusNotFound, "cohort not found")
+		return models.Cohort{}, false
+	}
+
+	cohort, err := h.cohortSvc.Get(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		response.Error(c, http.StatusNotFound, "cohort not found")
+		return models.Cohort{}, false
+	}
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to get cohort")
+		return models.Cohort{}, false
+	}
+	return cohort, true
+}
+
+func (h *CohortHandler) currentUser(c *gin.Context) (models.User, bool) {
+	claims := c.MustGet("user").(*middleware.MyClaims)
+	user, err := h.userSvc.Get(c.Request.Context(), claims.Subject)
+	if err != nil {
+		response.Error(c, http.StatusUnauthorized, "user not found")
+		return models.User{}, false
+	}
+	return user, true
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	"altoai_mvp/internal/middleware"
+	"altoai_mvp/internal/models"
+	"altoai_mvp/internal/services"
+	errs "altoai_mvp/pkg/errors"
+	"altoai_mvp/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mfaError answers for an error from the MFA service
+func mfaError(c *gin.Context, err error) {
+	if lockedOut(c, err) {
+		return
+	}
+	switch err {
+	case services.ErrMFAChallengeInvalid, services.ErrInvalidMFACode:
+		response.Error(c, http.StatusUnauthorized, err.Error())
+	case services.ErrMFARequiredByRole:
+		response.Error(c, http.StatusForbidden, err.Error())
+	case services.ErrMFAAlreadyEnabled, services.ErrMFANotEnabled, services.ErrMFANotEnrolling:
+		response.Error(c, http.StatusConflict, err.Error())
+	default:
+		log.Printf("MFA request failed: %v", err)
+		response.Error(c, http.StatusInternalServerError, "two-factor authentication failed")
+	}
+}
+
+// VerifyMFA finishes a login with a code from the authenticator app or a recovery code.
+// POST /api/v1/auth/mfa/verify {"mfa_token": "...", "code": "123456"}
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+	var dto models.MFAChallengeDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		response.ValidationError(c, errs.FromBinding(err))
+		return
+	}
+
+	accessToken, refreshToken, user, err := h.authSvc.VerifyMFA(clientContext(c), dto)
+	if err != nil {
+		mfaError(c, err)
+		return
+	}
+	loggedIn(c, accessToken, refreshToken, user)
+}
+
+// SetupMFA starts setting up an authenticator app during a login whose challenge has
+// mfa_setup_required, for roles that must use MFA.
+// POST /api/v1/auth/mfa/setup {"mfa_token": "..."}
+func (h *AuthHandler) SetupMFA(c *gin.Context) {
+	var dto models.MFASetupDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		response.ValidationError(c, errs.FromBinding(err))
+		return
+	}
+
+	user, err := h.authSvc.MFAChallengeUser(c.Request.Context(), dto.MFAToken)
+	if err != nil {
+		mfaError(c, err)
+		return
+	}
+	enrollment, err := h.authSvc.BeginTOTPEnrollment(c.Request.Context(), user.ID)
+	if err != nil {
+		mfaError(c, err)
+		return
+	}
+	response.OK(c, enrollment)
+}
+
+// ConfirmMFASetup turns MFA on with a first code from the app and completes the login.
+// The recovery codes are only ever returned here.
+// POST /api/v1/auth/mfa/setup/confirm {"mfa_token": "...", "code": "123456"}
+func (h *AuthHandler) ConfirmMFASetup(c *gin.Context) {
+	var dto models.MFAChallengeDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		response.ValidationError(c, errs.FromBinding(err))
+		return
+	}
+
+	ctx := clientContext(c)
+	user, err := h.authSvc.MFAChallengeUser(ctx, dto.MFAToken)
+	if err != nil {
+		mfaError(c, err)
+		return
+	}
+	codes, err := h.authSvc.ConfirmTOTPEnrollment(ctx, user.ID, dto.Code)
+	if err != nil {
+		mfaError(c, err)
+		return
+	}
+	accessToken, refreshToken, err := h.authSvc.IssueTokens(ctx, user, "")
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "could not issue tokens")
+		return
+	}
+
+	c.SetCookie("refresh_token", refreshToken, 30*24*60*60, "/", os.Getenv("COOKIE_DOMAIN"), os.Getenv("GIN_MODE") == "release", true)
+	response.OK(c, gin.H{
+		"access_token": accessToken,
+		"user": gin.H{
+			"email": user.Email,
+			"name":  user.Name,
+		},
+		"recovery_codes": codes,
+	})
+}
+
+// MFAStatus reports whether the caller has MFA on and whether their role requires it.
+// GET /api/v1/auth/mfa
+func (h *AuthHandler) MFAStatus(c *gin.Context) {
+	claims := c.MustGet("user").(*middleware.MyClaims)
+
+	status, err := h.authSvc.MFAStatus(c.Request.Context(), claims.Subject)
+	if err != nil {
+		mfaError(c, err)
+		return
+	}
+	response.OK(c, status)
+}
+
+// EnrollTOTP generates a secret and otpauth:// URI for the caller's authenticator app.
+// POST /api/v1/auth/mfa/totp
+func (h *AuthHandler) EnrollTOTP(c *gin.Context) {
+	claims := c.MustGet("user").(*middleware.MyClaims)
+
+	enrollment, err := h.authSvc.BeginTOTPEnrollment(c.Request.Context(), claims.Subject)
+	if err != nil {
+		mfaError(c, err)
+		return
+	}
+	response.OK(c, enrollment)
+}
+
+// ConfirmTOTP turns MFA on with a first code from the app and returns the recovery codes.
+// POST /api/v1/auth/mfa/totp/confirm {"code": "123456"}
+func (h *AuthHandler) ConfirmTOTP(c *gin.Context) {
+	claims := c.MustGet("user").(*middleware.MyClaims)
+	var dto models.MFACodeDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		response.ValidationError(c, errs.FromBinding(err))
+		return
+	}
+
+	codes, err := h.authSvc.ConfirmTOTPEnrollment(clientContext(c), claims.Subject, dto.Code)
+	if err != nil {
+		mfaError(c, err)
+		return
+	}
+	response.OK(c, gin.H{"recovery_codes": codes})
+}
+
+// DisableMFA turns MFA off, given a current code.
+// POST /api/v1/auth/mfa/disable {"code": "123456"}
+func (h *AuthHandler) DisableMFA(c *gin.Context) {
+	claims := c.MustGet("user").(*middleware.MyClaims)
+	var dto models.MFACodeDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		response.ValidationError(c, errs.FromBinding(err))
+		return
+	}
+
+	if err := h.authSvc.DisableMFA(clientContext(c), claims.Subject, dto.Code); err != nil {
+		mfaError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes replaces the caller's recovery codes, given a current code.
+// POST /api/v1/auth/mfa/recovery-codes {"code": "123456"}
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	claims := c.MustGet("user").(*middleware.MyClaims)
+	var dto models.MFACodeDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		response.ValidationError(c, errs.FromBinding(err))
+		return
+	}
+
+	codes, err := h.authSvc.RegenerateRecoveryCodes(clientContext(c), claims.Subject, dto.Code)
+	if err != nil {
+		mfaError(c, err)
+		return
+	}
+	response.OK(c, gin.H{"recovery_codes": codes})
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"altoai_mvp/internal/middleware"
+	"altoai_mvp/internal/repository"
+	"altoai_mvp/internal/services"
+	"altoai_mvp/interview"
+	"altoai_mvp/pkg/response"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PracticeHandler struct {
+	userSvc services.UserService
+}
+
+func NewPracticeHandler(userSvc services.UserService) *PracticeHandler {
+	return &PracticeHandler{userSvc: userSvc}
+}
+
+// Due lists the questions the caller should review today.
+// Start a drill with POST /api/v1/chat and "mode": "practice".
+func (h *PracticeHandler) Due(c *gin.Context) {
+	claims := c.MustGet("user").(*middleware.MyClaims)
+	user, err := h.userSvc.Get(c.Request.Context(), claims.Subject)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			response.Error(c, http.StatusNotFound, "user not found")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "failed to get user")
+		return
+	}
+
+	due, err := interview.DueReviews(user.ID, time.Now())
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to load reviews")
+		return
+	}
+	if due == nil {
+		due = []interview.ReviewItem{}
+	}
+	response.OK(c, gin.H{
+		"count":        len(due),
+		"session_size": min(len(due), interview.DefaultPracticeSize),
+		"items":        due,
+	})
+}
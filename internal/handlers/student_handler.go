@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"altoai_mvp/internal/middleware"
+	"altoai_mvp/internal/repository"
+	"altoai_mvp/internal/services"
+	"altoai_mvp/interview"
+	"altoai_mvp/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StudentHandler serves counsellors' views of their students and admins' assignment routes
+type StudentHandler struct {
+	userSvc       services.UserService
+	counsellorSvc services.CounsellorService
+}
+
+func NewStudentHandler(userSvc services.UserService, counsellorSvc services.CounsellorService) *StudentHandler {
+	return &StudentHandler{userSvc: userSvc, counsellorSvc: counsellorSvc}
+}
+
+// List returns the students assigned to the calling counsellor
+func (h *StudentHandler) List(c *gin.Context) {
+	claims := c.MustGet("user").(*middleware.MyClaims)
+	students, err := h.counsellorSvc.ListStudents(c.Request.Context(), claims.Subject)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to list students")
+		return
+	}
+	response.OK(c, students)
+}
+
+// Interviews returns a student's interview sessions, newest first. Counsellors only see
+// students assigned to them; admins see everyone.
+func (h *StudentHandler) Interviews(c *gin.Context) {
+	claims := c.MustGet("user").(*middleware.MyClaims)
+	studentID := c.Param("id")
+
+	if !middleware.HasPermission(middleware.ClaimsRole(claims), middleware.PermViewAllStudents) {
+		assigned, err := h.counsellorSvc.IsAssigned(c.Request.Context(), claims.Subject, studentID)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "failed to check assignment")
+			return
+		}
+		if !assigned {
+			// Same answer as a missing student, so counsellors can't probe for other accounts
+			response.Error(c, http.StatusNotFound, "student not found")
+			return
+		}
+	}
+
+	student, err := h.userSvc.Get(c.Request.Context(), studentID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			response.Error(c, http.StatusNotFound, "student not found")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "failed to get student")
+		return
+	}
+
+	sessions := interview.UserSessionHistory(student.ID)
+	for _, s := range sessions {
+		s.IdempotencyKeys = nil // stored responses are the student's, not part of the results
+	}
+	if sessions == nil {
+		sessions = []*interview.Session{}
+	}
+	response.OK(c, gin.H{"student": student, "sessions": sessions})
+}
+
+// Assign puts a student in a counsellor's care
+func (h *StudentHandler) Assign(c *gin.Context) {
+	err := h.counsellorSvc.AssignStudent(c.Request.Context(), c.Param("id"), c.Param("studentId"))
+	if err != nil {
+		h.assignmentError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Unassign removes a student from a counsellor
+func (h *StudentHandler) Unassign(c *gin.Context) {
+	err := h.counsellorSvc.UnassignStudent(c.Request.Context(), c.Param("id"), c.Param("studentId"))
+	if err != nil {
+		h.assignmentError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *StudentHandler) assignmentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		response.Error(c, http.StatusNotFound, "assignment not found")
+	case errors.Is(err, services.ErrNotCounsellor), errors.Is(err, services.ErrNotStudent):
+		response.Error(c, http.StatusUnprocessableEntity, err.Error())
+	default:
+		response.Error(c, http.StatusInternalServerError, "failed to update assignment")
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"altoai_mvp/internal/middleware"
@@ -91,8 +92,7 @@ func (h *UserHandler) Delete(c *gin.Context) {
 
 func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	claims := c.MustGet("user").(*middleware.MyClaims)
-	// Get user by email
-	user, err := h.svc.GetByEmail(c.Request.Context(), claims.Email)
+	user, err := h.svc.Get(c.Request.Context(), claims.Subject)
 	if err != nil {
 		if err == repository.ErrNotFound {
 			response.Error(c, http.StatusNotFound, "user not found")
@@ -108,6 +108,13 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 		return
 	}
 	
+	// A new email has to be confirmed through POST /api/v1/auth/change-email
+	if dto.Email != nil && *dto.Email != user.Email {
+		response.ValidationError(c, map[string]string{"email": "change your email through /api/v1/auth/change-email"})
+		return
+	}
+	dto.Email = nil
+
 	u, err := h.svc.Update(c.Request.Context(), user.ID, dto)
 	if err != nil {
 		if err == repository.ErrNotFound {
@@ -119,3 +126,69 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	}
 	response.OK(c, u)
 }
+
+// SetRole changes a user's role (admin only)
+func (h *UserHandler) SetRole(c *gin.Context) {
+	var dto models.SetRoleDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		response.ValidationError(c, errs.FromBinding(err))
+		return
+	}
+	claims := c.MustGet("user").(*middleware.MyClaims)
+	if c.Param("id") == claims.Subject && dto.Role != models.RoleAdmin {
+		// Keeps at least the acting admin in place; another admin has to demote them
+		response.Error(c, http.StatusConflict, "admins cannot remove their own admin role")
+		return
+	}
+	u, err := h.svc.SetRole(c.Request.Context(), c.Param("id"), dto.Role)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			response.Error(c, http.StatusNotFound, "user not found")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "failed to set role")
+		return
+	}
+	response.OK(c, u)
+}
+
+// AuditEvents lists recent security events, newest first (admin only). ?limit= defaults to 100.
+func (h *UserHandler) AuditEvents(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		response.Error(c, http.StatusBadRequest, "limit must be between 1 and 1000")
+		return
+	}
+	events, err := h.svc.AuditEvents(c.Request.Context(), limit)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to list audit events")
+		return
+	}
+	response.OK(c, events)
+}
+
+// MFAPolicy lists the roles that must use two-factor authentication (admin only)
+func (h *UserHandler) MFAPolicy(c *gin.Context) {
+	roles, err := h.svc.MFAPolicy(c.Request.Context())
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to load MFA policy")
+		return
+	}
+	response.OK(c, gin.H{"required_roles": roles})
+}
+
+// SetMFAPolicy replaces the roles that must use two-factor authentication (admin only).
+// Members of those roles without MFA are asked to set it up at their next login.
+func (h *UserHandler) SetMFAPolicy(c *gin.Context) {
+	var dto models.MFAPolicyDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		response.ValidationError(c, errs.FromBinding(err))
+		return
+	}
+	roles, err := h.svc.SetMFAPolicy(c.Request.Context(), dto.RequiredRoles)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "failed to update MFA policy")
+		return
+	}
+	response.OK(c, gin.H{"required_roles": roles})
+}
@@ -1,25 +1,31 @@
 package middleware
 
-
 import (
+	"context"
 	"net/http"
-	"os"
 	"strings"
 
+	"altoai_mvp/internal/token"
+
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
-type MyClaims struct {
-	Email   string `json:"email"`
-	Name    string `json:"name"`
-	Picture string `json:"picture"`
-	Type    string `json:"type"`
-	jwt.RegisteredClaims
-}
+// MyClaims are the access token claims JWTAuth stores on the context
+type MyClaims = token.Claims
 
+// TokenVersionFunc returns the current token version of a user
+type TokenVersionFunc func(ctx context.Context, userID string) (int, error)
+
+var tokenVersionSource TokenVersionFunc
+
+// SetTokenVersionSource makes JWTAuth reject access tokens issued before the user's token
+// version was last bumped ("log out everywhere"). Without a source versions aren't checked.
+func SetTokenVersionSource(f TokenVersionFunc) {
+	tokenVersionSource = f
+}
 
-func JWTAuth() gin.HandlerFunc {
+// JWTAuth accepts requests bearing a valid access token and stores its claims as "user"
+func JWTAuth(tokens *token.Service) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Read from Authorization header
 		authHeader := c.GetHeader("Authorization")
@@ -27,38 +33,31 @@ func JWTAuth() gin.HandlerFunc {
 			c.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
-		
+
 		// Extract token from "Bearer <token>"
 		tok := strings.TrimPrefix(authHeader, "Bearer ")
 		if tok == "" {
 			c.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
-		
-		secret := os.Getenv("JWT_SECRET")
-		token, err := jwt.ParseWithClaims(tok, &MyClaims{}, func(token *jwt.Token) (interface{}, error) {
-			return []byte(secret), nil
-		})
-		if err != nil || !token.Valid {
+
+		// Signature, algorithm, issuer, audience, expiry and type (access, not refresh)
+		claims, err := tokens.ParseAccess(tok)
+		if err != nil {
 			c.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
 
-		claims := token.Claims.(*MyClaims)
-		
-		// Verify this is an access token (not a refresh token)
-		// Check the type claim from MapClaims if available
-		if mapClaims, ok := token.Claims.(jwt.MapClaims); ok {
-			if tokenType, ok := mapClaims["type"].(string); ok && tokenType != "access" {
+		// Tokens issued before the user logged out everywhere are dead
+		if tokenVersionSource != nil {
+			current, err := tokenVersionSource(c.Request.Context(), claims.Subject)
+			if claims.Subject == "" || err != nil || claims.Version < current {
 				c.AbortWithStatus(http.StatusUnauthorized)
 				return
 			}
-		} else if claims.Type != "" && claims.Type != "access" {
-			c.AbortWithStatus(http.StatusUnauthorized)
-			return
 		}
-		
+
 		c.Set("user", claims)
 		c.Next()
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit is a token bucket: it holds up to Burst requests and refills Burst tokens
+// every Period, so bursts are allowed but the long-run rate is Burst per Period
+type RateLimit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// ParseRateLimit reads a limit written as "requests/period", e.g. "5/15m" or "30/1m"
+func ParseRateLimit(s string) (RateLimit, error) {
+	n, period, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("rate limit %q must look like 30/1m", s)
+	}
+	burst, err := strconv.Atoi(n)
+	if err != nil || burst < 1 {
+		return RateLimit{}, fmt.Errorf("rate limit %q: request count must be a positive integer", s)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return RateLimit{}, fmt.Errorf("rate limit %q: period must be a positive duration", s)
+	}
+	return RateLimit{Burst: burst, Period: d}, nil
+}
+
+// RateLimitFromEnv reads RATE_LIMIT_<GROUP> (e.g. RATE_LIMIT_CHAT=20/1m), or returns fallback
+func RateLimitFromEnv(group string, fallback RateLimit) (RateLimit, error) {
+	v := os.Getenv("RATE_LIMIT_" + strings.ToUpper(group))
+	if v == "" {
+		return fallback, nil
+	}
+	return ParseRateLimit(v)
+}
+
+// interval is the time it takes to refill one token
+func (l RateLimit) interval() time.Duration {
+	return l.Period / time.Duration(l.Burst)
+}
+
+// RateLimitResult is the outcome of taking a token
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int           // whole tokens left after this request
+	Reset     time.Duration // until the bucket is full again
+	// RetryAfter is how long until a token is available; zero when Allowed
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps token buckets. Take refills the bucket for key up to now and takes
+// one token if there is one.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error)
+}
+
+// TakeToken applies the token bucket to a bucket holding tokens as of updated.
+// Stores call it and persist the returned level.
+func TakeToken(tokens float64, updated time.Time, limit RateLimit, now time.Time) (float64, RateLimitResult) {
+	capacity := float64(limit.Burst)
+	perToken := limit.interval()
+	if elapsed := now.Sub(updated); elapsed > 0 {
+		tokens = math.Min(capacity, tokens+float64(elapsed)/float64(perToken))
+	}
+
+	res := RateLimitResult{}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration((1 - tokens) * float64(perToken))
+	}
+	res.Remaining = int(math.Floor(tokens))
+	res.Reset = time.Duration((capacity - tokens) * float64(perToken))
+	return tokens, res
+}
+
+// RateLimitKey picks whose bucket a request draws from
+type RateLimitKey func(c *gin.Context) string
+
+// ByIP limits each client IP address
+func ByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// ByUser limits each signed-in user, falling back to the IP address before JWTAuth has run
+func ByUser(c *gin.Context) string {
+	if claims, ok := c.Get("user"); ok {
+		if mc, ok := claims.(*MyClaims); ok && mc.Subject != "" {
+			return "user:" + mc.Subject
+		}
+	}
+	return ByIP(c)
+}
+
+// RateLimiter rejects requests beyond limit with 429. Buckets are per route group and per key,
+// so the same client has separate budgets for, say, "email" and "chat". Every response carries
+// RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset; rejections add Retry-After.
+// If the store fails the request is let through, so a database hiccup doesn't take the API down.
+func RateLimiter(store RateLimitStore, group string, limit RateLimit, key RateLimitKey) gin.HandlerFunc {
+	policy := fmt.Sprintf("%d;w=%d", limit.Burst, int(math.Ceil(limit.Period.Seconds())))
+	return func(c *gin.Context) {
+		res, err := store.Take(c.Request.Context(), group+":"+key(c), limit, time.Now())
+		if err != nil {
+			log.Printf("rate limit %s: %v", group, err)
+			c.Next()
+			return
+		}
+
+		h := c.Writer.Header()
+		h.Set("RateLimit-Policy", policy)
+		h.Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+		h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+		if !res.Allowed {
+			h.Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(res.RetryAfter))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded, please slow down"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// memoryBucket is a token bucket's level as of updated
+type memoryBucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time // when the bucket refills completely and can be dropped
+}
+
+// MemoryRateLimitStore keeps buckets in process. Each replica counts separately, so use the
+// Postgres store when running more than one.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: map[string]*memoryBucket{}}
+}
+
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{tokens: float64(limit.Burst), updated: now}
+		s.buckets[key] = b
+	}
+	tokens, res := TakeToken(b.tokens, b.updated, limit, now)
+	b.tokens = tokens
+	if now.After(b.updated) {
+		b.updated = now
+	}
+	b.full = now.Add(res.Reset)
+	return res, nil
+}
+
+// sweep drops buckets that have refilled, at most once a minute; s.mu must be held
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for k, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, k)
+		}
+	}
+}
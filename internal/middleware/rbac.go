@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"altoai_mvp/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Permission is an action a route requires
+type Permission string
+
+const (
+	PermManageUsers      Permission = "users:manage"     // list, create, edit, delete users and set roles
+	PermManageAssignment Permission = "students:assign"  // assign students to counsellors
+	PermViewStudents     Permission = "students:view"    // read assigned students' interview results
+	PermViewAllStudents  Permission = "students:viewall" // read any student's interview results
+	PermManageCohorts    Permission = "cohorts:manage"   // create cohorts and run the ones they counsel
+	PermManageAllCohorts Permission = "cohorts:all"      // manage every cohort
+)
+
+// rolePermissions lists what each role may do; students only act on their own account
+var rolePermissions = map[models.Role][]Permission{
+	models.RoleStudent:    {},
+	models.RoleCounsellor: {PermViewStudents, PermManageCohorts},
+	models.RoleAdmin: {
+		PermManageUsers, PermManageAssignment, PermViewStudents, PermViewAllStudents,
+		PermManageCohorts, PermManageAllCohorts,
+	},
+}
+
+// ClaimsRole returns the role in an access token. Tokens issued before roles existed are students.
+func ClaimsRole(claims *MyClaims) models.Role {
+	if role := models.Role(claims.Role); role.Valid() {
+		return role
+	}
+	return models.RoleStudent
+}
+
+// HasPermission reports whether role grants perm
+func HasPermission(role models.Role, perm Permission) bool {
+	return slices.Contains(rolePermissions[role], perm)
+}
+
+// RequirePermission rejects requests whose access token's role lacks perm. It must run after JWTAuth.
+func RequirePermission(perm Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := c.Get("user")
+		if !ok {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		if !HasPermission(ClaimsRole(claims.(*MyClaims)), perm) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// AttemptCounter tracks failed attempts against one key, such as an account's logins or
+// one IP address's code guesses. Failures counts the failures since WindowStart; every
+// lockout doubles the next one until the counter is cleared.
+type AttemptCounter struct {
+	Key         string     `json:"key"`
+	Failures    int        `json:"failures"`
+	WindowStart time.Time  `json:"window_start"`
+	Lockouts    int        `json:"lockouts"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// AuditEvent records a security-relevant event, such as an account being locked
+type AuditEvent struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	UserID    string    `json:"user_id,omitempty"`
+	Email     string    `json:"email,omitempty"`
+	IP        string    `json:"ip,omitempty"`
+	Detail    string    `json:"detail,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,75 @@
+package models
+
+import "time"
+
+// Cohort is a group of students preparing together, looked after by one or more counsellors.
+// Level and QuestionSet are the practice assigned to the cohort; sessions started in the
+// cohort use them.
+type Cohort struct {
+	ID           string           `json:"id"`
+	Name         string           `json:"name"`
+	Organization string           `json:"organization,omitempty"`
+	CreatedBy    string           `json:"created_by"`
+	Level        string           `json:"level,omitempty"`
+	QuestionSet  []CohortQuestion `json:"question_set,omitempty"`
+	CreatedAt    time.Time        `json:"created_at"`
+	UpdatedAt    time.Time        `json:"updated_at"`
+}
+
+// CohortQuestion is one question of a cohort's assigned question set
+type CohortQuestion struct {
+	ID       string `json:"id"`
+	Category string `json:"category" binding:"required"`
+	Text     string `json:"text" binding:"required"`
+}
+
+// CohortRole is a member's part in a cohort
+type CohortRole string
+
+const (
+	CohortRoleStudent    CohortRole = "student"
+	CohortRoleCounsellor CohortRole = "counsellor"
+)
+
+// CohortMember is a user's membership of a cohort
+type CohortMember struct {
+	CohortID string     `json:"cohort_id"`
+	UserID   string     `json:"user_id"`
+	Role     CohortRole `json:"role"`
+	JoinedAt time.Time  `json:"joined_at"`
+	User     *User      `json:"user,omitempty"`
+}
+
+// CohortInvite invites an email address to a cohort. The code is emailed to the invitee;
+// only its SHA-256 hash is stored.
+type CohortInvite struct {
+	ID         string     `json:"id"`
+	CohortID   string     `json:"cohort_id"`
+	Email      string     `json:"email"`
+	Role       CohortRole `json:"role"`
+	InvitedBy  string     `json:"invited_by"`
+	CodeHash   string     `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+}
+
+type CreateCohortDTO struct {
+	Name         string `json:"name" binding:"required,min=1,max=255"`
+	Organization string `json:"organization" binding:"max=255"`
+}
+
+type CohortInviteDTO struct {
+	Email string     `json:"email" binding:"required,email"`
+	Role  CohortRole `json:"role" binding:"omitempty,oneof=student counsellor"`
+}
+
+type AcceptCohortInviteDTO struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// CohortAssignmentDTO sets a cohort's practice; empty fields clear it
+type CohortAssignmentDTO struct {
+	Level       string           `json:"level" binding:"omitempty,oneof=easy medium hard"`
+	QuestionSet []CohortQuestion `json:"question_set" binding:"omitempty,max=30,dive"`
+}
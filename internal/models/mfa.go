@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// UserMFA is a user's TOTP enrolment. It only guards logins once ConfirmedAt is set, that is
+// after the user has entered a code from their authenticator app.
+type UserMFA struct {
+	UserID       string     `json:"user_id"`
+	Secret       string     `json:"-"`
+	ConfirmedAt  *time.Time `json:"confirmed_at,omitempty"`
+	LastUsedStep int64      `json:"-"` // the last accepted TOTP time step, so codes can't be replayed
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// RecoveryCode is a single-use code for logging in without the authenticator app
+type RecoveryCode struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id"`
+	CodeHash  string     `json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+}
+
+type MFAStatus struct {
+	Enabled           bool `json:"enabled"`
+	Required          bool `json:"required"` // the user's role must use MFA
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// MFAEnrollment is shown once while setting up an authenticator app
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// MFACodeDTO carries a code from the authenticator app, or a recovery code
+type MFACodeDTO struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// MFAChallengeDTO answers the challenge a login returned
+type MFAChallengeDTO struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// MFASetupDTO starts enrolment from a login challenge, for roles that must use MFA
+type MFASetupDTO struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+}
+
+type MFAPolicyDTO struct {
+	RequiredRoles []Role `json:"required_roles" binding:"dive,oneof=student counsellor admin"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// CodePurpose is what a one-time code proves; a user has at most one live code per purpose
+type CodePurpose string
+
+const (
+	CodePurposeVerifyEmail   CodePurpose = "verify_email"
+	CodePurposeResetPassword CodePurpose = "reset_password"
+	CodePurposeMagicLink     CodePurpose = "magic_link"   // the nonce of a signed sign-in link
+	CodePurposeChangeEmail   CodePurpose = "change_email" // sent to the new address of a pending email change
+	CodePurposeOAuthLogin    CodePurpose = "oauth_login"  // the nonce of a login code from an OAuth redirect
+)
+
+// OneTimeCode is a short code emailed to a user. Only a keyed hash of the code is stored;
+// Attempts counts wrong guesses against it.
+type OneTimeCode struct {
+	ID        string      `json:"id"`
+	UserID    string      `json:"user_id"`
+	Purpose   CodePurpose `json:"purpose"`
+	CodeHash  string      `json:"-"`
+	Attempts  int         `json:"attempts"`
+	ExpiresAt time.Time   `json:"expires_at"`
+	CreatedAt time.Time   `json:"created_at"`
+}
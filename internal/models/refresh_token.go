@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// RefreshToken is a persisted refresh token. Only a SHA-256 hash of the token is stored.
+// Every token rotated from the same login shares a FamilyID, so a stolen token that is
+// replayed after rotation can revoke the whole chain.
+type RefreshToken struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	FamilyID   string     `json:"family_id"`
+	TokenHash  string     `json:"-"`
+	Device     string     `json:"device"` // human-readable label derived from the User-Agent
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	IssuedAt   time.Time  `json:"issued_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	ReplacedBy string     `json:"replaced_by,omitempty"` // ID of the token issued when this one was rotated
+}
+
+// DeviceSession is one signed-in device: a refresh token family that can still be refreshed
+type DeviceSession struct {
+	ID         string    `json:"id"` // refresh token family ID
+	Device     string    `json:"device"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`   // when the device logged in
+	LastSeenAt time.Time `json:"last_seen_at"` // when its token was last refreshed
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"` // the device making the request
+}
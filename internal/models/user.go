@@ -2,26 +2,38 @@ package models
 
 import "time"
 
+// Role decides what a user may do beyond their own account
+type Role string
+
+const (
+	RoleStudent    Role = "student"
+	RoleCounsellor Role = "counsellor" // reads the interview results of assigned students
+	RoleAdmin      Role = "admin"      // manages accounts, roles and assignments
+)
+
+// Valid reports whether r is one of the known roles
+func (r Role) Valid() bool {
+	return r == RoleStudent || r == RoleCounsellor || r == RoleAdmin
+}
+
 type User struct {
-	ID                      string    `json:"id"`
-	Email                   string    `json:"email"`
-	Name                    string    `json:"name"`
-	Password                string    `json:"-"` // Don't serialize password
-	EmailVerified           bool      `json:"email_verified"`
-	College                 string    `json:"college,omitempty"`
-	Major                   string    `json:"major,omitempty"`
-	VerificationCode        string    `json:"-"`
-	VerificationCodeExpires time.Time `json:"-"`
-	ResetCode               string    `json:"-"`
-	ResetCodeExpires        time.Time `json:"-"`
-	CreatedAt               time.Time `json:"created_at"`
-	UpdatedAt               time.Time `json:"updated_at"`
+	ID            string    `json:"id"`
+	Email         string    `json:"email"`
+	Name          string    `json:"name"`
+	Password      string    `json:"-"` // Don't serialize password
+	EmailVerified bool      `json:"email_verified"`
+	Role          Role      `json:"role"`
+	College       string    `json:"college,omitempty"`
+	Major         string    `json:"major,omitempty"`
+	TokenVersion  int       `json:"-"` // bumped by "log out everywhere" to kill issued access tokens
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
 type CreateUserDTO struct {
 	Email    string `json:"email" binding:"required,email"`
 	Name     string `json:"name"  binding:"required,min=2,max=64"`
-	Password string `json:"password" binding:"required,min=6"` // Required for signup
+	Password string `json:"password" binding:"required"` // Required for signup; checked against the password policy
 }
 
 type LoginDTO struct {
@@ -48,9 +60,39 @@ type ForgotPasswordDTO struct {
 type ResetPasswordDTO struct {
 	Email    string `json:"email" binding:"required,email"`
 	Code     string `json:"code" binding:"required,len=6"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"`
+}
+
+// ChangePasswordDTO changes a signed-in user's password. CurrentPassword can be left out
+// by accounts that don't have one yet, such as Google sign-ups.
+type ChangePasswordDTO struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password" binding:"required"`
 }
 
 type ResendVerificationDTO struct {
 	Email string `json:"email" binding:"required,email"`
 }
+
+type MagicLinkDTO struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type MagicLinkLoginDTO struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ChangeEmailDTO starts an email change; the new address has to confirm it with a code.
+// CurrentPassword can be left out by accounts that don't have one.
+type ChangeEmailDTO struct {
+	NewEmail        string `json:"new_email" binding:"required,email"`
+	CurrentPassword string `json:"current_password"`
+}
+
+type ConfirmEmailChangeDTO struct {
+	Code string `json:"code" binding:"required,len=6"`
+}
+
+type SetRoleDTO struct {
+	Role Role `json:"role" binding:"required,oneof=student counsellor admin"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// UserIdentity links a user to their account at an external login provider.
+// Subject is the provider's stable ID for the account (the ID token's sub claim).
+type UserIdentity struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email"` // email the provider reported when the identity was linked
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"sort"
+	"time"
+
+	"altoai_mvp/internal/models"
+)
+
+func (r *userMemoryRepo) SetRole(id string, role models.Role) (models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.store[id]
+	if !ok {
+		return models.User{}, ErrNotFound
+	}
+	u.Role = role
+	u.UpdatedAt = time.Now().UTC()
+	r.store[id] = u
+	return u, nil
+}
+
+func (r *userMemoryRepo) AssignStudent(counsellorID, studentID string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.store[counsellorID]; !ok {
+		return ErrNotFound
+	}
+	if _, ok := r.store[studentID]; !ok {
+		return ErrNotFound
+	}
+	students, ok := r.assignments[counsellorID]
+	if !ok {
+		students = map[string]time.Time{}
+		r.assignments[counsellorID] = students
+	}
+	if _, ok := students[studentID]; !ok {
+		students[studentID] = at
+	}
+	return nil
+}
+
+func (r *userMemoryRepo) UnassignStudent(counsellorID, studentID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.assignments[counsellorID][studentID]; !ok {
+		return ErrNotFound
+	}
+	delete(r.assignments[counsellorID], studentID)
+	return nil
+}
+
+func (r *userMemoryRepo) ListAssignedStudents(counsellorID string) ([]models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := []models.User{}
+	for id := range r.assignments[counsellorID] {
+		if u, ok := r.store[id]; ok {
+			out = append(out, u)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+func (r *userMemoryRepo) IsStudentAssigned(counsellorID, studentID string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.assignments[counsellorID][studentID]
+	return ok, nil
+}
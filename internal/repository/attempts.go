@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"time"
+
+	"altoai_mvp/internal/models"
+)
+
+func (r *userMemoryRepo) GetAttemptCounter(key string) (models.AttemptCounter, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.attempts[key]
+	if !ok {
+		return models.AttemptCounter{}, ErrNotFound
+	}
+	return c, nil
+}
+
+func (r *userMemoryRepo) RecordFailedAttempt(key string, at, windowStart time.Time) (models.AttemptCounter, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.attempts[key]
+	if !ok {
+		c = models.AttemptCounter{Key: key}
+	}
+	if !ok || c.WindowStart.Before(windowStart) {
+		c.Failures = 0
+		c.WindowStart = at
+	}
+	c.Failures++
+	c.UpdatedAt = at
+	r.attempts[key] = c
+	return c, nil
+}
+
+func (r *userMemoryRepo) LockAttempts(key string, at, until time.Time) (models.AttemptCounter, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.attempts[key]
+	if !ok {
+		c = models.AttemptCounter{Key: key, WindowStart: at}
+	}
+	c.Failures = 0
+	c.Lockouts++
+	c.LockedUntil = &until
+	c.UpdatedAt = at
+	r.attempts[key] = c
+	return c, nil
+}
+
+func (r *userMemoryRepo) ClearAttempts(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.attempts, key)
+	return nil
+}
+
+func (r *userMemoryRepo) CreateAuditEvent(e models.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.auditEvents = append(r.auditEvents, e)
+	return nil
+}
+
+func (r *userMemoryRepo) ListAuditEvents(limit int) ([]models.AuditEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := []models.AuditEvent{}
+	for i := len(r.auditEvents) - 1; i >= 0 && len(out) < limit; i-- {
+		out = append(out, r.auditEvents[i])
+	}
+	return out, nil
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"time"
+
+	"altoai_mvp/internal/models"
+)
+
+// ErrInviteSpent is returned when accepting a cohort invite that was already accepted
+var ErrInviteSpent = errors.New("invite already accepted")
+
+func (r *userMemoryRepo) CreateCohort(c models.Cohort) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c.QuestionSet = slices.Clone(c.QuestionSet)
+	r.cohorts[c.ID] = c
+	r.cohortMembers[c.ID] = map[string]models.CohortMember{}
+	return nil
+}
+
+func (r *userMemoryRepo) GetCohort(id string) (models.Cohort, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.cohorts[id]
+	if !ok {
+		return models.Cohort{}, ErrNotFound
+	}
+	c.QuestionSet = slices.Clone(c.QuestionSet)
+	return c, nil
+}
+
+func (r *userMemoryRepo) UpdateCohort(c models.Cohort) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.cohorts[c.ID]; !ok {
+		return ErrNotFound
+	}
+	c.QuestionSet = slices.Clone(c.QuestionSet)
+	r.cohorts[c.ID] = c
+	return nil
+}
+
+func (r *userMemoryRepo) DeleteCohort(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.cohorts[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.cohorts, id)
+	delete(r.cohortMembers, id)
+	for k, i := range r.cohortInvites {
+		if i.CohortID == id {
+			delete(r.cohortInvites, k)
+		}
+	}
+	return nil
+}
+
+func (r *userMemoryRepo) ListCohorts() ([]models.Cohort, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]models.Cohort, 0, len(r.cohorts))
+	for _, c := range r.cohorts {
+		out = append(out, c)
+	}
+	sortCohorts(out)
+	return out, nil
+}
+
+func (r *userMemoryRepo) ListUserCohorts(userID string) ([]models.Cohort, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := []models.Cohort{}
+	for id, members := range r.cohortMembers {
+		if _, ok := members[userID]; ok {
+			out = append(out, r.cohorts[id])
+		}
+	}
+	sortCohorts(out)
+	return out, nil
+}
+
+func sortCohorts(list []models.Cohort) {
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+}
+
+func (r *userMemoryRepo) AddCohortMember(m models.CohortMember) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.addCohortMember(m)
+}
+
+// addCohortMember adds or updates a membership, keeping the original join time; r.mu must be held
+func (r *userMemoryRepo) addCohortMember(m models.CohortMember) error {
+	members, ok := r.cohortMembers[m.CohortID]
+	if !ok {
+		return ErrNotFound
+	}
+	if _, ok := r.store[m.UserID]; !ok {
+		return ErrNotFound
+	}
+	if existing, ok := members[m.UserID]; ok {
+		m.JoinedAt = existing.JoinedAt
+	}
+	m.User = nil
+	members[m.UserID] = m
+	return nil
+}
+
+func (r *userMemoryRepo) RemoveCohortMember(cohortID, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.cohortMembers[cohortID][userID]; !ok {
+		return ErrNotFound
+	}
+	delete(r.cohortMembers[cohortID], userID)
+	return nil
+}
+
+func (r *userMemoryRepo) GetCohortMember(cohortID, userID string) (models.CohortMember, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	m, ok := r.cohortMembers[cohortID][userID]
+	if !ok {
+		return models.CohortMember{}, ErrNotFound
+	}
+	return m, nil
+}
+
+func (r *userMemoryRepo) ListCohortMembers(cohortID string) ([]models.CohortMember, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	members, ok := r.cohortMembers[cohortID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	out := make([]models.CohortMember, 0, len(members))
+	for _, m := range members {
+		u := r.store[m.UserID]
+		m.User = &u
+		out = append(out, m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].User.Name < out[j].User.Name })
+	return out, nil
+}
+
+func (r *userMemoryRepo) CreateCohortInvite(i models.CohortInvite) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.cohorts[i.CohortID]; !ok {
+		return ErrNotFound
+	}
+	r.cohortInvites[i.ID] = i
+	return nil
+}
+
+func (r *userMemoryRepo) GetCohortInvite(codeHash string) (models.CohortInvite, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, i := range r.cohortInvites {
+		if i.CodeHash == codeHash {
+			return i, nil
+		}
+	}
+	return models.CohortInvite{}, ErrNotFound
+}
+
+func (r *userMemoryRepo) AcceptCohortInvite(inviteID string, m models.CohortMember, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i, ok := r.cohortInvites[inviteID]
+	if !ok {
+		return ErrNotFound
+	}
+	if i.AcceptedAt != nil {
+		return ErrInviteSpent
+	}
+	if err := r.addCohortMember(m); err != nil {
+		return err
+	}
+	i.AcceptedAt = &at
+	r.cohortInvites[inviteID] = i
+	return nil
+}
+
+func (r *userMemoryRepo) ListCohortInvites(cohortID string) ([]models.CohortInvite, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := []models.CohortInvite{}
+	for _, i := range r.cohortInvites {
+		if i.CohortID == cohortID {
+			out = append(out, i)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
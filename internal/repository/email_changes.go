@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"altoai_mvp/internal/models"
+)
+
+// ErrEmailTaken is returned when moving a user to an email another account already has
+var ErrEmailTaken = errors.New("email already in use")
+
+func (r *userMemoryRepo) SetPendingEmail(userID, email string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.store[userID]; !ok {
+		return ErrNotFound
+	}
+	r.pendingEmails[userID] = email
+	return nil
+}
+
+func (r *userMemoryRepo) ConfirmEmailChange(userID, code string, at time.Time) (models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	email, ok := r.pendingEmails[userID]
+	if !ok {
+		return models.User{}, ErrInvalidCode
+	}
+	for id, u := range r.store {
+		if id != userID && strings.EqualFold(u.Email, email) {
+			return models.User{}, ErrEmailTaken
+		}
+	}
+	if err := r.consumeCodeLocked(userID, models.CodePurposeChangeEmail, code, at); err != nil {
+		return models.User{}, err
+	}
+
+	u, ok := r.store[userID]
+	if !ok {
+		return models.User{}, ErrNotFound
+	}
+	u.Email = email
+	u.EmailVerified = true
+	u.UpdatedAt = at
+	r.store[userID] = u
+	delete(r.pendingEmails, userID)
+	return u, nil
+}
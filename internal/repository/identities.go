@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"errors"
+
+	"altoai_mvp/internal/models"
+)
+
+// ErrIdentityTaken is returned when linking a provider account that is already linked
+var ErrIdentityTaken = errors.New("identity already linked")
+
+func identityKey(provider, subject string) string {
+	return provider + "|" + subject
+}
+
+func (r *userMemoryRepo) GetIdentity(provider, subject string) (models.UserIdentity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	i, ok := r.identities[identityKey(provider, subject)]
+	if !ok {
+		return models.UserIdentity{}, ErrNotFound
+	}
+	return i, nil
+}
+
+func (r *userMemoryRepo) CreateIdentity(i models.UserIdentity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := identityKey(i.Provider, i.Subject)
+	if _, ok := r.identities[key]; ok {
+		return ErrIdentityTaken
+	}
+	if _, ok := r.store[i.UserID]; !ok {
+		return ErrNotFound
+	}
+	r.identities[key] = i
+	return nil
+}
+
+func (r *userMemoryRepo) ListIdentities(userID string) ([]models.UserIdentity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []models.UserIdentity
+	for _, i := range r.identities {
+		if i.UserID == userID {
+			out = append(out, i)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"slices"
+	"time"
+
+	"altoai_mvp/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func (r *userMemoryRepo) GetMFA(userID string) (models.UserMFA, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	m, ok := r.mfa[userID]
+	if !ok {
+		return models.UserMFA{}, ErrNotFound
+	}
+	return m, nil
+}
+
+func (r *userMemoryRepo) SaveMFA(m models.UserMFA) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.store[m.UserID]; !ok {
+		return ErrNotFound
+	}
+	r.mfa[m.UserID] = m
+	return nil
+}
+
+func (r *userMemoryRepo) DeleteMFA(userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.mfa, userID)
+	delete(r.recoveryCodes, userID)
+	return nil
+}
+
+func (r *userMemoryRepo) MarkTOTPStepUsed(userID string, step int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m, ok := r.mfa[userID]
+	if !ok {
+		return false, ErrNotFound
+	}
+	if step <= m.LastUsedStep {
+		return false, nil
+	}
+	m.LastUsedStep = step
+	r.mfa[userID] = m
+	return true, nil
+}
+
+func (r *userMemoryRepo) ReplaceRecoveryCodes(userID string, codeHashes []string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	codes := make([]models.RecoveryCode, 0, len(codeHashes))
+	for _, h := range codeHashes {
+		codes = append(codes, models.RecoveryCode{ID: uuid.NewString(), UserID: userID, CodeHash: h, CreatedAt: at})
+	}
+	r.recoveryCodes[userID] = codes
+	return nil
+}
+
+func (r *userMemoryRepo) UseRecoveryCode(userID, codeHash string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, c := range r.recoveryCodes[userID] {
+		if c.CodeHash == codeHash && c.UsedAt == nil {
+			r.recoveryCodes[userID][i].UsedAt = &at
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *userMemoryRepo) CountRecoveryCodes(userID string) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	n := 0
+	for _, c := range r.recoveryCodes[userID] {
+		if c.UsedAt == nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *userMemoryRepo) ListMFARequiredRoles() ([]models.Role, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.mfaRoles), nil
+}
+
+func (r *userMemoryRepo) SetMFARequiredRoles(roles []models.Role) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mfaRoles = slices.Clone(roles)
+	return nil
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"time"
+
+	"altoai_mvp/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// MaxCodeAttempts is how many wrong guesses a one-time code survives before it is deleted
+const MaxCodeAttempts = 5
+
+var (
+	ErrInvalidCode = errors.New("invalid code")
+	ErrCodeExpired = errors.New("code expired")
+)
+
+// codeHasher keys the HMAC one-time codes are stored under, so a copy of the database alone
+// can't be used to read codes or to brute-force the 6-digit space offline
+type codeHasher []byte
+
+// codeHasherFromEnv reads CODE_HASH_KEY, or derives a key from JWT_SECRET; nil if neither is set
+func codeHasherFromEnv() codeHasher {
+	if key := os.Getenv("CODE_HASH_KEY"); key != "" {
+		return codeHasher(key)
+	}
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte("altoai one-time codes"))
+		return codeHasher(mac.Sum(nil))
+	}
+	return nil
+}
+
+// randomCodeHasher is for the in-memory store, whose codes don't outlive the process anyway
+func randomCodeHasher() codeHasher {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return codeHasher(key)
+}
+
+// hash binds the code to its user and purpose, so a stored hash can't be replayed elsewhere
+func (k codeHasher) hash(userID string, purpose models.CodePurpose, code string) string {
+	mac := hmac.New(sha256.New, k)
+	mac.Write([]byte(string(purpose) + "\x00" + userID + "\x00" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (k codeHasher) newCode(userID string, purpose models.CodePurpose, code string, expiresAt time.Time) models.OneTimeCode {
+	return models.OneTimeCode{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		Purpose:   purpose,
+		CodeHash:  k.hash(userID, purpose, code),
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+// check compares code with c in constant time. A wrong guess is counted on c; keep reports
+// whether c should stay stored afterwards.
+func (k codeHasher) check(c *models.OneTimeCode, code string, at time.Time) (keep bool, err error) {
+	if !hmac.Equal([]byte(c.CodeHash), []byte(k.hash(c.UserID, c.Purpose, code))) {
+		c.Attempts++
+		return c.Attempts < MaxCodeAttempts, ErrInvalidCode
+	}
+	if at.After(c.ExpiresAt) {
+		return false, ErrCodeExpired
+	}
+	return false, nil
+}
+
+// codeError maps ErrInvalidCode and ErrCodeExpired to purpose-specific errors
+func codeError(err, invalid, expired error) error {
+	switch err {
+	case ErrInvalidCode:
+		return invalid
+	case ErrCodeExpired:
+		return expired
+	}
+	return err
+}
+
+func codeMapKey(userID string, purpose models.CodePurpose) string {
+	return userID + "|" + string(purpose)
+}
+
+func (r *userMemoryRepo) SetOneTimeCode(userID string, purpose models.CodePurpose, code string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.store[userID]; !ok {
+		return ErrNotFound
+	}
+	r.codes[codeMapKey(userID, purpose)] = r.hasher.newCode(userID, purpose, code, expiresAt)
+	return nil
+}
+
+func (r *userMemoryRepo) ConsumeOneTimeCode(userID string, purpose models.CodePurpose, code string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.consumeCodeLocked(userID, purpose, code, at)
+}
+
+// consumeCodeLocked checks and, on a match, deletes the code; r.mu must be held
+func (r *userMemoryRepo) consumeCodeLocked(userID string, purpose models.CodePurpose, code string, at time.Time) error {
+	key := codeMapKey(userID, purpose)
+	c, ok := r.codes[key]
+	if !ok {
+		return ErrInvalidCode
+	}
+	keep, err := r.hasher.check(&c, code, at)
+	if keep {
+		r.codes[key] = c
+	} else {
+		delete(r.codes, key)
+	}
+	return err
+}
+
+func (r *userMemoryRepo) DeleteOneTimeCode(userID string, purpose models.CodePurpose) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.codes, codeMapKey(userID, purpose))
+	return nil
+}
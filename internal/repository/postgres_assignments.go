@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"altoai_mvp/internal/models"
+)
+
+func createCounsellorStudentsTable(db *sql.DB) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS counsellor_students (
+			counsellor_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			student_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			assigned_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (counsellor_id, student_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS counsellor_students_student_idx ON counsellor_students (student_id)`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("error creating counsellor_students table: %v", err)
+		}
+	}
+	return nil
+}
+
+func (r *postgresRepo) SetRole(id string, role models.Role) (models.User, error) {
+	res, err := r.db.Exec("UPDATE users SET role = $1, updated_at = $2 WHERE id = $3", role, time.Now().UTC(), id)
+	if err != nil {
+		return models.User{}, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return models.User{}, ErrNotFound
+	}
+	return r.Get(id)
+}
+
+func (r *postgresRepo) AssignStudent(counsellorID, studentID string, at time.Time) error {
+	res, err := r.db.Exec(
+		`INSERT INTO counsellor_students (counsellor_id, student_id, assigned_at)
+		SELECT $1, $2, $3 WHERE EXISTS (SELECT 1 FROM users WHERE id = $1) AND EXISTS (SELECT 1 FROM users WHERE id = $2)
+		ON CONFLICT (counsellor_id, student_id) DO NOTHING`,
+		counsellorID, studentID, at,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		// Either a user is missing or the assignment already exists
+		ok, err := r.IsStudentAssigned(counsellorID, studentID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrNotFound
+		}
+	}
+	return nil
+}
+
+func (r *postgresRepo) UnassignStudent(counsellorID, studentID string) error {
+	res, err := r.db.Exec("DELETE FROM counsellor_students WHERE counsellor_id = $1 AND student_id = $2", counsellorID, studentID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *postgresRepo) ListAssignedStudents(counsellorID string) ([]models.User, error) {
+	rows, err := r.db.Query(
+		`SELECT u.id FROM users u JOIN counsellor_students cs ON cs.student_id = u.id
+		WHERE cs.counsellor_id = $1 ORDER BY u.name`,
+		counsellorID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]models.User, 0, len(ids))
+	for _, id := range ids {
+		u, err := r.Get(id)
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, nil
+}
+
+func (r *postgresRepo) IsStudentAssigned(counsellorID, studentID string) (bool, error) {
+	var ok bool
+	err := r.db.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM counsellor_students WHERE counsellor_id = $1 AND student_id = $2)",
+		counsellorID, studentID,
+	).Scan(&ok)
+	return ok, err
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"altoai_mvp/internal/models"
+)
+
+func createAttemptTables(db *sql.DB) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS attempt_counters (
+			key VARCHAR(320) PRIMARY KEY,
+			failures INT NOT NULL DEFAULT 0,
+			window_start TIMESTAMP NOT NULL,
+			lockouts INT NOT NULL DEFAULT 0,
+			locked_until TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS audit_events (
+			id VARCHAR(36) PRIMARY KEY,
+			type VARCHAR(64) NOT NULL,
+			user_id VARCHAR(36),
+			email VARCHAR(255),
+			ip VARCHAR(64),
+			detail TEXT,
+			created_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS audit_events_created_idx ON audit_events (created_at)`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("error creating attempt tables: %v", err)
+		}
+	}
+	return nil
+}
+
+const attemptColumns = "key, failures, window_start, lockouts, locked_until, updated_at"
+
+func scanAttemptCounter(row interface{ Scan(...any) error }) (models.AttemptCounter, error) {
+	var c models.AttemptCounter
+	var lockedUntil sql.NullTime
+	if err := row.Scan(&c.Key, &c.Failures, &c.WindowStart, &c.Lockouts, &lockedUntil, &c.UpdatedAt); err != nil {
+		return models.AttemptCounter{}, err
+	}
+	if lockedUntil.Valid {
+		c.LockedUntil = &lockedUntil.Time
+	}
+	return c, nil
+}
+
+func (r *postgresRepo) GetAttemptCounter(key string) (models.AttemptCounter, error) {
+	c, err := scanAttemptCounter(r.db.QueryRow("SELECT "+attemptColumns+" FROM attempt_counters WHERE key = $1", key))
+	if err == sql.ErrNoRows {
+		return models.AttemptCounter{}, ErrNotFound
+	}
+	return c, err
+}
+
+func (r *postgresRepo) RecordFailedAttempt(key string, at, windowStart time.Time) (models.AttemptCounter, error) {
+	// One statement, so concurrent guesses can't overwrite each other's count
+	return scanAttemptCounter(r.db.QueryRow(
+		`INSERT INTO attempt_counters (key, failures, window_start, lockouts, updated_at) VALUES ($1, 1, $2, 0, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN attempt_counters.window_start < $3 THEN 1 ELSE attempt_counters.failures + 1 END,
+			window_start = CASE WHEN attempt_counters.window_start < $3 THEN $2 ELSE attempt_counters.window_start END,
+			updated_at = $2
+		RETURNING `+attemptColumns,
+		key, at, windowStart,
+	))
+}
+
+func (r *postgresRepo) LockAttempts(key string, at, until time.Time) (models.AttemptCounter, error) {
+	return scanAttemptCounter(r.db.QueryRow(
+		`INSERT INTO attempt_counters (key, failures, window_start, lockouts, locked_until, updated_at) VALUES ($1, 0, $2, 1, $3, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = 0, lockouts = attempt_counters.lockouts + 1, locked_until = $3, updated_at = $2
+		RETURNING `+attemptColumns,
+		key, at, until,
+	))
+}
+
+func (r *postgresRepo) ClearAttempts(key string) error {
+	_, err := r.db.Exec("DELETE FROM attempt_counters WHERE key = $1", key)
+	return err
+}
+
+func (r *postgresRepo) CreateAuditEvent(e models.AuditEvent) error {
+	_, err := r.db.Exec(
+		"INSERT INTO audit_events (id, type, user_id, email, ip, detail, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		e.ID, e.Type, e.UserID, e.Email, e.IP, e.Detail, e.CreatedAt,
+	)
+	return err
+}
+
+func (r *postgresRepo) ListAuditEvents(limit int) ([]models.AuditEvent, error) {
+	rows, err := r.db.Query(
+		`SELECT id, type, COALESCE(user_id, ''), COALESCE(email, ''), COALESCE(ip, ''), COALESCE(detail, ''), created_at
+		FROM audit_events ORDER BY created_at DESC LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.AuditEvent{}
+	for rows.Next() {
+		var e models.AuditEvent
+		if err := rows.Scan(&e.ID, &e.Type, &e.UserID, &e.Email, &e.IP, &e.Detail, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
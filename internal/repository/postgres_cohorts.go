@@ -0,0 +1,309 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"altoai_mvp/internal/models"
+)
+
+func createCohortTables(db *sql.DB) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS cohorts (
+			id VARCHAR(36) PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			organization VARCHAR(255) NOT NULL DEFAULT '',
+			created_by VARCHAR(36) NOT NULL,
+			level VARCHAR(16) NOT NULL DEFAULT '',
+			question_set JSONB NOT NULL DEFAULT '[]',
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS cohort_members (
+			cohort_id VARCHAR(36) NOT NULL REFERENCES cohorts(id) ON DELETE CASCADE,
+			user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			role VARCHAR(32) NOT NULL,
+			joined_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (cohort_id, user_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS cohort_members_user_idx ON cohort_members (user_id)`,
+		`CREATE TABLE IF NOT EXISTS cohort_invites (
+			id VARCHAR(36) PRIMARY KEY,
+			cohort_id VARCHAR(36) NOT NULL REFERENCES cohorts(id) ON DELETE CASCADE,
+			email VARCHAR(255) NOT NULL,
+			role VARCHAR(32) NOT NULL,
+			invited_by VARCHAR(36) NOT NULL,
+			code_hash VARCHAR(64) UNIQUE NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			accepted_at TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS cohort_invites_cohort_idx ON cohort_invites (cohort_id)`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("error creating cohort tables: %v", err)
+		}
+	}
+	return nil
+}
+
+const cohortColumns = "id, name, organization, created_by, level, question_set, created_at, updated_at"
+
+func scanCohort(row interface{ Scan(...any) error }) (models.Cohort, error) {
+	var c models.Cohort
+	var questions []byte
+	if err := row.Scan(&c.ID, &c.Name, &c.Organization, &c.CreatedBy, &c.Level, &questions, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return models.Cohort{}, err
+	}
+	if err := json.Unmarshal(questions, &c.QuestionSet); err != nil {
+		return models.Cohort{}, err
+	}
+	return c, nil
+}
+
+func (r *postgresRepo) queryCohorts(query string, args ...any) ([]models.Cohort, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.Cohort{}
+	for rows.Next() {
+		c, err := scanCohort(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+func (r *postgresRepo) CreateCohort(c models.Cohort) error {
+	questions, err := json.Marshal(c.QuestionSet)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(
+		"INSERT INTO cohorts ("+cohortColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		c.ID, c.Name, c.Organization, c.CreatedBy, c.Level, questions, c.CreatedAt, c.UpdatedAt,
+	)
+	return err
+}
+
+func (r *postgresRepo) GetCohort(id string) (models.Cohort, error) {
+	c, err := scanCohort(r.db.QueryRow("SELECT "+cohortColumns+" FROM cohorts WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return models.Cohort{}, ErrNotFound
+	}
+	return c, err
+}
+
+func (r *postgresRepo) UpdateCohort(c models.Cohort) error {
+	questions, err := json.Marshal(c.QuestionSet)
+	if err != nil {
+		return err
+	}
+	res, err := r.db.Exec(
+		"UPDATE cohorts SET name = $1, organization = $2, level = $3, question_set = $4, updated_at = $5 WHERE id = $6",
+		c.Name, c.Organization, c.Level, questions, c.UpdatedAt, c.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *postgresRepo) DeleteCohort(id string) error {
+	res, err := r.db.Exec("DELETE FROM cohorts WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *postgresRepo) ListCohorts() ([]models.Cohort, error) {
+	return r.queryCohorts("SELECT " + cohortColumns + " FROM cohorts ORDER BY name")
+}
+
+func (r *postgresRepo) ListUserCohorts(userID string) ([]models.Cohort, error) {
+	return r.queryCohorts(
+		"SELECT "+cohortColumns+" FROM cohorts WHERE id IN (SELECT cohort_id FROM cohort_members WHERE user_id = $1) ORDER BY name",
+		userID,
+	)
+}
+
+func (r *postgresRepo) AddCohortMember(m models.CohortMember) error {
+	return addCohortMember(r.db, m)
+}
+
+// addCohortMember adds or updates a membership, keeping the original join time
+func addCohortMember(db interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}, m models.CohortMember) error {
+	res, err := db.Exec(
+		`INSERT INTO cohort_members (cohort_id, user_id, role, joined_at)
+		SELECT $1, $2, $3, $4 WHERE EXISTS (SELECT 1 FROM cohorts WHERE id = $1) AND EXISTS (SELECT 1 FROM users WHERE id = $2)
+		ON CONFLICT (cohort_id, user_id) DO UPDATE SET role = EXCLUDED.role`,
+		m.CohortID, m.UserID, m.Role, m.JoinedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *postgresRepo) RemoveCohortMember(cohortID, userID string) error {
+	res, err := r.db.Exec("DELETE FROM cohort_members WHERE cohort_id = $1 AND user_id = $2", cohortID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *postgresRepo) GetCohortMember(cohortID, userID string) (models.CohortMember, error) {
+	m := models.CohortMember{CohortID: cohortID, UserID: userID}
+	err := r.db.QueryRow(
+		"SELECT role, joined_at FROM cohort_members WHERE cohort_id = $1 AND user_id = $2",
+		cohortID, userID,
+	).Scan(&m.Role, &m.JoinedAt)
+	if err == sql.ErrNoRows {
+		return models.CohortMember{}, ErrNotFound
+	}
+	return m, err
+}
+
+func (r *postgresRepo) ListCohortMembers(cohortID string) ([]models.CohortMember, error) {
+	if _, err := r.GetCohort(cohortID); err != nil {
+		return nil, err
+	}
+	rows, err := r.db.Query(
+		`SELECT cm.user_id, cm.role, cm.joined_at FROM cohort_members cm JOIN users u ON u.id = cm.user_id
+		WHERE cm.cohort_id = $1 ORDER BY u.name`,
+		cohortID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	var members []models.CohortMember
+	for rows.Next() {
+		m := models.CohortMember{CohortID: cohortID}
+		if err := rows.Scan(&m.UserID, &m.Role, &m.JoinedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]models.CohortMember, 0, len(members))
+	for _, m := range members {
+		u, err := r.Get(m.UserID)
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		m.User = &u
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+const inviteColumns = "id, cohort_id, email, role, invited_by, code_hash, created_at, expires_at, accepted_at"
+
+func scanInvite(row interface{ Scan(...any) error }) (models.CohortInvite, error) {
+	var i models.CohortInvite
+	var acceptedAt sql.NullTime
+	if err := row.Scan(&i.ID, &i.CohortID, &i.Email, &i.Role, &i.InvitedBy, &i.CodeHash, &i.CreatedAt, &i.ExpiresAt, &acceptedAt); err != nil {
+		return models.CohortInvite{}, err
+	}
+	if acceptedAt.Valid {
+		i.AcceptedAt = &acceptedAt.Time
+	}
+	return i, nil
+}
+
+func (r *postgresRepo) CreateCohortInvite(i models.CohortInvite) error {
+	_, err := r.db.Exec(
+		"INSERT INTO cohort_invites ("+inviteColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULL)",
+		i.ID, i.CohortID, i.Email, i.Role, i.InvitedBy, i.CodeHash, i.CreatedAt, i.ExpiresAt,
+	)
+	return err
+}
+
+func (r *postgresRepo) GetCohortInvite(codeHash string) (models.CohortInvite, error) {
+	i, err := scanInvite(r.db.QueryRow("SELECT "+inviteColumns+" FROM cohort_invites WHERE code_hash = $1", codeHash))
+	if err == sql.ErrNoRows {
+		return models.CohortInvite{}, ErrNotFound
+	}
+	return i, err
+}
+
+func (r *postgresRepo) AcceptCohortInvite(inviteID string, m models.CohortMember, at time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Only one request can claim the invite
+	res, err := tx.Exec("UPDATE cohort_invites SET accepted_at = $1 WHERE id = $2 AND accepted_at IS NULL", at, inviteID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		var exists bool
+		if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM cohort_invites WHERE id = $1)", inviteID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return ErrNotFound
+		}
+		return ErrInviteSpent
+	}
+	if err := addCohortMember(tx, m); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *postgresRepo) ListCohortInvites(cohortID string) ([]models.CohortInvite, error) {
+	rows, err := r.db.Query(
+		"SELECT "+inviteColumns+" FROM cohort_invites WHERE cohort_id = $1 ORDER BY created_at DESC",
+		cohortID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.CohortInvite{}
+	for rows.Next() {
+		i, err := scanInvite(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, i)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"altoai_mvp/internal/models"
+
+	"github.com/lib/pq"
+)
+
+func createEmailChangesTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS email_changes (
+			user_id VARCHAR(36) PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+			new_email VARCHAR(255) NOT NULL,
+			requested_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("error creating email_changes table: %v", err)
+	}
+	return nil
+}
+
+func (r *postgresRepo) SetPendingEmail(userID, email string, at time.Time) error {
+	_, err := r.db.Exec(
+		`INSERT INTO email_changes (user_id, new_email, requested_at) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET new_email = EXCLUDED.new_email, requested_at = EXCLUDED.requested_at`,
+		userID, email, at,
+	)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (r *postgresRepo) ConfirmEmailChange(userID, code string, at time.Time) (models.User, error) {
+	err := r.consumeCode(userID, models.CodePurposeChangeEmail, code, at, func(tx *sql.Tx, now time.Time) error {
+		var email string
+		err := tx.QueryRow("SELECT new_email FROM email_changes WHERE user_id = $1 FOR UPDATE", userID).Scan(&email)
+		if err == sql.ErrNoRows {
+			return ErrInvalidCode
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(
+			"UPDATE users SET email = $1, email_verified = TRUE, updated_at = $2 WHERE id = $3",
+			email, now, userID,
+		)
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			// Rolling back keeps the code, so the user can retry once the address is free
+			return ErrEmailTaken
+		}
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM email_changes WHERE user_id = $1", userID)
+		return err
+	})
+	if err != nil {
+		return models.User{}, err
+	}
+	return r.Get(userID)
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"altoai_mvp/internal/models"
+
+	"github.com/lib/pq"
+)
+
+func createUserIdentitiesTable(db *sql.DB) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS user_identities (
+			id VARCHAR(36) PRIMARY KEY,
+			user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			provider VARCHAR(64) NOT NULL,
+			subject VARCHAR(255) NOT NULL,
+			email VARCHAR(255),
+			created_at TIMESTAMP NOT NULL,
+			UNIQUE (provider, subject)
+		)`,
+		`CREATE INDEX IF NOT EXISTS user_identities_user_idx ON user_identities (user_id)`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("error creating user_identities table: %v", err)
+		}
+	}
+	return nil
+}
+
+const identityColumns = "id, user_id, provider, subject, email, created_at"
+
+func scanIdentity(row interface{ Scan(...any) error }) (models.UserIdentity, error) {
+	var i models.UserIdentity
+	var email sql.NullString
+	if err := row.Scan(&i.ID, &i.UserID, &i.Provider, &i.Subject, &email, &i.CreatedAt); err != nil {
+		return models.UserIdentity{}, err
+	}
+	i.Email = email.String
+	return i, nil
+}
+
+func (r *postgresRepo) GetIdentity(provider, subject string) (models.UserIdentity, error) {
+	i, err := scanIdentity(r.db.QueryRow(
+		"SELECT "+identityColumns+" FROM user_identities WHERE provider = $1 AND subject = $2",
+		provider, subject,
+	))
+	if err == sql.ErrNoRows {
+		return models.UserIdentity{}, ErrNotFound
+	}
+	return i, err
+}
+
+func (r *postgresRepo) CreateIdentity(i models.UserIdentity) error {
+	_, err := r.db.Exec(
+		"INSERT INTO user_identities ("+identityColumns+") VALUES ($1, $2, $3, $4, $5, $6)",
+		i.ID, i.UserID, i.Provider, i.Subject, i.Email, i.CreatedAt,
+	)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return ErrIdentityTaken
+	}
+	return err
+}
+
+func (r *postgresRepo) ListIdentities(userID string) ([]models.UserIdentity, error) {
+	rows, err := r.db.Query(
+		"SELECT "+identityColumns+" FROM user_identities WHERE user_id = $1 ORDER BY created_at",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.UserIdentity
+	for rows.Next() {
+		i, err := scanIdentity(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, i)
+	}
+	return out, rows.Err()
+}
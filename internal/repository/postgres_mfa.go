@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"altoai_mvp/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func createMFATables(db *sql.DB) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS user_mfa (
+			user_id VARCHAR(36) PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+			secret VARCHAR(64) NOT NULL,
+			confirmed_at TIMESTAMP,
+			last_used_step BIGINT NOT NULL DEFAULT 0,
+			created_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS recovery_codes (
+			id VARCHAR(36) PRIMARY KEY,
+			user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			code_hash VARCHAR(64) NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			used_at TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS recovery_codes_user_idx ON recovery_codes (user_id)`,
+		`CREATE TABLE IF NOT EXISTS mfa_required_roles (
+			role VARCHAR(32) PRIMARY KEY
+		)`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("error creating MFA tables: %v", err)
+		}
+	}
+	return nil
+}
+
+func (r *postgresRepo) GetMFA(userID string) (models.UserMFA, error) {
+	var m models.UserMFA
+	var confirmedAt sql.NullTime
+	err := r.db.QueryRow(
+		"SELECT user_id, secret, confirmed_at, last_used_step, created_at FROM user_mfa WHERE user_id = $1",
+		userID,
+	).Scan(&m.UserID, &m.Secret, &confirmedAt, &m.LastUsedStep, &m.CreatedAt)
+	if err == sql.ErrNoRows {
+		return models.UserMFA{}, ErrNotFound
+	}
+	if err != nil {
+		return models.UserMFA{}, err
+	}
+	if confirmedAt.Valid {
+		m.ConfirmedAt = &confirmedAt.Time
+	}
+	return m, nil
+}
+
+func (r *postgresRepo) SaveMFA(m models.UserMFA) error {
+	_, err := r.db.Exec(
+		`INSERT INTO user_mfa (user_id, secret, confirmed_at, last_used_step, created_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE SET
+			secret = EXCLUDED.secret, confirmed_at = EXCLUDED.confirmed_at,
+			last_used_step = EXCLUDED.last_used_step, created_at = EXCLUDED.created_at`,
+		m.UserID, m.Secret, m.ConfirmedAt, m.LastUsedStep, m.CreatedAt,
+	)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (r *postgresRepo) DeleteMFA(userID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM user_mfa WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *postgresRepo) MarkTOTPStepUsed(userID string, step int64) (bool, error) {
+	res, err := r.db.Exec(
+		"UPDATE user_mfa SET last_used_step = $1 WHERE user_id = $2 AND last_used_step < $1",
+		step, userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		if _, err := r.GetMFA(userID); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+func (r *postgresRepo) ReplaceRecoveryCodes(userID string, codeHashes []string, at time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	for _, h := range codeHashes {
+		if _, err := tx.Exec(
+			"INSERT INTO recovery_codes (id, user_id, code_hash, created_at) VALUES ($1, $2, $3, $4)",
+			uuid.NewString(), userID, h, at,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *postgresRepo) UseRecoveryCode(userID, codeHash string, at time.Time) error {
+	// Only one request can use each code
+	res, err := r.db.Exec(
+		`UPDATE recovery_codes SET used_at = $1
+		WHERE id = (SELECT id FROM recovery_codes WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL LIMIT 1)
+		AND used_at IS NULL`,
+		at, userID, codeHash,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *postgresRepo) CountRecoveryCodes(userID string) (int, error) {
+	var n int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL", userID).Scan(&n)
+	return n, err
+}
+
+func (r *postgresRepo) ListMFARequiredRoles() ([]models.Role, error) {
+	rows, err := r.db.Query("SELECT role FROM mfa_required_roles ORDER BY role")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []models.Role{}
+	for rows.Next() {
+		var role models.Role
+		if err := rows.Scan(&role); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+func (r *postgresRepo) SetMFARequiredRoles(roles []models.Role) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM mfa_required_roles"); err != nil {
+		return err
+	}
+	for _, role := range roles {
+		if _, err := tx.Exec("INSERT INTO mfa_required_roles (role) VALUES ($1) ON CONFLICT DO NOTHING", role); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"altoai_mvp/internal/models"
+
+	"github.com/lib/pq"
+)
+
+func createOneTimeCodesTable(db *sql.DB, hasher codeHasher) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS one_time_codes (
+			id VARCHAR(36) PRIMARY KEY,
+			user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			purpose VARCHAR(32) NOT NULL,
+			code_hash VARCHAR(64) NOT NULL,
+			attempts INT NOT NULL DEFAULT 0,
+			expires_at TIMESTAMP NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			UNIQUE (user_id, purpose)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("error creating one_time_codes table: %v", err)
+	}
+	if err := migrateUserCodes(db, hasher); err != nil {
+		return fmt.Errorf("error migrating codes to one_time_codes: %v", err)
+	}
+	return nil
+}
+
+// migrateUserCodes moves live codes that older versions kept in plain text on the users
+// table into one_time_codes, hashed, and drops the old columns
+func migrateUserCodes(db *sql.DB, hasher codeHasher) error {
+	legacy := []struct {
+		column  string
+		purpose models.CodePurpose
+	}{
+		{"verification_code", models.CodePurposeVerifyEmail},
+		{"reset_code", models.CodePurposeResetPassword},
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, l := range legacy {
+		var exists bool
+		err := tx.QueryRow(`
+			SELECT EXISTS (
+				SELECT 1 FROM information_schema.columns
+				WHERE table_name = 'users' AND column_name = $1
+			)
+		`, l.column).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			continue
+		}
+
+		var codes []models.OneTimeCode
+		rows, err := tx.Query(
+			fmt.Sprintf("SELECT id, %[1]s, %[1]s_expires FROM users WHERE %[1]s IS NOT NULL AND %[1]s <> '' AND %[1]s_expires > $1", l.column),
+			time.Now().UTC(),
+		)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var userID, code string
+			var expiresAt time.Time
+			if err := rows.Scan(&userID, &code, &expiresAt); err != nil {
+				rows.Close()
+				return err
+			}
+			codes = append(codes, hasher.newCode(userID, l.purpose, code, expiresAt))
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, c := range codes {
+			if _, err := tx.Exec(
+				"INSERT INTO one_time_codes ("+codeColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (user_id, purpose) DO NOTHING",
+				c.ID, c.UserID, c.Purpose, c.CodeHash, c.Attempts, c.ExpiresAt, c.CreatedAt,
+			); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE users DROP COLUMN %[1]s, DROP COLUMN IF EXISTS %[1]s_expires", l.column)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+const codeColumns = "id, user_id, purpose, code_hash, attempts, expires_at, created_at"
+
+func (r *postgresRepo) SetOneTimeCode(userID string, purpose models.CodePurpose, code string, expiresAt time.Time) error {
+	c := r.hasher.newCode(userID, purpose, code, expiresAt)
+	_, err := r.db.Exec(
+		`INSERT INTO one_time_codes (`+codeColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id, purpose) DO UPDATE SET
+			id = EXCLUDED.id, code_hash = EXCLUDED.code_hash, attempts = 0,
+			expires_at = EXCLUDED.expires_at, created_at = EXCLUDED.created_at`,
+		c.ID, c.UserID, c.Purpose, c.CodeHash, c.Attempts, c.ExpiresAt, c.CreatedAt,
+	)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (r *postgresRepo) ConsumeOneTimeCode(userID string, purpose models.CodePurpose, code string, at time.Time) error {
+	return r.consumeCode(userID, purpose, code, at, nil)
+}
+
+// consumeCode checks the user's code as of at and, if it matches, deletes it and runs apply
+// in the same transaction. A wrong guess is still committed so it counts against the code.
+func (r *postgresRepo) consumeCode(userID string, purpose models.CodePurpose, code string, at time.Time, apply func(tx *sql.Tx, now time.Time) error) error {
+	now := at.UTC()
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var c models.OneTimeCode
+	err = tx.QueryRow(
+		"SELECT "+codeColumns+" FROM one_time_codes WHERE user_id = $1 AND purpose = $2 FOR UPDATE",
+		userID, purpose,
+	).Scan(&c.ID, &c.UserID, &c.Purpose, &c.CodeHash, &c.Attempts, &c.ExpiresAt, &c.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrInvalidCode
+	}
+	if err != nil {
+		return err
+	}
+
+	keep, checkErr := r.hasher.check(&c, code, now)
+	if keep {
+		_, err = tx.Exec("UPDATE one_time_codes SET attempts = $1 WHERE id = $2", c.Attempts, c.ID)
+	} else {
+		_, err = tx.Exec("DELETE FROM one_time_codes WHERE id = $1", c.ID)
+	}
+	if err != nil {
+		return err
+	}
+	if checkErr == nil && apply != nil {
+		if err := apply(tx, now); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return checkErr
+}
+
+func (r *postgresRepo) DeleteOneTimeCode(userID string, purpose models.CodePurpose) error {
+	_, err := r.db.Exec("DELETE FROM one_time_codes WHERE user_id = $1 AND purpose = $2", userID, purpose)
+	return err
+}
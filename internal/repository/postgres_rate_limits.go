@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"altoai_mvp/internal/middleware"
+)
+
+// postgresRateLimitStore shares token buckets between replicas through the rate_limit_buckets table
+type postgresRateLimitStore struct {
+	db *sql.DB
+
+	sweepMu   sync.Mutex
+	lastSweep time.Time
+}
+
+// NewPostgresRateLimitStore returns a middleware.RateLimitStore backed by Postgres
+func NewPostgresRateLimitStore(db *sql.DB) (middleware.RateLimitStore, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+			key VARCHAR(320) PRIMARY KEY,
+			tokens DOUBLE PRECISION NOT NULL,
+			updated_at TIMESTAMP NOT NULL,
+			full_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("error creating rate_limit_buckets table: %v", err)
+	}
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS rate_limit_buckets_full_idx ON rate_limit_buckets (full_at)`)
+	if err != nil {
+		return nil, fmt.Errorf("error creating rate_limit_buckets index: %v", err)
+	}
+	return &postgresRateLimitStore{db: db}, nil
+}
+
+func (s *postgresRateLimitStore) Take(ctx context.Context, key string, limit middleware.RateLimit, now time.Time) (middleware.RateLimitResult, error) {
+	now = now.UTC()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return middleware.RateLimitResult{}, err
+	}
+	defer tx.Rollback()
+
+	// Create a full bucket on first use, then lock the row so concurrent requests on
+	// any replica take tokens one at a time
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO rate_limit_buckets (key, tokens, updated_at, full_at) VALUES ($1, $2, $3, $3)
+		ON CONFLICT (key) DO NOTHING`,
+		key, float64(limit.Burst), now,
+	)
+	if err != nil {
+		return middleware.RateLimitResult{}, err
+	}
+	var tokens float64
+	var updated time.Time
+	err = tx.QueryRowContext(ctx, "SELECT tokens, updated_at FROM rate_limit_buckets WHERE key = $1 FOR UPDATE", key).Scan(&tokens, &updated)
+	if err != nil {
+		return middleware.RateLimitResult{}, err
+	}
+
+	tokens, res := middleware.TakeToken(tokens, updated, limit, now)
+	if now.After(updated) {
+		updated = now
+	}
+	_, err = tx.ExecContext(ctx,
+		"UPDATE rate_limit_buckets SET tokens = $1, updated_at = $2, full_at = $3 WHERE key = $4",
+		tokens, updated, now.Add(res.Reset), key,
+	)
+	if err != nil {
+		return middleware.RateLimitResult{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return middleware.RateLimitResult{}, err
+	}
+
+	s.sweep(now)
+	return res, nil
+}
+
+// sweep deletes buckets that have refilled, at most once a minute per replica; a missing
+// bucket is the same as a full one
+func (s *postgresRateLimitStore) sweep(now time.Time) {
+	s.sweepMu.Lock()
+	if now.Sub(s.lastSweep) < time.Minute {
+		s.sweepMu.Unlock()
+		return
+	}
+	s.lastSweep = now
+	s.sweepMu.Unlock()
+	s.db.Exec("DELETE FROM rate_limit_buckets WHERE full_at < $1", now)
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"altoai_mvp/internal/models"
+)
+
+func createRefreshTokensTable(db *sql.DB) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS refresh_tokens (
+			id VARCHAR(36) PRIMARY KEY,
+			user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			family_id VARCHAR(36) NOT NULL,
+			token_hash CHAR(64) UNIQUE NOT NULL,
+			device VARCHAR(255),
+			user_agent TEXT,
+			ip VARCHAR(64),
+			issued_at TIMESTAMP NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			last_used_at TIMESTAMP,
+			revoked_at TIMESTAMP,
+			replaced_by VARCHAR(36)
+		)`,
+		`CREATE INDEX IF NOT EXISTS refresh_tokens_family_idx ON refresh_tokens (family_id)`,
+		`CREATE INDEX IF NOT EXISTS refresh_tokens_user_idx ON refresh_tokens (user_id)`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("error creating refresh_tokens table: %v", err)
+		}
+	}
+	return nil
+}
+
+const refreshTokenColumns = "id, user_id, family_id, token_hash, device, user_agent, ip, issued_at, expires_at, last_used_at, revoked_at, replaced_by"
+
+func scanRefreshToken(row interface{ Scan(...any) error }) (models.RefreshToken, error) {
+	var t models.RefreshToken
+	var device, userAgent, ip, replacedBy sql.NullString
+	var lastUsedAt, revokedAt sql.NullTime
+	err := row.Scan(&t.ID, &t.UserID, &t.FamilyID, &t.TokenHash, &device, &userAgent, &ip,
+		&t.IssuedAt, &t.ExpiresAt, &lastUsedAt, &revokedAt, &replacedBy)
+	if err != nil {
+		return models.RefreshToken{}, err
+	}
+	t.Device = device.String
+	t.UserAgent = userAgent.String
+	t.IP = ip.String
+	t.ReplacedBy = replacedBy.String
+	if lastUsedAt.Valid {
+		t.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		t.RevokedAt = &revokedAt.Time
+	}
+	return t, nil
+}
+
+func (r *postgresRepo) CreateRefreshToken(t models.RefreshToken) error {
+	return insertRefreshToken(r.db, t)
+}
+
+func insertRefreshToken(db interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}, t models.RefreshToken) error {
+	_, err := db.Exec(
+		"INSERT INTO refresh_tokens (id, user_id, family_id, token_hash, device, user_agent, ip, issued_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)",
+		t.ID, t.UserID, t.FamilyID, t.TokenHash, t.Device, t.UserAgent, t.IP, t.IssuedAt, t.ExpiresAt,
+	)
+	return err
+}
+
+func (r *postgresRepo) GetRefreshToken(tokenHash string) (models.RefreshToken, error) {
+	t, err := scanRefreshToken(r.db.QueryRow(
+		"SELECT "+refreshTokenColumns+" FROM refresh_tokens WHERE token_hash = $1",
+		tokenHash,
+	))
+	if err == sql.ErrNoRows {
+		return models.RefreshToken{}, ErrNotFound
+	}
+	return t, err
+}
+
+func (r *postgresRepo) RotateRefreshToken(oldHash string, next models.RefreshToken, usedAt time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Only one concurrent rotation can claim the old token
+	result, err := tx.Exec(
+		"UPDATE refresh_tokens SET replaced_by = $1, last_used_at = $2 WHERE token_hash = $3 AND replaced_by IS NULL AND revoked_at IS NULL",
+		next.ID, usedAt, oldHash,
+	)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRefreshTokenSpent
+	}
+
+	if err := insertRefreshToken(tx, next); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *postgresRepo) RevokeRefreshTokenFamily(familyID string, at time.Time) error {
+	_, err := r.db.Exec(
+		"UPDATE refresh_tokens SET revoked_at = $1 WHERE family_id = $2 AND revoked_at IS NULL",
+		at, familyID,
+	)
+	return err
+}
+
+func (r *postgresRepo) ListRefreshTokens(userID string) ([]models.RefreshToken, error) {
+	rows, err := r.db.Query(
+		"SELECT "+refreshTokenColumns+" FROM refresh_tokens WHERE user_id = $1 ORDER BY issued_at",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.RefreshToken
+	for rows.Next() {
+		t, err := scanRefreshToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+func (r *postgresRepo) RevokeAllRefreshTokens(userID string, at time.Time) error {
+	_, err := r.db.Exec(
+		"UPDATE refresh_tokens SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL",
+		at, userID,
+	)
+	return err
+}
+
+func (r *postgresRepo) IncrementTokenVersion(id string) (int, error) {
+	var version int
+	err := r.db.QueryRow(
+		"UPDATE users SET token_version = token_version + 1, updated_at = $1 WHERE id = $2 RETURNING token_version",
+		time.Now().UTC(), id,
+	).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, ErrNotFound
+	}
+	return version, err
+}
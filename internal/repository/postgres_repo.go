@@ -2,7 +2,6 @@ package repository
 
 import (
 	"database/sql"
-	"errors"
 	"fmt"
 	"os"
 	"time"
@@ -14,10 +13,12 @@ import (
 )
 
 type postgresRepo struct {
-	db *sql.DB
+	db     *sql.DB
+	hasher codeHasher
 }
 
-func NewPostgresRepo() (UserRepo, error) {
+// OpenPostgres connects to the database configured by the POSTGRES_* environment variables
+func OpenPostgres() (*sql.DB, error) {
 	connStr := fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 		os.Getenv("POSTGRES_HOST"),
@@ -36,8 +37,12 @@ func NewPostgresRepo() (UserRepo, error) {
 		return nil, fmt.Errorf("error pinging database: %v", err)
 	}
 
+	return db, nil
+}
+
+func NewPostgresRepo(db *sql.DB) (UserRepo, error) {
 	// Create users table if it doesn't exist
-	_, err = db.Exec(`
+	_, err := db.Exec(`
 		CREATE TABLE IF NOT EXISTS users (
 			id VARCHAR(36) PRIMARY KEY,
 			email VARCHAR(255) UNIQUE NOT NULL,
@@ -46,10 +51,6 @@ func NewPostgresRepo() (UserRepo, error) {
 			email_verified BOOLEAN DEFAULT FALSE,
 			college VARCHAR(255),
 			major VARCHAR(255),
-			verification_code VARCHAR(6),
-			verification_code_expires TIMESTAMP,
-			reset_code VARCHAR(6),
-			reset_code_expires TIMESTAMP,
 			created_at TIMESTAMP NOT NULL,
 			updated_at TIMESTAMP NOT NULL
 		)
@@ -64,10 +65,8 @@ func NewPostgresRepo() (UserRepo, error) {
 		`ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified BOOLEAN DEFAULT FALSE`,
 		`ALTER TABLE users ADD COLUMN IF NOT EXISTS college VARCHAR(255)`,
 		`ALTER TABLE users ADD COLUMN IF NOT EXISTS major VARCHAR(255)`,
-		`ALTER TABLE users ADD COLUMN IF NOT EXISTS verification_code VARCHAR(6)`,
-		`ALTER TABLE users ADD COLUMN IF NOT EXISTS verification_code_expires TIMESTAMP`,
-		`ALTER TABLE users ADD COLUMN IF NOT EXISTS reset_code VARCHAR(6)`,
-		`ALTER TABLE users ADD COLUMN IF NOT EXISTS reset_code_expires TIMESTAMP`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS token_version INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(32) NOT NULL DEFAULT 'student'`,
 	}
 
 	// Check if password column exists and rename it to password_hash if needed
@@ -106,11 +105,43 @@ func NewPostgresRepo() (UserRepo, error) {
 		}
 	}
 
-	return &postgresRepo{db: db}, nil
+	if err := createRefreshTokensTable(db); err != nil {
+		return nil, err
+	}
+	if err := createUserIdentitiesTable(db); err != nil {
+		return nil, err
+	}
+	if err := createCounsellorStudentsTable(db); err != nil {
+		return nil, err
+	}
+	if err := createCohortTables(db); err != nil {
+		return nil, err
+	}
+	if err := createAttemptTables(db); err != nil {
+		return nil, err
+	}
+	hasher := codeHasherFromEnv()
+	if hasher == nil {
+		return nil, fmt.Errorf("CODE_HASH_KEY or JWT_SECRET must be set to store one-time codes")
+	}
+	if err := createOneTimeCodesTable(db, hasher); err != nil {
+		return nil, err
+	}
+	if err := createEmailChangesTable(db); err != nil {
+		return nil, err
+	}
+	if err := createMFATables(db); err != nil {
+		return nil, err
+	}
+	if err := createReviewItemsTable(db); err != nil {
+		return nil, err
+	}
+
+	return &postgresRepo{db: db, hasher: hasher}, nil
 }
 
 func (r *postgresRepo) List() ([]models.User, error) {
-	rows, err := r.db.Query("SELECT id, email, name, password_hash, email_verified, college, major, verification_code, verification_code_expires, reset_code, reset_code_expires, created_at, updated_at FROM users")
+	rows, err := r.db.Query("SELECT id, email, name, password_hash, email_verified, college, major, token_version, role, created_at, updated_at FROM users")
 	if err != nil {
 		return nil, err
 	}
@@ -119,27 +150,14 @@ func (r *postgresRepo) List() ([]models.User, error) {
 	var users []models.User
 	for rows.Next() {
 		var u models.User
-		var passwordHash, verificationCode, resetCode, college, major sql.NullString
-		var verificationCodeExpires, resetCodeExpires sql.NullTime
-		err := rows.Scan(&u.ID, &u.Email, &u.Name, &passwordHash, &u.EmailVerified, &college, &major, &verificationCode, &verificationCodeExpires, &resetCode, &resetCodeExpires, &u.CreatedAt, &u.UpdatedAt)
+		var passwordHash, college, major sql.NullString
+		err := rows.Scan(&u.ID, &u.Email, &u.Name, &passwordHash, &u.EmailVerified, &college, &major, &u.TokenVersion, &u.Role, &u.CreatedAt, &u.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
 		if passwordHash.Valid {
 			u.Password = passwordHash.String
 		}
-		if verificationCode.Valid {
-			u.VerificationCode = verificationCode.String
-		}
-		if resetCode.Valid {
-			u.ResetCode = resetCode.String
-		}
-		if verificationCodeExpires.Valid {
-			u.VerificationCodeExpires = verificationCodeExpires.Time
-		}
-		if resetCodeExpires.Valid {
-			u.ResetCodeExpires = resetCodeExpires.Time
-		}
 		if college.Valid {
 			u.College = college.String
 		}
@@ -153,12 +171,11 @@ func (r *postgresRepo) List() ([]models.User, error) {
 
 func (r *postgresRepo) Get(id string) (models.User, error) {
 	var u models.User
-	var passwordHash, verificationCode, resetCode, college, major sql.NullString
-	var verificationCodeExpires, resetCodeExpires sql.NullTime
+	var passwordHash, college, major sql.NullString
 	err := r.db.QueryRow(
-		"SELECT id, email, name, password_hash, email_verified, college, major, verification_code, verification_code_expires, reset_code, reset_code_expires, created_at, updated_at FROM users WHERE id = $1",
+		"SELECT id, email, name, password_hash, email_verified, college, major, token_version, role, created_at, updated_at FROM users WHERE id = $1",
 		id,
-	).Scan(&u.ID, &u.Email, &u.Name, &passwordHash, &u.EmailVerified, &college, &major, &verificationCode, &verificationCodeExpires, &resetCode, &resetCodeExpires, &u.CreatedAt, &u.UpdatedAt)
+	).Scan(&u.ID, &u.Email, &u.Name, &passwordHash, &u.EmailVerified, &college, &major, &u.TokenVersion, &u.Role, &u.CreatedAt, &u.UpdatedAt)
 
 	if err == sql.ErrNoRows {
 		return models.User{}, ErrNotFound
@@ -169,29 +186,16 @@ func (r *postgresRepo) Get(id string) (models.User, error) {
 	if passwordHash.Valid {
 		u.Password = passwordHash.String
 	}
-	if verificationCode.Valid {
-		u.VerificationCode = verificationCode.String
-	}
-	if resetCode.Valid {
-		u.ResetCode = resetCode.String
-	}
-	if verificationCodeExpires.Valid {
-		u.VerificationCodeExpires = verificationCodeExpires.Time
-	}
-	if resetCodeExpires.Valid {
-		u.ResetCodeExpires = resetCodeExpires.Time
-	}
 	return u, nil
 }
 
 func (r *postgresRepo) GetByEmail(email string) (models.User, error) {
 	var u models.User
-	var passwordHash, verificationCode, resetCode, college, major sql.NullString
-	var verificationCodeExpires, resetCodeExpires sql.NullTime
+	var passwordHash, college, major sql.NullString
 	err := r.db.QueryRow(
-		"SELECT id, email, name, password_hash, email_verified, college, major, verification_code, verification_code_expires, reset_code, reset_code_expires, created_at, updated_at FROM users WHERE email = $1",
+		"SELECT id, email, name, password_hash, email_verified, college, major, token_version, role, created_at, updated_at FROM users WHERE email = $1",
 		email,
-	).Scan(&u.ID, &u.Email, &u.Name, &passwordHash, &u.EmailVerified, &college, &major, &verificationCode, &verificationCodeExpires, &resetCode, &resetCodeExpires, &u.CreatedAt, &u.UpdatedAt)
+	).Scan(&u.ID, &u.Email, &u.Name, &passwordHash, &u.EmailVerified, &college, &major, &u.TokenVersion, &u.Role, &u.CreatedAt, &u.UpdatedAt)
 
 	if err == sql.ErrNoRows {
 		return models.User{}, ErrNotFound
@@ -202,18 +206,6 @@ func (r *postgresRepo) GetByEmail(email string) (models.User, error) {
 	if passwordHash.Valid {
 		u.Password = passwordHash.String
 	}
-	if verificationCode.Valid {
-		u.VerificationCode = verificationCode.String
-	}
-	if resetCode.Valid {
-		u.ResetCode = resetCode.String
-	}
-	if verificationCodeExpires.Valid {
-		u.VerificationCodeExpires = verificationCodeExpires.Time
-	}
-	if resetCodeExpires.Valid {
-		u.ResetCodeExpires = resetCodeExpires.Time
-	}
 	if college.Valid {
 		u.College = college.String
 	}
@@ -231,13 +223,14 @@ func (r *postgresRepo) Create(email, name, passwordHash string) (models.User, er
 		Name:          name,
 		Password:      passwordHash,
 		EmailVerified: false,
+		Role:          models.RoleStudent,
 		CreatedAt:     now,
 		UpdatedAt:     now,
 	}
 
 	_, err := r.db.Exec(
-		"INSERT INTO users (id, email, name, password_hash, email_verified, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7)",
-		u.ID, u.Email, u.Name, u.Password, u.EmailVerified, u.CreatedAt, u.UpdatedAt,
+		"INSERT INTO users (id, email, name, password_hash, email_verified, role, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		u.ID, u.Email, u.Name, u.Password, u.EmailVerified, u.Role, u.CreatedAt, u.UpdatedAt,
 	)
 	if err != nil {
 		return models.User{}, err
@@ -332,48 +325,16 @@ func (r *postgresRepo) Delete(id string) error {
 	return nil
 }
 
-func (r *postgresRepo) SetVerificationCode(email, code string, expiresAt time.Time) error {
-	_, err := r.db.Exec(
-		"UPDATE users SET verification_code = $1, verification_code_expires = $2, updated_at = $3 WHERE email = $4",
-		code, expiresAt, time.Now().UTC(), email,
-	)
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
 func (r *postgresRepo) VerifyEmail(email, code string) error {
-	var storedCode sql.NullString
-	var expiresAt sql.NullTime
-	err := r.db.QueryRow(
-		"SELECT verification_code, verification_code_expires FROM users WHERE email = $1",
-		email,
-	).Scan(&storedCode, &expiresAt)
-
-	if err == sql.ErrNoRows {
-		return ErrNotFound
-	}
+	u, err := r.GetByEmail(email)
 	if err != nil {
 		return err
 	}
-
-	if !storedCode.Valid || storedCode.String != code {
-		return errors.New("invalid verification code")
-	}
-	if !expiresAt.Valid {
-		return errors.New("verification code expired")
-	}
-	// Use UTC for comparison to avoid timezone issues
-	if time.Now().UTC().After(expiresAt.Time) {
-		return errors.New("verification code expired")
-	}
-
-	_, err = r.db.Exec(
-		"UPDATE users SET email_verified = TRUE, verification_code = NULL, verification_code_expires = NULL, updated_at = $1 WHERE email = $2",
-		time.Now().UTC(), email,
-	)
-	return err
+	err = r.consumeCode(u.ID, models.CodePurposeVerifyEmail, code, time.Now(), func(tx *sql.Tx, now time.Time) error {
+		_, err := tx.Exec("UPDATE users SET email_verified = TRUE, updated_at = $1 WHERE id = $2", now, u.ID)
+		return err
+	})
+	return codeError(err, ErrInvalidVerificationCode, ErrVerificationCodeExpired)
 }
 
 func (r *postgresRepo) MarkEmailVerified(email string) error {
@@ -387,48 +348,33 @@ func (r *postgresRepo) MarkEmailVerified(email string) error {
 	return nil
 }
 
-func (r *postgresRepo) SetResetCode(email, code string, expiresAt time.Time) error {
-	_, err := r.db.Exec(
-		"UPDATE users SET reset_code = $1, reset_code_expires = $2, updated_at = $3 WHERE email = $4",
-		code, expiresAt, time.Now().UTC(), email,
-	)
+func (r *postgresRepo) ResetPassword(email, code, newPasswordHash string) error {
+	u, err := r.GetByEmail(email)
 	if err != nil {
 		return err
 	}
-	return nil
+	err = r.consumeCode(u.ID, models.CodePurposeResetPassword, code, time.Now(), func(tx *sql.Tx, now time.Time) error {
+		_, err := tx.Exec("UPDATE users SET password_hash = $1, updated_at = $2 WHERE id = $3", newPasswordHash, now, u.ID)
+		return err
+	})
+	return codeError(err, ErrInvalidResetCode, ErrResetCodeExpired)
 }
 
-func (r *postgresRepo) ResetPassword(email, code, newPasswordHash string) error {
-	var storedCode sql.NullString
-	var expiresAt sql.NullTime
-	err := r.db.QueryRow(
-		"SELECT reset_code, reset_code_expires FROM users WHERE email = $1",
-		email,
-	).Scan(&storedCode, &expiresAt)
-
-	if err == sql.ErrNoRows {
-		return ErrNotFound
-	}
+func (r *postgresRepo) UpdatePassword(id, passwordHash string) error {
+	result, err := r.db.Exec("UPDATE users SET password_hash = $1, updated_at = $2 WHERE id = $3", passwordHash, time.Now().UTC(), id)
 	if err != nil {
 		return err
 	}
 
-	if !storedCode.Valid || storedCode.String != code {
-		return errors.New("invalid reset code")
-	}
-	if !expiresAt.Valid {
-		return errors.New("reset code expired")
-	}
-	// Use UTC for comparison to avoid timezone issues
-	if time.Now().UTC().After(expiresAt.Time) {
-		return errors.New("reset code expired")
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
 	}
 
-	_, err = r.db.Exec(
-		"UPDATE users SET password_hash = $1, reset_code = NULL, reset_code_expires = NULL, updated_at = $2 WHERE email = $3",
-		newPasswordHash, time.Now().UTC(), email,
-	)
-	return err
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
 }
 
 func (r *postgresRepo) Close() error {
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"altoai_mvp/interview"
+
+	"github.com/lib/pq"
+)
+
+func createReviewItemsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS review_items (
+			user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			category VARCHAR(64) NOT NULL,
+			text TEXT NOT NULL,
+			ease_factor DOUBLE PRECISION NOT NULL,
+			repetitions INTEGER NOT NULL,
+			interval_days INTEGER NOT NULL,
+			due_at TIMESTAMP NOT NULL,
+			last_score INTEGER NOT NULL,
+			reviewed_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (user_id, category, text)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("error creating review_items table: %v", err)
+	}
+	return nil
+}
+
+func (r *postgresRepo) ListReviews(userID string) ([]interview.ReviewItem, error) {
+	rows, err := r.db.Query(
+		`SELECT user_id, category, text, ease_factor, repetitions, interval_days, due_at, last_score, reviewed_at
+		FROM review_items WHERE user_id = $1 ORDER BY category, text`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []interview.ReviewItem{}
+	for rows.Next() {
+		var item interview.ReviewItem
+		if err := rows.Scan(&item.UserID, &item.Category, &item.Text, &item.EaseFactor, &item.Repetitions,
+			&item.Interval, &item.DueAt, &item.LastScore, &item.ReviewedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (r *postgresRepo) SaveReview(item interview.ReviewItem) error {
+	_, err := r.db.Exec(
+		`INSERT INTO review_items (user_id, category, text, ease_factor, repetitions, interval_days, due_at, last_score, reviewed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (user_id, category, text) DO UPDATE SET
+			ease_factor = EXCLUDED.ease_factor, repetitions = EXCLUDED.repetitions,
+			interval_days = EXCLUDED.interval_days, due_at = EXCLUDED.due_at,
+			last_score = EXCLUDED.last_score, reviewed_at = EXCLUDED.reviewed_at`,
+		item.UserID, item.Category, item.Text, item.EaseFactor, item.Repetitions,
+		item.Interval, item.DueAt.UTC(), item.LastScore, item.ReviewedAt.UTC(),
+	)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"altoai_mvp/internal/models"
+)
+
+// ErrRefreshTokenSpent is returned when rotating a refresh token that was already rotated or revoked
+var ErrRefreshTokenSpent = errors.New("refresh token already used")
+
+func (r *userMemoryRepo) CreateRefreshToken(t models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.refreshTokens[t.TokenHash] = t
+	return nil
+}
+
+func (r *userMemoryRepo) GetRefreshToken(tokenHash string) (models.RefreshToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.refreshTokens[tokenHash]
+	if !ok {
+		return models.RefreshToken{}, ErrNotFound
+	}
+	return t, nil
+}
+
+func (r *userMemoryRepo) RotateRefreshToken(oldHash string, next models.RefreshToken, usedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	old, ok := r.refreshTokens[oldHash]
+	if !ok {
+		return ErrNotFound
+	}
+	if old.ReplacedBy != "" || old.RevokedAt != nil {
+		return ErrRefreshTokenSpent
+	}
+	old.ReplacedBy = next.ID
+	old.LastUsedAt = &usedAt
+	r.refreshTokens[oldHash] = old
+	r.refreshTokens[next.TokenHash] = next
+	return nil
+}
+
+func (r *userMemoryRepo) RevokeRefreshTokenFamily(familyID string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for hash, t := range r.refreshTokens {
+		if t.FamilyID == familyID && t.RevokedAt == nil {
+			t.RevokedAt = &at
+			r.refreshTokens[hash] = t
+		}
+	}
+	return nil
+}
+
+func (r *userMemoryRepo) ListRefreshTokens(userID string) ([]models.RefreshToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []models.RefreshToken
+	for _, t := range r.refreshTokens {
+		if t.UserID == userID {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+func (r *userMemoryRepo) RevokeAllRefreshTokens(userID string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for hash, t := range r.refreshTokens {
+		if t.UserID == userID && t.RevokedAt == nil {
+			t.RevokedAt = &at
+			r.refreshTokens[hash] = t
+		}
+	}
+	return nil
+}
+
+func (r *userMemoryRepo) IncrementTokenVersion(id string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.store[id]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	u.TokenVersion++
+	u.UpdatedAt = time.Now().UTC()
+	r.store[id] = u
+	return u.TokenVersion, nil
+}
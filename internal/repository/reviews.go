@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"sort"
+
+	"altoai_mvp/interview"
+)
+
+func (r *userMemoryRepo) ListReviews(userID string) ([]interview.ReviewItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	items := make([]interview.ReviewItem, 0, len(r.reviews[userID]))
+	for _, item := range r.reviews[userID] {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return interview.QuestionKey(items[i].Category, items[i].Text) < interview.QuestionKey(items[j].Category, items[j].Text)
+	})
+	return items, nil
+}
+
+func (r *userMemoryRepo) SaveReview(item interview.ReviewItem) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.store[item.UserID]; !ok {
+		return ErrNotFound
+	}
+	items, ok := r.reviews[item.UserID]
+	if !ok {
+		items = map[string]interview.ReviewItem{}
+		r.reviews[item.UserID] = items
+	}
+	items[interview.QuestionKey(item.Category, item.Text)] = item
+	return nil
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"altoai_mvp/interview"
+)
+
+// postgresSessionArchive stores closed interview sessions as JSON documents
+type postgresSessionArchive struct {
+	db *sql.DB
+}
+
+// NewPostgresSessionArchive returns an interview.SessionArchive backed by the interview_sessions table
+func NewPostgresSessionArchive(db *sql.DB) (interview.SessionArchive, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS interview_sessions (
+			id VARCHAR(36) PRIMARY KEY,
+			user_id VARCHAR(36),
+			status VARCHAR(16) NOT NULL,
+			data JSONB NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("error creating interview_sessions table: %v", err)
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS interview_sessions_user_id_idx ON interview_sessions (user_id, updated_at)`)
+	if err != nil {
+		return nil, fmt.Errorf("error creating interview_sessions index: %v", err)
+	}
+
+	return &postgresSessionArchive{db: db}, nil
+}
+
+func (a *postgresSessionArchive) ArchiveSession(s *interview.Session) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.db.Exec(`
+		INSERT INTO interview_sessions (id, user_id, status, data, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (id) DO UPDATE SET status = EXCLUDED.status, data = EXCLUDED.data, updated_at = EXCLUDED.updated_at`,
+		s.ID, s.UserID, string(s.Status), data, s.CreatedAt.UTC(), s.UpdatedAt.UTC(),
+	)
+	return err
+}
+
+func (a *postgresSessionArchive) GetArchivedSession(id string) (*interview.Session, error) {
+	var data []byte
+	err := a.db.QueryRow("SELECT data FROM interview_sessions WHERE id = $1", id).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var s interview.Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (a *postgresSessionArchive) ListArchivedSessions(userID string) ([]*interview.Session, error) {
+	rows, err := a.db.Query("SELECT data FROM interview_sessions WHERE user_id = $1 ORDER BY updated_at", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*interview.Session
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var s interview.Session
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, err
+		}
+		out = append(out, &s)
+	}
+	return out, rows.Err()
+}
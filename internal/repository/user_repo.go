@@ -6,12 +6,21 @@ import (
 	"time"
 
 	"altoai_mvp/internal/models"
+	"altoai_mvp/interview"
 
 	"github.com/google/uuid"
 )
 
 var ErrNotFound = errors.New("not found")
 
+// Errors for a wrong or stale emailed code
+var (
+	ErrInvalidVerificationCode = errors.New("invalid verification code")
+	ErrVerificationCodeExpired = errors.New("verification code expired")
+	ErrInvalidResetCode        = errors.New("invalid reset code")
+	ErrResetCodeExpired        = errors.New("reset code expired")
+)
+
 type UserRepo interface {
 	List() ([]models.User, error)
 	Get(id string) (models.User, error)
@@ -20,21 +29,131 @@ type UserRepo interface {
 	Update(id string, email, name *string) (models.User, error)
 	UpdateCollegeMajor(id string, college, major *string) (models.User, error)
 	Delete(id string) error
-	SetVerificationCode(email, code string, expiresAt time.Time) error
+	// One-time codes, one per user and purpose, stored as a keyed hash. Setting a code
+	// replaces the previous one. Checking compares in constant time and counts wrong guesses;
+	// a code is deleted once it matches, expires or reaches MaxCodeAttempts wrong guesses.
+	SetOneTimeCode(userID string, purpose models.CodePurpose, code string, expiresAt time.Time) error
+	ConsumeOneTimeCode(userID string, purpose models.CodePurpose, code string, at time.Time) error
+	DeleteOneTimeCode(userID string, purpose models.CodePurpose) error
+	// VerifyEmail and ResetPassword consume the user's code and apply the change in one step
 	VerifyEmail(email, code string) error
 	MarkEmailVerified(email string) error
-	SetResetCode(email, code string, expiresAt time.Time) error
 	ResetPassword(email, code, newPasswordHash string) error
+	UpdatePassword(id, passwordHash string) error
+	// An email change waits, one per user, until the new address confirms a change_email code.
+	// ConfirmEmailChange consumes the code and moves the user to the new, verified address in
+	// one step; it returns ErrEmailTaken, keeping the code, if another account has it by now.
+	SetPendingEmail(userID, email string, at time.Time) error
+	ConfirmEmailChange(userID, code string, at time.Time) (models.User, error)
+	// Refresh tokens, looked up by the SHA-256 hash of the token
+	CreateRefreshToken(t models.RefreshToken) error
+	GetRefreshToken(tokenHash string) (models.RefreshToken, error)
+	// RotateRefreshToken marks the old token as replaced by next and stores next in one step.
+	// It returns ErrRefreshTokenSpent if the old token was already rotated or revoked.
+	RotateRefreshToken(oldHash string, next models.RefreshToken, usedAt time.Time) error
+	RevokeRefreshTokenFamily(familyID string, at time.Time) error
+	ListRefreshTokens(userID string) ([]models.RefreshToken, error)
+	RevokeAllRefreshTokens(userID string, at time.Time) error
+	// IncrementTokenVersion invalidates every access token issued to the user so far
+	IncrementTokenVersion(id string) (int, error)
+	// External login identities, looked up by provider and the provider's subject ID
+	GetIdentity(provider, subject string) (models.UserIdentity, error)
+	CreateIdentity(i models.UserIdentity) error
+	ListIdentities(userID string) ([]models.UserIdentity, error)
+	SetRole(id string, role models.Role) (models.User, error)
+	// Counsellor-student assignments
+	AssignStudent(counsellorID, studentID string, at time.Time) error
+	UnassignStudent(counsellorID, studentID string) error
+	ListAssignedStudents(counsellorID string) ([]models.User, error)
+	IsStudentAssigned(counsellorID, studentID string) (bool, error)
+	// Cohorts, their members and email invitations
+	CreateCohort(c models.Cohort) error
+	GetCohort(id string) (models.Cohort, error)
+	UpdateCohort(c models.Cohort) error
+	DeleteCohort(id string) error
+	ListCohorts() ([]models.Cohort, error)
+	ListUserCohorts(userID string) ([]models.Cohort, error)
+	AddCohortMember(m models.CohortMember) error
+	RemoveCohortMember(cohortID, userID string) error
+	GetCohortMember(cohortID, userID string) (models.CohortMember, error)
+	ListCohortMembers(cohortID string) ([]models.CohortMember, error)
+	CreateCohortInvite(i models.CohortInvite) error
+	GetCohortInvite(codeHash string) (models.CohortInvite, error)
+	// AcceptCohortInvite marks the invite accepted and adds m to the cohort in one step.
+	// It returns ErrInviteSpent if the invite was already accepted.
+	AcceptCohortInvite(inviteID string, m models.CohortMember, at time.Time) error
+	ListCohortInvites(cohortID string) ([]models.CohortInvite, error)
+	// Failed-attempt counters for brute-force protection. RecordFailedAttempt starts a new
+	// window when the current one began before windowStart; LockAttempts resets the failures
+	// and counts a lockout.
+	GetAttemptCounter(key string) (models.AttemptCounter, error)
+	RecordFailedAttempt(key string, at, windowStart time.Time) (models.AttemptCounter, error)
+	LockAttempts(key string, at, until time.Time) (models.AttemptCounter, error)
+	ClearAttempts(key string) error
+	// TOTP enrolment and recovery codes. DeleteMFA also deletes the recovery codes.
+	// MarkTOTPStepUsed records the last accepted time step and reports false if step isn't
+	// newer, so a code can't be replayed. UseRecoveryCode returns ErrNotFound unless an
+	// unused code matches.
+	GetMFA(userID string) (models.UserMFA, error)
+	SaveMFA(m models.UserMFA) error
+	DeleteMFA(userID string) error
+	MarkTOTPStepUsed(userID string, step int64) (bool, error)
+	ReplaceRecoveryCodes(userID string, codeHashes []string, at time.Time) error
+	UseRecoveryCode(userID, codeHash string, at time.Time) error
+	CountRecoveryCodes(userID string) (int, error)
+	// Roles whose members have to use MFA
+	ListMFARequiredRoles() ([]models.Role, error)
+	SetMFARequiredRoles(roles []models.Role) error
+	// Spaced-repetition review schedules, one per user and question
+	ListReviews(userID string) ([]interview.ReviewItem, error)
+	SaveReview(item interview.ReviewItem) error
+	// Audit log, newest first
+	CreateAuditEvent(e models.AuditEvent) error
+	ListAuditEvents(limit int) ([]models.AuditEvent, error)
 	Close() error
 }
 
 type userMemoryRepo struct {
-	mu    sync.RWMutex
-	store map[string]models.User
+	mu            sync.RWMutex
+	store         map[string]models.User
+	refreshTokens map[string]models.RefreshToken  // keyed by token hash
+	identities    map[string]models.UserIdentity  // keyed by provider|subject
+	assignments   map[string]map[string]time.Time // counsellor ID -> student ID -> assigned at
+	cohorts       map[string]models.Cohort
+	cohortMembers map[string]map[string]models.CohortMember // cohort ID -> user ID -> member
+	cohortInvites map[string]models.CohortInvite            // keyed by invite ID
+	attempts      map[string]models.AttemptCounter
+	auditEvents   []models.AuditEvent
+	codes         map[string]models.OneTimeCode // keyed by user ID|purpose
+	hasher        codeHasher
+	mfa           map[string]models.UserMFA
+	recoveryCodes map[string][]models.RecoveryCode // keyed by user ID
+	mfaRoles      []models.Role
+	pendingEmails map[string]string                          // user ID -> new email
+	reviews       map[string]map[string]interview.ReviewItem // user ID -> question key -> item
 }
 
 func NewUserMemoryRepo() UserRepo {
-	return &userMemoryRepo{store: map[string]models.User{}}
+	hasher := codeHasherFromEnv()
+	if hasher == nil {
+		hasher = randomCodeHasher()
+	}
+	return &userMemoryRepo{
+		store:         map[string]models.User{},
+		refreshTokens: map[string]models.RefreshToken{},
+		identities:    map[string]models.UserIdentity{},
+		assignments:   map[string]map[string]time.Time{},
+		cohorts:       map[string]models.Cohort{},
+		cohortMembers: map[string]map[string]models.CohortMember{},
+		cohortInvites: map[string]models.CohortInvite{},
+		attempts:      map[string]models.AttemptCounter{},
+		codes:         map[string]models.OneTimeCode{},
+		hasher:        hasher,
+		mfa:           map[string]models.UserMFA{},
+		recoveryCodes: map[string][]models.RecoveryCode{},
+		pendingEmails: map[string]string{},
+		reviews:       map[string]map[string]interview.ReviewItem{},
+	}
 }
 
 func (r *userMemoryRepo) List() ([]models.User, error) {
@@ -78,6 +197,7 @@ func (r *userMemoryRepo) Create(email, name, passwordHash string) (models.User,
 		Name:          name,
 		Password:      passwordHash,
 		EmailVerified: false,
+		Role:          models.RoleStudent,
 		CreatedAt:     now,
 		UpdatedAt:     now,
 	}
@@ -128,22 +248,28 @@ func (r *userMemoryRepo) Delete(id string) error {
 		return ErrNotFound
 	}
 	delete(r.store, id)
-	return nil
-}
-
-func (r *userMemoryRepo) SetVerificationCode(email, code string, expiresAt time.Time) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	for id, u := range r.store {
-		if u.Email == email {
-			u.VerificationCode = code
-			u.VerificationCodeExpires = expiresAt
-			u.UpdatedAt = time.Now().UTC()
-			r.store[id] = u
-			return nil
+	for k, i := range r.identities {
+		if i.UserID == id {
+			delete(r.identities, k)
 		}
 	}
-	return ErrNotFound
+	delete(r.assignments, id)
+	for _, students := range r.assignments {
+		delete(students, id)
+	}
+	for _, members := range r.cohortMembers {
+		delete(members, id)
+	}
+	for key, c := range r.codes {
+		if c.UserID == id {
+			delete(r.codes, key)
+		}
+	}
+	delete(r.mfa, id)
+	delete(r.recoveryCodes, id)
+	delete(r.pendingEmails, id)
+	delete(r.reviews, id)
+	return nil
 }
 
 func (r *userMemoryRepo) VerifyEmail(email, code string) error {
@@ -151,15 +277,11 @@ func (r *userMemoryRepo) VerifyEmail(email, code string) error {
 	defer r.mu.Unlock()
 	for id, u := range r.store {
 		if u.Email == email {
-			if u.VerificationCode != code {
-				return errors.New("invalid verification code")
-			}
-			if time.Now().After(u.VerificationCodeExpires) {
-				return errors.New("verification code expired")
+			err := r.consumeCodeLocked(id, models.CodePurposeVerifyEmail, code, time.Now().UTC())
+			if err != nil {
+				return codeError(err, ErrInvalidVerificationCode, ErrVerificationCodeExpired)
 			}
 			u.EmailVerified = true
-			u.VerificationCode = ""
-			u.VerificationCodeExpires = time.Time{}
 			u.UpdatedAt = time.Now().UTC()
 			r.store[id] = u
 			return nil
@@ -182,13 +304,16 @@ func (r *userMemoryRepo) MarkEmailVerified(email string) error {
 	return ErrNotFound
 }
 
-func (r *userMemoryRepo) SetResetCode(email, code string, expiresAt time.Time) error {
+func (r *userMemoryRepo) ResetPassword(email, code, newPasswordHash string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	for id, u := range r.store {
 		if u.Email == email {
-			u.ResetCode = code
-			u.ResetCodeExpires = expiresAt
+			err := r.consumeCodeLocked(id, models.CodePurposeResetPassword, code, time.Now().UTC())
+			if err != nil {
+				return codeError(err, ErrInvalidResetCode, ErrResetCodeExpired)
+			}
+			u.Password = newPasswordHash
 			u.UpdatedAt = time.Now().UTC()
 			r.store[id] = u
 			return nil
@@ -197,26 +322,17 @@ func (r *userMemoryRepo) SetResetCode(email, code string, expiresAt time.Time) e
 	return ErrNotFound
 }
 
-func (r *userMemoryRepo) ResetPassword(email, code, newPasswordHash string) error {
+func (r *userMemoryRepo) UpdatePassword(id, passwordHash string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	for id, u := range r.store {
-		if u.Email == email {
-			if u.ResetCode != code {
-				return errors.New("invalid reset code")
-			}
-			if time.Now().After(u.ResetCodeExpires) {
-				return errors.New("reset code expired")
-			}
-			u.Password = newPasswordHash
-			u.ResetCode = ""
-			u.ResetCodeExpires = time.Time{}
-			u.UpdatedAt = time.Now().UTC()
-			r.store[id] = u
-			return nil
-		}
+	u, ok := r.store[id]
+	if !ok {
+		return ErrNotFound
 	}
-	return ErrNotFound
+	u.Password = passwordHash
+	u.UpdatedAt = time.Now().UTC()
+	r.store[id] = u
+	return nil
 }
 
 func (r *userMemoryRepo) Close() error {
@@ -1,13 +1,22 @@
 package router
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"altoai_mvp/internal/auth"
 	"altoai_mvp/internal/handlers"
 	"altoai_mvp/internal/middleware"
+	"altoai_mvp/internal/models"
 	"altoai_mvp/internal/repository"
 	"altoai_mvp/internal/services"
+	"altoai_mvp/internal/token"
+	"altoai_mvp/interview"
+	"log"
 	"net/http"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -15,41 +24,102 @@ import (
 func New() (*gin.Engine, error) {
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
+	// Client IPs feed rate limits and lockouts, so X-Forwarded-For is only believed from
+	// the proxies listed in TRUSTED_PROXIES
+	if err := r.SetTrustedProxies(trustedProxies()); err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %v", err)
+	}
 	r.Use(gin.Recovery(), middleware.RequestLogger())
 
 	// wiring (DI) - Use PostgreSQL repository
-	userRepo, err := repository.NewPostgresRepo()
+	db, err := repository.OpenPostgres()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize PostgreSQL: %v", err)
+	}
+	userRepo, err := repository.NewPostgresRepo(db)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize PostgreSQL: %v", err)
 	}
 
+	// Closed interview sessions are archived to Postgres by the session janitor
+	sessionArchive, err := repository.NewPostgresSessionArchive(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize session archive: %v", err)
+	}
+	interview.SetSessionArchive(sessionArchive)
+	// Spaced-repetition schedules live with the rest of the user's data
+	interview.SetReviewStore(userRepo)
+
+	// Every login path and JWTAuth sign and verify tokens through one service
+	tokens, err := token.FromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure tokens: %v", err)
+	}
+	authRequired := middleware.JWTAuth(tokens)
+
 	userSvc := services.NewUserService(userRepo)
-	authSvc := services.NewAuthService(userRepo)
+	passwords, err := services.PasswordPolicyFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure password policy: %v", err)
+	}
+	authSvc := services.NewAuthServiceWithPolicy(userRepo, tokens, services.NewEmailService(), passwords)
 	userH := handlers.NewUserHandler(userSvc)
 	authH := handlers.NewAuthHandler(authSvc)
-	chatH := handlers.NewChatHandler(userSvc)
+	cohortSvc := services.NewCohortService(userRepo, services.NewEmailService())
+	chatH := handlers.NewChatHandler(userSvc, cohortSvc)
+	practiceH := handlers.NewPracticeHandler(userSvc)
+	studentH := handlers.NewStudentHandler(userSvc, services.NewCounsellorService(userRepo))
+	cohortH := handlers.NewCohortHandler(userSvc, cohortSvc)
+	adminOnly := middleware.RequirePermission(middleware.PermManageUsers)
 
-	// Initialize Google auth with the user repository
-	auth.SetUserRepo(userRepo)
+	// Accounts listed in ADMIN_EMAILS are promoted to admin at startup
+	promoteAdmins(userRepo, userSvc)
+
+	// Initialize provider logins (Google and any OIDC_PROVIDERS) with the auth service
+	auth.SetAuthService(authSvc)
+	if err := auth.LoadProvidersFromEnv(); err != nil {
+		return nil, fmt.Errorf("failed to configure login providers: %v", err)
+	}
+
+	limits, err := rateLimiters(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure rate limits: %v", err)
+	}
+
+	// Access tokens die when their user logs out everywhere
+	middleware.SetTokenVersionSource(func(ctx context.Context, userID string) (int, error) {
+		u, err := userRepo.Get(userID)
+		return u.TokenVersion, err
+	})
 
 	// health
 	r.GET("/health", func(c *gin.Context) { c.JSON(200, gin.H{"ok": true}) })
+	// Public keys other services verify our tokens with; they can't mint tokens from these
+	r.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, tokens.JWKS())
+	})
+	// Session counts and janitor stats are for admins only
+	r.GET("/metrics/sessions", authRequired, adminOnly, func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"live": interview.SessionCount(), "janitor": interview.JanitorMetrics()})
+	})
 
-	// AUTH - Google
-	r.GET("/auth/google", auth.HandleGoogleLogin)
-	r.GET("/auth/google/callback", auth.HandleGoogleCallback)
+	// AUTH - OpenID Connect providers (google, microsoft, ...)
+	r.GET("/auth/:provider", auth.HandleLogin)
+	r.GET("/auth/:provider/callback", auth.HandleCallback)
 	
 	// User info endpoint (requires auth)
-	r.GET("/me", middleware.JWTAuth(), func(c *gin.Context) {
+	r.GET("/me", authRequired, func(c *gin.Context) {
 		claims := c.MustGet("user").(*middleware.MyClaims)
 		// Get full user data from database
-		dbUser, err := userSvc.GetByEmail(c.Request.Context(), claims.Email)
+		dbUser, err := userSvc.Get(c.Request.Context(), claims.Subject)
 		if err != nil {
 			// Fallback to claims if user not found in DB
 			c.JSON(http.StatusOK, gin.H{
 				"email": claims.Email,
 				"name": claims.Name,
 				"picture": claims.Picture,
+				"role": middleware.ClaimsRole(claims),
 				"college": "",
 				"major": "",
 			})
@@ -59,6 +129,7 @@ func New() (*gin.Engine, error) {
 			"email": dbUser.Email,
 			"name": dbUser.Name,
 			"picture": claims.Picture,
+			"role": dbUser.Role,
 			"college": dbUser.College,
 			"major": dbUser.Major,
 		})
@@ -68,26 +139,143 @@ func New() (*gin.Engine, error) {
 	v1 := r.Group("/api/v1")
 	{
 		// Auth routes
-		v1.POST("/auth/login", authH.Login)
-		v1.POST("/auth/register", authH.Register)
-		v1.POST("/auth/verify-email", authH.VerifyEmail)
-		v1.POST("/auth/refresh", authH.Refresh) // No auth middleware needed
+		v1.POST("/auth/login", limits["auth"], authH.Login)
+		v1.POST("/auth/register", limits["email"], authH.Register)
+		v1.POST("/auth/verify-email", limits["auth"], authH.VerifyEmail)
+		v1.POST("/auth/refresh", limits["auth"], authH.Refresh) // No auth middleware needed
+		v1.POST("/auth/exchange", limits["auth"], auth.HandleCodeExchange) // one-time code from a provider callback
+		v1.GET("/auth/providers", auth.HandleListProviders)
 		v1.POST("/auth/logout", authH.Logout)
-		v1.POST("/auth/forgot-password", authH.ForgotPassword)
-		v1.POST("/auth/reset-password", authH.ResetPassword)
-		v1.POST("/auth/resend-verification", authH.ResendVerificationCode)
+		v1.POST("/auth/logout-all", authRequired, authH.LogoutAll)
+		v1.POST("/auth/change-password", authRequired, limits["auth"], authH.ChangePassword)
+		v1.POST("/auth/change-email", authRequired, limits["email"], authH.RequestEmailChange)
+		v1.POST("/auth/change-email/confirm", authRequired, limits["auth"], authH.ConfirmEmailChange)
+		v1.GET("/auth/sessions", authRequired, authH.Sessions)
+		v1.DELETE("/auth/sessions/:id", authRequired, authH.RevokeSession)
+		v1.POST("/auth/forgot-password", limits["email"], authH.ForgotPassword)
+		v1.POST("/auth/reset-password", limits["auth"], authH.ResetPassword)
+		v1.POST("/auth/resend-verification", limits["email"], authH.ResendVerificationCode)
+		v1.POST("/auth/magic-link", limits["email"], authH.RequestMagicLink)
+		v1.POST("/auth/magic-link/verify", limits["auth"], authH.MagicLinkLogin)
+		v1.POST("/auth/mfa/verify", limits["auth"], authH.VerifyMFA)
+		v1.POST("/auth/mfa/setup", limits["auth"], authH.SetupMFA)
+		v1.POST("/auth/mfa/setup/confirm", limits["auth"], authH.ConfirmMFASetup)
+		v1.GET("/auth/mfa", authRequired, authH.MFAStatus)
+		v1.POST("/auth/mfa/totp", authRequired, authH.EnrollTOTP)
+		v1.POST("/auth/mfa/totp/confirm", authRequired, authH.ConfirmTOTP)
+		v1.POST("/auth/mfa/disable", authRequired, authH.DisableMFA)
+		v1.POST("/auth/mfa/recovery-codes", authRequired, authH.RegenerateRecoveryCodes)
 		
-		// User routes
-		v1.GET("/users", userH.List)
-		v1.POST("/users", userH.Create)
-		v1.GET("/users/:id", userH.Get)
-		v1.PUT("/users/:id", middleware.JWTAuth(), userH.Update)
-		v1.DELETE("/users/:id", userH.Delete)
-		v1.PUT("/users/me/profile", middleware.JWTAuth(), userH.UpdateProfile)
+		// User management (admins only); everyone edits their own account via /users/me
+		v1.GET("/users", authRequired, adminOnly, userH.List)
+		v1.POST("/users", authRequired, adminOnly, userH.Create)
+		v1.GET("/users/:id", authRequired, adminOnly, userH.Get)
+		v1.PUT("/users/:id", authRequired, adminOnly, userH.Update)
+		v1.PUT("/users/:id/role", authRequired, adminOnly, userH.SetRole)
+		v1.DELETE("/users/:id", authRequired, adminOnly, userH.Delete)
+		v1.PUT("/users/me/profile", authRequired, userH.UpdateProfile)
+		v1.GET("/audit-events", authRequired, adminOnly, userH.AuditEvents)
+		v1.GET("/mfa-policy", authRequired, adminOnly, userH.MFAPolicy)
+		v1.PUT("/mfa-policy", authRequired, adminOnly, userH.SetMFAPolicy)
+
+		// Counsellors read their assigned students' results; admins manage assignments
+		v1.GET("/students", authRequired, middleware.RequirePermission(middleware.PermViewStudents), studentH.List)
+		v1.GET("/students/:id/interviews", authRequired, middleware.RequirePermission(middleware.PermViewStudents), studentH.Interviews)
+		v1.PUT("/counsellors/:id/students/:studentId", authRequired, middleware.RequirePermission(middleware.PermManageAssignment), studentH.Assign)
+		v1.DELETE("/counsellors/:id/students/:studentId", authRequired, middleware.RequirePermission(middleware.PermManageAssignment), studentH.Unassign)
+
+		// Cohorts: counsellors run the cohorts they belong to, students join by emailed invite
+		v1.GET("/cohorts", authRequired, cohortH.List)
+		v1.POST("/cohorts", authRequired, middleware.RequirePermission(middleware.PermManageCohorts), cohortH.Create)
+		v1.POST("/cohorts/join", authRequired, cohortH.Join)
+		v1.GET("/cohorts/:id", authRequired, cohortH.Get)
+		v1.DELETE("/cohorts/:id", authRequired, cohortH.Delete)
+		v1.GET("/cohorts/:id/members", authRequired, cohortH.Members)
+		v1.DELETE("/cohorts/:id/members/:userId", authRequired, cohortH.RemoveMember)
+		v1.GET("/cohorts/:id/invites", authRequired, cohortH.Invites)
+		v1.POST("/cohorts/:id/invites", authRequired, limits["invites"], cohortH.Invite)
+		v1.PUT("/cohorts/:id/assignment", authRequired, cohortH.SetAssignment)
+		v1.GET("/cohorts/:id/dashboard", authRequired, cohortH.Dashboard)
 		
 		// Chat route (requires auth)
-		v1.POST("/chat", middleware.JWTAuth(), chatH.Chat)
+		v1.POST("/chat", authRequired, limits["chat"], chatH.Chat)
+		v1.POST("/interviews/resume", authRequired, chatH.Resume)
+		v1.POST("/interviews/:id/pause", authRequired, chatH.Pause)
+
+		// Spaced-repetition practice (requires auth)
+		v1.GET("/practice/due", authRequired, practiceH.Due)
 	}
 
 	return r, nil
 }
+
+// rateLimiters returns a token-bucket limiter per route group. RATE_LIMIT_<GROUP> (e.g.
+// RATE_LIMIT_CHAT=20/1m) overrides a default; RATE_LIMIT_BACKEND=postgres shares the buckets
+// between replicas instead of counting in each process.
+func rateLimiters(db *sql.DB) (map[string]gin.HandlerFunc, error) {
+	var store middleware.RateLimitStore
+	switch backend := os.Getenv("RATE_LIMIT_BACKEND"); backend {
+	case "", "memory":
+		store = middleware.NewMemoryRateLimitStore()
+	case "postgres":
+		pgStore, err := repository.NewPostgresRateLimitStore(db)
+		if err != nil {
+			return nil, err
+		}
+		store = pgStore
+	default:
+		return nil, fmt.Errorf("RATE_LIMIT_BACKEND must be memory or postgres, not %q", backend)
+	}
+
+	groups := []struct {
+		name  string
+		limit middleware.RateLimit
+		key   middleware.RateLimitKey
+	}{
+		{"auth", middleware.RateLimit{Burst: 20, Period: time.Minute}, middleware.ByIP},      // logins and codes
+		{"email", middleware.RateLimit{Burst: 5, Period: 15 * time.Minute}, middleware.ByIP}, // routes that send email
+		{"invites", middleware.RateLimit{Burst: 30, Period: time.Hour}, middleware.ByUser},   // cohort invite emails
+		{"chat", middleware.RateLimit{Burst: 20, Period: time.Minute}, middleware.ByUser},    // LLM-backed
+	}
+	limiters := make(map[string]gin.HandlerFunc, len(groups))
+	for _, g := range groups {
+		limit, err := middleware.RateLimitFromEnv(g.name, g.limit)
+		if err != nil {
+			return nil, err
+		}
+		limiters[g.name] = middleware.RateLimiter(store, g.name, limit, g.key)
+	}
+	return limiters, nil
+}
+
+// trustedProxies reads TRUSTED_PROXIES (comma separated IPs or CIDRs); unset trusts no proxy
+func trustedProxies() []string {
+	var proxies []string
+	for _, p := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	return proxies
+}
+
+// promoteAdmins gives the admin role to the existing accounts in ADMIN_EMAILS (comma separated)
+func promoteAdmins(repo repository.UserRepo, userSvc services.UserService) {
+	for _, email := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		email = strings.TrimSpace(email)
+		if email == "" {
+			continue
+		}
+		u, err := repo.GetByEmail(email)
+		if err != nil {
+			log.Printf("ADMIN_EMAILS: %s: %v", email, err)
+			continue
+		}
+		if u.Role == models.RoleAdmin {
+			continue
+		}
+		if _, err := userSvc.SetRole(context.Background(), u.ID, models.RoleAdmin); err != nil {
+			log.Printf("ADMIN_EMAILS: %s: %v", email, err)
+		}
+	}
+}
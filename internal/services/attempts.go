@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"altoai_mvp/internal/models"
+	"altoai_mvp/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// ErrTooManyAttempts is matched by every LockoutError
+var ErrTooManyAttempts = errors.New("too many failed attempts, please try again later")
+
+// LockoutError is returned while an account or IP address is locked out after repeated failures
+type LockoutError struct {
+	Until time.Time
+}
+
+func (e *LockoutError) Error() string {
+	return ErrTooManyAttempts.Error()
+}
+
+func (e *LockoutError) Is(target error) bool {
+	return target == ErrTooManyAttempts
+}
+
+// RetryAfter is how long the caller has to wait, rounded up to a whole second
+func (e *LockoutError) RetryAfter(now time.Time) time.Duration {
+	d := e.Until.Sub(now)
+	if d <= 0 {
+		return 0
+	}
+	return d.Truncate(time.Second) + time.Second
+}
+
+// attemptPolicy locks a key for BaseLockout after MaxFailures failures within Window.
+// Each further lockout doubles, up to MaxLockout.
+type attemptPolicy struct {
+	MaxFailures int
+	Window      time.Duration
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+}
+
+var (
+	// Per account: a handful of wrong passwords
+	loginAccountPolicy = attemptPolicy{MaxFailures: 5, Window: 15 * time.Minute, BaseLockout: time.Minute, MaxLockout: time.Hour}
+	// Per account and code: reaching it also invalidates the emailed code
+	codeAccountPolicy = attemptPolicy{MaxFailures: 5, Window: 15 * time.Minute, BaseLockout: 5 * time.Minute, MaxLockout: 24 * time.Hour}
+	// Per IP address: looser, since several people can share one address
+	ipPolicy = attemptPolicy{MaxFailures: 20, Window: 15 * time.Minute, BaseLockout: time.Minute, MaxLockout: time.Hour}
+)
+
+// lockoutMemory is how long a key's lockout count survives without further failures
+const lockoutMemory = 24 * time.Hour
+
+func (p attemptPolicy) lockout(previous int) time.Duration {
+	d := p.BaseLockout
+	for i := 0; i < previous && d < p.MaxLockout; i++ {
+		d *= 2
+	}
+	return min(d, p.MaxLockout)
+}
+
+// attemptKey is one counter an attempt is charged to
+type attemptKey struct {
+	key    string
+	policy attemptPolicy
+	event  string // audit event type recorded when the key locks
+}
+
+// attemptKeys returns the account and, when known, the IP counters for an action such as "login"
+func attemptKeys(ctx context.Context, action, email string, accountPolicy attemptPolicy) []attemptKey {
+	keys := []attemptKey{{
+		key:    action + ":account:" + strings.ToLower(strings.TrimSpace(email)),
+		policy: accountPolicy,
+		event:  action + ".account_locked",
+	}}
+	if ip := ClientInfoFrom(ctx).IP; ip != "" {
+		keys = append(keys, attemptKey{key: action + ":ip:" + ip, policy: ipPolicy, event: action + ".ip_locked"})
+	}
+	return keys
+}
+
+// checkAttempts returns a LockoutError if any of keys is locked
+func (s *authService) checkAttempts(keys []attemptKey, now time.Time) error {
+	var until time.Time
+	for _, k := range keys {
+		c, err := s.userRepo.GetAttemptCounter(k.key)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if c.LockedUntil != nil && c.LockedUntil.After(now) && c.LockedUntil.After(until) {
+			until = *c.LockedUntil
+		}
+	}
+	if !until.IsZero() {
+		return &LockoutError{Until: until}
+	}
+	return nil
+}
+
+// recordFailure charges a failed attempt to every key, locking those that reach their limit.
+// It returns the keys that locked and a LockoutError for the longest lockout.
+func (s *authService) recordFailure(ctx context.Context, keys []attemptKey, email string, now time.Time) ([]attemptKey, error) {
+	var locked []attemptKey
+	var until time.Time
+	for _, k := range keys {
+		previous, err := s.userRepo.GetAttemptCounter(k.key)
+		if err == nil && previous.Lockouts > 0 && now.Sub(previous.UpdatedAt) > lockoutMemory {
+			// Old lockouts are forgiven so a long-idle account starts over at the base lockout
+			if err := s.userRepo.ClearAttempts(k.key); err != nil {
+				return nil, err
+			}
+		}
+
+		c, err := s.userRepo.RecordFailedAttempt(k.key, now, now.Add(-k.policy.Window))
+		if err != nil {
+			return nil, err
+		}
+		if c.Failures < k.policy.MaxFailures {
+			continue
+		}
+
+		lockedUntil := now.Add(k.policy.lockout(c.Lockouts))
+		if _, err := s.userRepo.LockAttempts(k.key, now, lockedUntil); err != nil {
+			return nil, err
+		}
+		s.audit(ctx, models.AuditEvent{
+			Type:   k.event,
+			Email:  email,
+			Detail: fmt.Sprintf("%d failed attempts; locked until %s", c.Failures, lockedUntil.Format(time.RFC3339)),
+		})
+		locked = append(locked, k)
+		if lockedUntil.After(until) {
+			until = lockedUntil
+		}
+	}
+	if len(locked) > 0 {
+		return locked, &LockoutError{Until: until}
+	}
+	return nil, nil
+}
+
+// clearAttempts forgets the account's failures after a success; IP counters keep running
+func (s *authService) clearAttempts(keys []attemptKey) {
+	if err := s.userRepo.ClearAttempts(keys[0].key); err != nil {
+		log.Printf("Failed to clear attempt counter %s: %v", keys[0].key, err)
+	}
+}
+
+// audit records e in the audit log, filling in its ID, time and client IP
+func (s *authService) audit(ctx context.Context, e models.AuditEvent) {
+	e.ID = uuid.NewString()
+	e.CreatedAt = time.Now().UTC()
+	if e.IP == "" {
+		e.IP = ClientInfoFrom(ctx).IP
+	}
+	if e.UserID == "" && e.Email != "" {
+		if u, err := s.userRepo.GetByEmail(e.Email); err == nil {
+			e.UserID = u.ID
+		}
+	}
+	log.Printf("audit: %s email=%s ip=%s %s", e.Type, e.Email, e.IP, e.Detail)
+	if err := s.userRepo.CreateAuditEvent(e); err != nil {
+		log.Printf("Failed to record audit event %s: %v", e.Type, err)
+	}
+}
@@ -2,18 +2,26 @@ package services
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
-	"fmt"
-	"os"
+	"log"
+	"strings"
 	"time"
 
 	"altoai_mvp/internal/models"
 	"altoai_mvp/internal/repository"
+	"altoai_mvp/internal/token"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// ErrRefreshTokenReused is returned when a refresh token that was already rotated is
+// presented again. The token's whole family is revoked, signing out every copy of it.
+var ErrRefreshTokenReused = errors.New("refresh token reuse detected, please log in again")
+
 type AuthService interface {
 	Login(ctx context.Context, dto models.LoginDTO) (string, string, *models.User, error) // accessToken, refreshToken, user, error
 	Register(ctx context.Context, dto models.CreateUserDTO) error
@@ -21,18 +29,63 @@ type AuthService interface {
 	ResendVerificationCode(ctx context.Context, dto models.ResendVerificationDTO) error
 	ForgotPassword(ctx context.Context, dto models.ForgotPasswordDTO) error
 	ResetPassword(ctx context.Context, dto models.ResetPasswordDTO) error
-	RefreshToken(ctx context.Context, refreshTokenString string) (string, string, error) // newAccessToken, newRefreshToken, error
+	RefreshToken(ctx context.Context, refreshTokenString string) (string, string, error)       // newAccessToken, newRefreshToken, error
+	IssueTokens(ctx context.Context, user models.User, picture string) (string, string, error) // accessToken, refreshToken, error
+	LoginWithIdentity(ctx context.Context, id ExternalIdentity) (models.User, error)
+	// Provider logins return to the frontend with a single-use code that the frontend
+	// exchanges for tokens, so no token appears in the redirect URL
+	IssueLoginCode(ctx context.Context, user models.User, picture string) (string, error)
+	ExchangeLoginCode(ctx context.Context, code string) (string, string, *models.User, error) // accessToken, refreshToken, user, error
+	// Passwordless login through a single-use link sent by email
+	RequestMagicLink(ctx context.Context, dto models.MagicLinkDTO) error
+	LoginWithMagicLink(ctx context.Context, dto models.MagicLinkLoginDTO) (string, string, *models.User, error) // accessToken, refreshToken, user, error
+	// TOTP two-factor authentication. Logins return an MFAChallengeError instead of tokens
+	// when a second factor is needed; VerifyMFA exchanges the challenge for tokens.
+	MFAChallenge(ctx context.Context, user models.User) error
+	MFAChallengeUser(ctx context.Context, mfaToken string) (models.User, error)
+	VerifyMFA(ctx context.Context, dto models.MFAChallengeDTO) (string, string, *models.User, error) // accessToken, refreshToken, user, error
+	MFAStatus(ctx context.Context, userID string) (models.MFAStatus, error)
+	BeginTOTPEnrollment(ctx context.Context, userID string) (models.MFAEnrollment, error)
+	ConfirmTOTPEnrollment(ctx context.Context, userID, code string) ([]string, error) // recovery codes
+	DisableMFA(ctx context.Context, userID, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error)
+	Logout(ctx context.Context, refreshTokenString string) error
+	// Signed-in devices, one per refresh token family
+	ListSessions(ctx context.Context, userID, currentRefreshToken string) ([]models.DeviceSession, error)
+	RevokeSession(ctx context.Context, userID, sessionID string) error
+	LogoutAll(ctx context.Context, userID string) error
+	// ChangePassword sets a new password for a signed-in user, signs out their other devices
+	// and returns fresh tokens for this one. Accounts without a password can set one.
+	ChangePassword(ctx context.Context, userID string, dto models.ChangePasswordDTO) (string, string, *models.User, error) // accessToken, refreshToken, user, error
+	// Email changes: a code goes to the new address, and confirming it moves the account
+	// there, tells the old address and returns tokens carrying the new email
+	RequestEmailChange(ctx context.Context, userID string, dto models.ChangeEmailDTO) error
+	ConfirmEmailChange(ctx context.Context, userID string, dto models.ConfirmEmailChangeDTO) (string, string, *models.User, error) // accessToken, refreshToken, user, error
 }
 
 type authService struct {
-	userRepo repository.UserRepo
-	emailSvc EmailService
+	userRepo  repository.UserRepo
+	emailSvc  EmailService
+	tokens    *token.Service
+	passwords PasswordPolicy
+}
+
+func NewAuthService(userRepo repository.UserRepo, tokens *token.Service) AuthService {
+	return NewAuthServiceWithEmail(userRepo, tokens, NewEmailService())
+}
+
+// NewAuthServiceWithEmail is NewAuthService with a chosen EmailService
+func NewAuthServiceWithEmail(userRepo repository.UserRepo, tokens *token.Service, emailSvc EmailService) AuthService {
+	return NewAuthServiceWithPolicy(userRepo, tokens, emailSvc, DefaultPasswordPolicy)
 }
 
-func NewAuthService(userRepo repository.UserRepo) AuthService {
+// NewAuthServiceWithPolicy is NewAuthServiceWithEmail with a chosen PasswordPolicy
+func NewAuthServiceWithPolicy(userRepo repository.UserRepo, tokens *token.Service, emailSvc EmailService, passwords PasswordPolicy) AuthService {
 	return &authService{
-		userRepo: userRepo,
-		emailSvc: NewEmailService(),
+		userRepo:  userRepo,
+		emailSvc:  emailSvc,
+		tokens:    tokens,
+		passwords: passwords,
 	}
 }
 
@@ -48,85 +101,88 @@ func (s *authService) comparePassword(hashedPassword, password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
 
-func (s *authService) generateAccessToken(user models.User) (string, error) {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		return "", errors.New("JWT_SECRET not set")
-	}
+func (s *authService) generateAccessToken(user models.User, picture string) (string, error) {
+	signed, _, err := s.tokens.IssueAccess(token.Claims{
+		Email:            user.Email,
+		Name:             user.Name,
+		Picture:          picture,
+		Role:             string(user.Role),
+		Version:          user.TokenVersion,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: user.ID},
+	})
+	return signed, err
+}
 
-	// Get expiration from env or default to 30 minutes
-	expiryStr := os.Getenv("ACCESS_TOKEN_EXPIRY")
-	if expiryStr == "" {
-		expiryStr = "30m"
-	}
-	
-	var expiry time.Duration
-	if expiryStr[len(expiryStr)-1] == 'm' {
-		minutes := 30
-		if len(expiryStr) > 1 {
-			_, _ = fmt.Sscanf(expiryStr[:len(expiryStr)-1], "%d", &minutes)
-		}
-		expiry = time.Duration(minutes) * time.Minute
-	} else {
-		expiry = 30 * time.Minute // default
-	}
+// generateRefreshToken signs a refresh token identified by tokenID and returns its expiry
+func (s *authService) generateRefreshToken(user models.User, picture, tokenID string) (string, time.Time, error) {
+	return s.tokens.IssueRefresh(token.Claims{
+		Email:            user.Email,
+		Name:             user.Name,
+		Picture:          picture,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: user.ID, ID: tokenID},
+	})
+}
 
-	claims := jwt.MapClaims{
-		"email":   user.Email,
-		"name":    user.Name,
-		"picture": "",
-		"exp":     time.Now().Add(expiry).Unix(),
-		"iat":     time.Now().Unix(),
-		"iss":     "altoai_mvp",
-		"type":    "access",
-	}
+// newRefreshToken signs a refresh token in familyID and builds its record, labelled with
+// the client info on ctx. The caller persists the record.
+func (s *authService) newRefreshToken(ctx context.Context, user models.User, picture, familyID string) (string, models.RefreshToken, error) {
+	id := uuid.NewString()
+	token, expiresAt, err := s.generateRefreshToken(user, picture, id)
+	if err != nil {
+		return "", models.RefreshToken{}, err
+	}
+
+	client := ClientInfoFrom(ctx)
+	return token, models.RefreshToken{
+		ID:        id,
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(token),
+		Device:    DeviceLabel(client.UserAgent),
+		UserAgent: client.UserAgent,
+		IP:        client.IP,
+		IssuedAt:  time.Now().UTC(),
+		ExpiresAt: expiresAt.UTC(),
+	}, nil
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+// hashToken is how refresh tokens are stored and looked up
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
-func (s *authService) generateRefreshToken(user models.User) (string, error) {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		return "", errors.New("JWT_SECRET not set")
+// IssueTokens signs an access token for user and starts a new refresh token family,
+// as happens on every fresh login
+func (s *authService) IssueTokens(ctx context.Context, user models.User, picture string) (string, string, error) {
+	accessToken, err := s.generateAccessToken(user, picture)
+	if err != nil {
+		return "", "", err
 	}
 
-	// Get expiration from env or default to 30 days
-	expiryStr := os.Getenv("REFRESH_TOKEN_EXPIRY")
-	if expiryStr == "" {
-		expiryStr = "720h" // 30 days
-	}
-	
-	var expiry time.Duration
-	if expiryStr[len(expiryStr)-1] == 'h' {
-		hours := 720
-		if len(expiryStr) > 1 {
-			_, _ = fmt.Sscanf(expiryStr[:len(expiryStr)-1], "%d", &hours)
-		}
-		expiry = time.Duration(hours) * time.Hour
-	} else {
-		expiry = 30 * 24 * time.Hour // default 30 days
+	refreshToken, record, err := s.newRefreshToken(ctx, user, picture, uuid.NewString())
+	if err != nil {
+		return "", "", err
 	}
-
-	claims := jwt.MapClaims{
-		"email":   user.Email,
-		"name":    user.Name,
-		"picture": "",
-		"exp":     time.Now().Add(expiry).Unix(),
-		"iat":     time.Now().Unix(),
-		"iss":     "altoai_mvp",
-		"type":    "refresh",
+	if err := s.userRepo.CreateRefreshToken(record); err != nil {
+		return "", "", err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return accessToken, refreshToken, nil
 }
 
 func (s *authService) Login(ctx context.Context, dto models.LoginDTO) (string, string, *models.User, error) {
+	// Wrong passwords are counted per account and per IP address; either can lock out
+	now := time.Now().UTC()
+	keys := attemptKeys(ctx, "login", dto.Email, loginAccountPolicy)
+	if err := s.checkAttempts(keys, now); err != nil {
+		return "", "", nil, err
+	}
+
 	user, err := s.userRepo.GetByEmail(dto.Email)
 	if err != nil {
 		if err == repository.ErrNotFound {
-			return "", "", nil, errors.New("invalid email or password")
+			return "", "", nil, s.loginFailed(ctx, keys, dto.Email, now)
 		}
 		return "", "", nil, err
 	}
@@ -138,21 +194,20 @@ func (s *authService) Login(ctx context.Context, dto models.LoginDTO) (string, s
 
 	// Check if user has a password (OAuth users might not have one)
 	if user.Password == "" {
-		return "", "", nil, errors.New("invalid email or password")
+		return "", "", nil, s.loginFailed(ctx, keys, dto.Email, now)
 	}
 
 	// Verify password
 	if err := s.comparePassword(user.Password, dto.Password); err != nil {
-		return "", "", nil, errors.New("invalid email or password")
+		return "", "", nil, s.loginFailed(ctx, keys, dto.Email, now)
 	}
-
-	// Generate access and refresh tokens
-	accessToken, err := s.generateAccessToken(user)
-	if err != nil {
+	s.clearAttempts(keys)
+	if err := s.MFAChallenge(ctx, user); err != nil {
 		return "", "", nil, err
 	}
 
-	refreshToken, err := s.generateRefreshToken(user)
+	// Generate access and refresh tokens
+	accessToken, refreshToken, err := s.IssueTokens(ctx, user, "")
 	if err != nil {
 		return "", "", nil, err
 	}
@@ -160,11 +215,22 @@ func (s *authService) Login(ctx context.Context, dto models.LoginDTO) (string, s
 	return accessToken, refreshToken, &user, nil
 }
 
+// loginFailed records a failed login and returns the error to show for it
+func (s *authService) loginFailed(ctx context.Context, keys []attemptKey, email string, now time.Time) error {
+	if _, err := s.recordFailure(ctx, keys, email, now); err != nil {
+		return err
+	}
+	return errors.New("invalid email or password")
+}
+
 func (s *authService) Register(ctx context.Context, dto models.CreateUserDTO) error {
 	// Password is required for registration
 	if dto.Password == "" {
 		return errors.New("password is required")
 	}
+	if err := s.passwords.Check(dto.Password, dto.Email, dto.Name); err != nil {
+		return err
+	}
 
 	// Check if user already exists
 	_, err := s.userRepo.GetByEmail(dto.Email)
@@ -195,7 +261,7 @@ func (s *authService) Register(ctx context.Context, dto models.CreateUserDTO) er
 
 	// Set verification code (expires in 15 minutes) - use UTC
 	expiresAt := time.Now().UTC().Add(15 * time.Minute)
-	if err := s.userRepo.SetVerificationCode(user.Email, code, expiresAt); err != nil {
+	if err := s.userRepo.SetOneTimeCode(user.ID, models.CodePurposeVerifyEmail, code, expiresAt); err != nil {
 		return err
 	}
 
@@ -208,24 +274,29 @@ func (s *authService) Register(ctx context.Context, dto models.CreateUserDTO) er
 }
 
 func (s *authService) VerifyEmail(ctx context.Context, dto models.VerifyEmailDTO) (string, string, *models.User, error) {
+	now := time.Now().UTC()
+	keys := attemptKeys(ctx, "verify_email", dto.Email, codeAccountPolicy)
+	if err := s.checkAttempts(keys, now); err != nil {
+		return "", "", nil, err
+	}
+
 	// Verify the code
 	if err := s.userRepo.VerifyEmail(dto.Email, dto.Code); err != nil {
-		return "", "", nil, err
+		return "", "", nil, s.codeFailed(ctx, keys, dto.Email, models.CodePurposeVerifyEmail, now, err)
 	}
+	s.clearAttempts(keys)
 
 	// Get the verified user
 	user, err := s.userRepo.GetByEmail(dto.Email)
 	if err != nil {
 		return "", "", nil, err
 	}
-
-	// Generate access and refresh tokens
-	accessToken, err := s.generateAccessToken(user)
-	if err != nil {
+	if err := s.MFAChallenge(ctx, user); err != nil {
 		return "", "", nil, err
 	}
 
-	refreshToken, err := s.generateRefreshToken(user)
+	// Generate access and refresh tokens
+	accessToken, refreshToken, err := s.IssueTokens(ctx, user, "")
 	if err != nil {
 		return "", "", nil, err
 	}
@@ -257,7 +328,7 @@ func (s *authService) ResendVerificationCode(ctx context.Context, dto models.Res
 
 	// Set verification code (expires in 15 minutes) - use UTC
 	expiresAt := time.Now().UTC().Add(15 * time.Minute)
-	if err := s.userRepo.SetVerificationCode(user.Email, code, expiresAt); err != nil {
+	if err := s.userRepo.SetOneTimeCode(user.ID, models.CodePurposeVerifyEmail, code, expiresAt); err != nil {
 		return err
 	}
 
@@ -288,7 +359,7 @@ func (s *authService) ForgotPassword(ctx context.Context, dto models.ForgotPassw
 
 	// Set reset code (expires in 15 minutes) - use UTC
 	expiresAt := time.Now().UTC().Add(15 * time.Minute)
-	if err := s.userRepo.SetResetCode(user.Email, code, expiresAt); err != nil {
+	if err := s.userRepo.SetOneTimeCode(user.ID, models.CodePurposeResetPassword, code, expiresAt); err != nil {
 		return err
 	}
 
@@ -301,6 +372,18 @@ func (s *authService) ForgotPassword(ctx context.Context, dto models.ForgotPassw
 }
 
 func (s *authService) ResetPassword(ctx context.Context, dto models.ResetPasswordDTO) error {
+	now := time.Now().UTC()
+	keys := attemptKeys(ctx, "reset_password", dto.Email, codeAccountPolicy)
+	if err := s.checkAttempts(keys, now); err != nil {
+		return err
+	}
+
+	// The name is only known for real accounts; unknown emails fail on the code below
+	user, _ := s.userRepo.GetByEmail(dto.Email)
+	if err := s.passwords.Check(dto.Password, dto.Email, user.Name); err != nil {
+		return err
+	}
+
 	// Verify reset code and update password
 	passwordHash, err := s.hashPassword(dto.Password)
 	if err != nil {
@@ -308,63 +391,191 @@ func (s *authService) ResetPassword(ctx context.Context, dto models.ResetPasswor
 	}
 
 	if err := s.userRepo.ResetPassword(dto.Email, dto.Code, passwordHash); err != nil {
-		return err
+		return s.codeFailed(ctx, keys, dto.Email, models.CodePurposeResetPassword, now, err)
 	}
+	s.clearAttempts(keys)
 
 	return nil
 }
 
-func (s *authService) RefreshToken(ctx context.Context, refreshTokenString string) (string, string, error) {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		return "", "", errors.New("JWT_SECRET not set")
+var (
+	ErrWrongPassword = errors.New("current password is incorrect")
+	ErrSamePassword  = errors.New("new password must be different from the current one")
+)
+
+func (s *authService) ChangePassword(ctx context.Context, userID string, dto models.ChangePasswordDTO) (string, string, *models.User, error) {
+	user, err := s.userRepo.Get(userID)
+	if err != nil {
+		return "", "", nil, err
 	}
 
-	// Parse and validate refresh token (ignore access token completely)
-	token, err := jwt.Parse(refreshTokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
+	if err := s.checkCurrentPassword(ctx, "change_password", user, dto.CurrentPassword); err != nil {
+		return "", "", nil, err
+	}
+	if user.Password != "" && dto.NewPassword == dto.CurrentPassword {
+		return "", "", nil, ErrSamePassword
+	}
+	if err := s.passwords.Check(dto.NewPassword, user.Email, user.Name); err != nil {
+		return "", "", nil, err
+	}
+
+	passwordHash, err := s.hashPassword(dto.NewPassword)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if err := s.userRepo.UpdatePassword(userID, passwordHash); err != nil {
+		return "", "", nil, err
+	}
+	event := "password.changed"
+	if user.Password == "" {
+		event = "password.set"
+	}
+	s.audit(ctx, models.AuditEvent{Type: event, UserID: userID, Email: user.Email})
+
+	// Whoever else knew the old password is signed out; this device gets new tokens
+	if err := s.LogoutAll(ctx, userID); err != nil {
+		return "", "", nil, err
+	}
+	if user, err = s.userRepo.Get(userID); err != nil {
+		return "", "", nil, err
+	}
+	accessToken, refreshToken, err := s.IssueTokens(ctx, user, "")
+	if err != nil {
+		return "", "", nil, err
+	}
+	return accessToken, refreshToken, &user, nil
+}
+
+// checkCurrentPassword re-authenticates a signed-in user before a sensitive change. Wrong
+// passwords count towards a lockout like logins do. Accounts created through Google or
+// another provider have no password to confirm.
+func (s *authService) checkCurrentPassword(ctx context.Context, action string, user models.User, password string) error {
+	if user.Password == "" {
+		return nil
+	}
+	now := time.Now().UTC()
+	keys := attemptKeys(ctx, action, user.Email, loginAccountPolicy)
+	if err := s.checkAttempts(keys, now); err != nil {
+		return err
+	}
+	if s.comparePassword(user.Password, password) != nil {
+		if _, err := s.recordFailure(ctx, keys, user.Email, now); err != nil {
+			return err
 		}
-		return []byte(secret), nil
-	})
+		return ErrWrongPassword
+	}
+	s.clearAttempts(keys)
+	return nil
+}
 
-	if err != nil || !token.Valid {
-		return "", "", errors.New("invalid refresh token")
+// codeFailed handles a rejected emailed code. Wrong, stale and unknown-account guesses are
+// counted; when the account's counter locks, the code is invalidated so the remaining
+// guesses are worthless and the user has to request a new one.
+func (s *authService) codeFailed(ctx context.Context, keys []attemptKey, email string, purpose models.CodePurpose, now time.Time, err error) error {
+	switch err {
+	case repository.ErrInvalidVerificationCode, repository.ErrVerificationCodeExpired,
+		repository.ErrInvalidResetCode, repository.ErrResetCodeExpired,
+		repository.ErrInvalidCode, repository.ErrCodeExpired, repository.ErrNotFound:
+	default:
+		return err
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return "", "", errors.New("invalid token claims")
+	locked, lockErr := s.recordFailure(ctx, keys, email, now)
+	for _, k := range locked {
+		if k.key != keys[0].key {
+			continue
+		}
+		if invErr := s.invalidateCode(email, purpose); invErr != nil && invErr != repository.ErrNotFound {
+			log.Printf("Failed to invalidate code for %s: %v", email, invErr)
+			continue
+		}
+		s.audit(ctx, models.AuditEvent{Type: strings.TrimSuffix(k.event, ".account_locked") + ".code_invalidated", Email: email})
 	}
+	if lockErr != nil {
+		return lockErr
+	}
+	return err
+}
 
-	// Verify this is a refresh token (not an access token)
-	tokenType, ok := claims["type"].(string)
-	if !ok || tokenType != "refresh" {
-		return "", "", errors.New("invalid token type")
+func (s *authService) invalidateCode(email string, purpose models.CodePurpose) error {
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil {
+		return err
 	}
+	return s.userRepo.DeleteOneTimeCode(user.ID, purpose)
+}
 
-	// Get user email from claims
-	email, ok := claims["email"].(string)
-	if !ok {
-		return "", "", errors.New("invalid token claims")
+// RefreshToken rotates a refresh token: the presented token is marked as replaced and a new
+// one in the same family is issued. Presenting a replaced token again means it was copied,
+// so the whole family is revoked.
+func (s *authService) RefreshToken(ctx context.Context, refreshTokenString string) (string, string, error) {
+	claims, err := s.tokens.ParseRefresh(refreshTokenString)
+	if err != nil {
+		return "", "", errors.New("invalid refresh token")
+	}
+
+	// Only tokens we issued and stored can be refreshed
+	stored, err := s.userRepo.GetRefreshToken(hashToken(refreshTokenString))
+	if err != nil {
+		return "", "", errors.New("invalid refresh token")
+	}
+	now := time.Now().UTC()
+	if stored.RevokedAt != nil {
+		return "", "", errors.New("refresh token revoked")
+	}
+	if stored.ReplacedBy != "" {
+		return "", "", s.revokeReusedFamily(stored, now)
+	}
+	if now.After(stored.ExpiresAt) {
+		return "", "", errors.New("refresh token expired")
 	}
 
 	// Get user from database
-	user, err := s.userRepo.GetByEmail(email)
+	user, err := s.userRepo.Get(stored.UserID)
 	if err != nil {
 		return "", "", errors.New("user not found")
 	}
+	picture := claims.Picture
 
 	// Generate new access and refresh tokens (rotation)
-	newAccessToken, err := s.generateAccessToken(user)
+	newAccessToken, err := s.generateAccessToken(user, picture)
 	if err != nil {
 		return "", "", err
 	}
 
-	newRefreshToken, err := s.generateRefreshToken(user)
+	newRefreshToken, next, err := s.newRefreshToken(ctx, user, picture, stored.FamilyID)
 	if err != nil {
 		return "", "", err
 	}
+	if err := s.userRepo.RotateRefreshToken(stored.TokenHash, next, now); err != nil {
+		if err == repository.ErrRefreshTokenSpent {
+			// Another request rotated it first: the same token was used twice
+			return "", "", s.revokeReusedFamily(stored, now)
+		}
+		return "", "", err
+	}
 
 	return newAccessToken, newRefreshToken, nil
 }
+
+// revokeReusedFamily revokes the family of a refresh token that was presented after rotation
+func (s *authService) revokeReusedFamily(stored models.RefreshToken, now time.Time) error {
+	log.Printf("Refresh token reuse detected for user %s (family %s); revoking family", stored.UserID, stored.FamilyID)
+	if err := s.userRepo.RevokeRefreshTokenFamily(stored.FamilyID, now); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// Logout revokes the refresh token family of the presented token, signing out that device.
+// Unknown or invalid tokens are ignored; there is nothing left to revoke.
+func (s *authService) Logout(ctx context.Context, refreshTokenString string) error {
+	stored, err := s.userRepo.GetRefreshToken(hashToken(refreshTokenString))
+	if err == repository.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.userRepo.RevokeRefreshTokenFamily(stored.FamilyID, time.Now().UTC())
+}
@@ -0,0 +1,365 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"altoai_mvp/internal/models"
+	"altoai_mvp/internal/repository"
+	"altoai_mvp/interview"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInviteInvalid   = errors.New("invite is invalid or has expired")
+	ErrInviteEmail     = errors.New("sign in with the verified email address the invite was sent to")
+	ErrUnknownCategory = errors.New("unknown question category")
+)
+
+// cohortInviteTTL is how long an emailed invite can be accepted
+const cohortInviteTTL = 7 * 24 * time.Hour
+
+// CohortService manages cohorts, their members and invitations, and builds counsellors' dashboards
+type CohortService interface {
+	// Create makes a cohort with creator as its first counsellor
+	Create(ctx context.Context, creator models.User, dto models.CreateCohortDTO) (models.Cohort, error)
+	Get(ctx context.Context, id string) (models.Cohort, error)
+	List(ctx context.Context) ([]models.Cohort, error)
+	ListForUser(ctx context.Context, userID string) ([]models.Cohort, error)
+	Delete(ctx context.Context, id string) error
+	Member(ctx context.Context, cohortID, userID string) (models.CohortMember, error)
+	Members(ctx context.Context, cohortID string) ([]models.CohortMember, error)
+	RemoveMember(ctx context.Context, cohortID, userID string) error
+	// Invite emails a one-time code that lets the owner of dto.Email join the cohort
+	Invite(ctx context.Context, cohortID string, inviter models.User, dto models.CohortInviteDTO) (models.CohortInvite, error)
+	Invites(ctx context.Context, cohortID string) ([]models.CohortInvite, error)
+	AcceptInvite(ctx context.Context, user models.User, code string) (models.Cohort, error)
+	SetAssignment(ctx context.Context, cohortID string, dto models.CohortAssignmentDTO) (models.Cohort, error)
+	// SessionOptions returns the options for a session a member starts in the cohort
+	SessionOptions(ctx context.Context, cohortID, userID string) (interview.SessionOptions, error)
+	Dashboard(ctx context.Context, cohortID string) (CohortDashboard, error)
+}
+
+// CriterionAverages are the mean AnalysisScores over graded answers
+type CriterionAverages struct {
+	MigrationIntent   float64 `json:"migration_intent"`
+	GoalUnderstanding float64 `json:"goal_understanding"`
+	AnswerLength      float64 `json:"answer_length"`
+	TotalScore        float64 `json:"total_score"`
+	GradedAnswers     int     `json:"graded_answers"`
+}
+
+// SessionOverview is one of a student's sessions as listed on a dashboard
+type SessionOverview struct {
+	ID                string                    `json:"id"`
+	Type              interview.SessionType     `json:"type"`
+	Status            interview.SessionStatus   `json:"status"`
+	CohortID          string                    `json:"cohort_id,omitempty"`
+	AnsweredQuestions int                       `json:"answered_questions"`
+	TotalQuestions    int                       `json:"total_questions"`
+	CreatedAt         time.Time                 `json:"created_at"`
+	UpdatedAt         time.Time                 `json:"updated_at"`
+	Summary           *interview.SessionSummary `json:"summary,omitempty"`
+}
+
+// StudentProgress is one student's row on a cohort dashboard; sessions are newest first
+type StudentProgress struct {
+	Student       models.User               `json:"student"`
+	JoinedAt      time.Time                 `json:"joined_at"`
+	Sessions      []SessionOverview         `json:"sessions"`
+	Averages      CriterionAverages         `json:"averages"`
+	LatestSummary *interview.SessionSummary `json:"latest_summary,omitempty"`
+}
+
+// CohortDashboard is a counsellor's view of a cohort's results
+type CohortDashboard struct {
+	Cohort   models.Cohort     `json:"cohort"`
+	Students []StudentProgress `json:"students"`
+	Averages CriterionAverages `json:"averages"` // across every student's graded answers
+}
+
+type cohortService struct {
+	repo     repository.UserRepo
+	emailSvc EmailService
+}
+
+func NewCohortService(repo repository.UserRepo, emailSvc EmailService) CohortService {
+	return &cohortService{repo: repo, emailSvc: emailSvc}
+}
+
+func (s *cohortService) Create(ctx context.Context, creator models.User, dto models.CreateCohortDTO) (models.Cohort, error) {
+	now := time.Now().UTC()
+	c := models.Cohort{
+		ID:           uuid.NewString(),
+		Name:         strings.TrimSpace(dto.Name),
+		Organization: strings.TrimSpace(dto.Organization),
+		CreatedBy:    creator.ID,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := s.repo.CreateCohort(c); err != nil {
+		return models.Cohort{}, err
+	}
+	err := s.repo.AddCohortMember(models.CohortMember{CohortID: c.ID, UserID: creator.ID, Role: models.CohortRoleCounsellor, JoinedAt: now})
+	if err != nil {
+		return models.Cohort{}, err
+	}
+	return c, nil
+}
+
+func (s *cohortService) Get(ctx context.Context, id string) (models.Cohort, error) {
+	return s.repo.GetCohort(id)
+}
+
+func (s *cohortService) List(ctx context.Context) ([]models.Cohort, error) {
+	return s.repo.ListCohorts()
+}
+
+func (s *cohortService) ListForUser(ctx context.Context, userID string) ([]models.Cohort, error) {
+	return s.repo.ListUserCohorts(userID)
+}
+
+func (s *cohortService) Delete(ctx context.Context, id string) error {
+	return s.repo.DeleteCohort(id)
+}
+
+func (s *cohortService) Member(ctx context.Context, cohortID, userID string) (models.CohortMember, error) {
+	return s.repo.GetCohortMember(cohortID, userID)
+}
+
+func (s *cohortService) Members(ctx context.Context, cohortID string) ([]models.CohortMember, error) {
+	return s.repo.ListCohortMembers(cohortID)
+}
+
+func (s *cohortService) RemoveMember(ctx context.Context, cohortID, userID string) error {
+	return s.repo.RemoveCohortMember(cohortID, userID)
+}
+
+func (s *cohortService) Invite(ctx context.Context, cohortID string, inviter models.User, dto models.CohortInviteDTO) (models.CohortInvite, error) {
+	cohort, err := s.repo.GetCohort(cohortID)
+	if err != nil {
+		return models.CohortInvite{}, err
+	}
+	role := dto.Role
+	if role == "" {
+		role = models.CohortRoleStudent
+	}
+
+	code, err := newRandomCode()
+	if err != nil {
+		return models.CohortInvite{}, err
+	}
+	now := time.Now().UTC()
+	invite := models.CohortInvite{
+		ID:        uuid.NewString(),
+		CohortID:  cohort.ID,
+		Email:     strings.ToLower(strings.TrimSpace(dto.Email)),
+		Role:      role,
+		InvitedBy: inviter.ID,
+		CodeHash:  hashToken(code),
+		CreatedAt: now,
+		ExpiresAt: now.Add(cohortInviteTTL),
+	}
+	if err := s.repo.CreateCohortInvite(invite); err != nil {
+		return models.CohortInvite{}, err
+	}
+	if err := s.emailSvc.SendCohortInvite(invite.Email, cohort.Name, inviter.Name, code); err != nil {
+		return models.CohortInvite{}, fmt.Errorf("failed to send invite email: %w", err)
+	}
+	return invite, nil
+}
+
+// newRandomCode returns an unguessable URL-safe code, for invites and sign-in links
+func newRandomCode() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (s *cohortService) Invites(ctx context.Context, cohortID string) ([]models.CohortInvite, error) {
+	return s.repo.ListCohortInvites(cohortID)
+}
+
+func (s *cohortService) AcceptInvite(ctx context.Context, user models.User, code string) (models.Cohort, error) {
+	invite, err := s.repo.GetCohortInvite(hashToken(strings.TrimSpace(code)))
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.Cohort{}, ErrInviteInvalid
+	}
+	if err != nil {
+		return models.Cohort{}, err
+	}
+	now := time.Now().UTC()
+	if invite.AcceptedAt != nil || now.After(invite.ExpiresAt) {
+		return models.Cohort{}, ErrInviteInvalid
+	}
+	// The code alone isn't enough: it must be redeemed by the account it was sent to
+	if !user.EmailVerified || !strings.EqualFold(user.Email, invite.Email) {
+		return models.Cohort{}, ErrInviteEmail
+	}
+	switch invite.Role {
+	case models.CohortRoleCounsellor:
+		if user.Role != models.RoleCounsellor && user.Role != models.RoleAdmin {
+			return models.Cohort{}, ErrNotCounsellor
+		}
+	default:
+		if user.Role != models.RoleStudent {
+			return models.Cohort{}, ErrNotStudent
+		}
+	}
+
+	member := models.CohortMember{CohortID: invite.CohortID, UserID: user.ID, Role: invite.Role, JoinedAt: now}
+	err = s.repo.AcceptCohortInvite(invite.ID, member, now)
+	if errors.Is(err, repository.ErrInviteSpent) {
+		return models.Cohort{}, ErrInviteInvalid
+	}
+	if err != nil {
+		return models.Cohort{}, err
+	}
+	return s.repo.GetCohort(invite.CohortID)
+}
+
+func (s *cohortService) SetAssignment(ctx context.Context, cohortID string, dto models.CohortAssignmentDTO) (models.Cohort, error) {
+	cohort, err := s.repo.GetCohort(cohortID)
+	if err != nil {
+		return models.Cohort{}, err
+	}
+	questions := make([]models.CohortQuestion, 0, len(dto.QuestionSet))
+	for i, q := range dto.QuestionSet {
+		if !slices.Contains(interview.CategoryOrder, q.Category) {
+			return models.Cohort{}, fmt.Errorf("%w: %q", ErrUnknownCategory, q.Category)
+		}
+		questions = append(questions, models.CohortQuestion{
+			ID:       fmt.Sprintf("cohort_q%d", i+1),
+			Category: q.Category,
+			Text:     strings.TrimSpace(q.Text),
+		})
+	}
+	cohort.Level = dto.Level
+	cohort.QuestionSet = questions
+	cohort.UpdatedAt = time.Now().UTC()
+	if err := s.repo.UpdateCohort(cohort); err != nil {
+		return models.Cohort{}, err
+	}
+	return cohort, nil
+}
+
+func (s *cohortService) SessionOptions(ctx context.Context, cohortID, userID string) (interview.SessionOptions, error) {
+	if _, err := s.repo.GetCohortMember(cohortID, userID); err != nil {
+		return interview.SessionOptions{}, err
+	}
+	cohort, err := s.repo.GetCohort(cohortID)
+	if err != nil {
+		return interview.SessionOptions{}, err
+	}
+	opts := interview.SessionOptions{Level: cohort.Level, CohortID: cohort.ID}
+	for _, q := range cohort.QuestionSet {
+		opts.Questions = append(opts.Questions, interview.Question{ID: q.ID, Category: q.Category, Text: q.Text})
+	}
+	return opts, nil
+}
+
+func (s *cohortService) Dashboard(ctx context.Context, cohortID string) (CohortDashboard, error) {
+	cohort, err := s.repo.GetCohort(cohortID)
+	if err != nil {
+		return CohortDashboard{}, err
+	}
+	members, err := s.repo.ListCohortMembers(cohortID)
+	if err != nil {
+		return CohortDashboard{}, err
+	}
+
+	dash := CohortDashboard{Cohort: cohort, Students: []StudentProgress{}}
+	var cohortTally scoreTally
+	for _, m := range members {
+		if m.Role != models.CohortRoleStudent || m.User == nil {
+			continue
+		}
+		sessions := interview.UserSessionHistory(m.UserID)
+
+		row := StudentProgress{Student: *m.User, JoinedAt: m.JoinedAt, Sessions: make([]SessionOverview, 0, len(sessions))}
+		var tally scoreTally
+		for _, sess := range sessions {
+			row.Sessions = append(row.Sessions, overviewOf(sess))
+			if row.LatestSummary == nil && sess.Summary != nil {
+				row.LatestSummary = sess.Summary
+			}
+			tally.addSession(sess)
+		}
+		row.Averages = tally.averages()
+		cohortTally.merge(tally)
+		dash.Students = append(dash.Students, row)
+	}
+	dash.Averages = cohortTally.averages()
+	return dash, nil
+}
+
+func overviewOf(s *interview.Session) SessionOverview {
+	answered := 0
+	for _, a := range s.Answers {
+		if !a.Skipped {
+			answered++
+		}
+	}
+	return SessionOverview{
+		ID:                s.ID,
+		Type:              s.Type,
+		Status:            s.Status,
+		CohortID:          s.CohortID,
+		AnsweredQuestions: answered,
+		TotalQuestions:    len(s.SelectedQuestions),
+		CreatedAt:         s.CreatedAt,
+		UpdatedAt:         s.UpdatedAt,
+		Summary:           s.Summary,
+	}
+}
+
+// scoreTally sums AnalysisScores criteria over graded answers
+type scoreTally struct {
+	migrationIntent, goalUnderstanding, answerLength, total, n int
+}
+
+func (t *scoreTally) addSession(s *interview.Session) {
+	for _, a := range s.Answers {
+		if a.Skipped || a.Analysis == nil {
+			continue
+		}
+		sc := a.Analysis.Scores
+		t.migrationIntent += sc.MigrationIntent
+		t.goalUnderstanding += sc.GoalUnderstanding
+		t.answerLength += sc.AnswerLength
+		t.total += sc.TotalScore
+		t.n++
+	}
+}
+
+func (t *scoreTally) merge(o scoreTally) {
+	t.migrationIntent += o.migrationIntent
+	t.goalUnderstanding += o.goalUnderstanding
+	t.answerLength += o.answerLength
+	t.total += o.total
+	t.n += o.n
+}
+
+func (t scoreTally) averages() CriterionAverages {
+	if t.n == 0 {
+		return CriterionAverages{}
+	}
+	avg := func(sum int) float64 { return math.Round(float64(sum)/float64(t.n)*100) / 100 }
+	return CriterionAverages{
+		MigrationIntent:   avg(t.migrationIntent),
+		GoalUnderstanding: avg(t.goalUnderstanding),
+		AnswerLength:      avg(t.answerLength),
+		TotalScore:        avg(t.total),
+		GradedAnswers:     t.n,
+	}
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"altoai_mvp/internal/models"
+	"altoai_mvp/internal/repository"
+)
+
+var (
+	ErrNotCounsellor = errors.New("user is not a counsellor")
+	ErrNotStudent    = errors.New("user is not a student")
+)
+
+// CounsellorService manages which students each counsellor looks after
+type CounsellorService interface {
+	AssignStudent(ctx context.Context, counsellorID, studentID string) error
+	UnassignStudent(ctx context.Context, counsellorID, studentID string) error
+	ListStudents(ctx context.Context, counsellorID string) ([]models.User, error)
+	IsAssigned(ctx context.Context, counsellorID, studentID string) (bool, error)
+}
+
+type counsellorService struct {
+	repo repository.UserRepo
+}
+
+func NewCounsellorService(repo repository.UserRepo) CounsellorService {
+	return &counsellorService{repo: repo}
+}
+
+func (s *counsellorService) AssignStudent(ctx context.Context, counsellorID, studentID string) error {
+	counsellor, err := s.repo.Get(counsellorID)
+	if err != nil {
+		return err
+	}
+	if counsellor.Role != models.RoleCounsellor {
+		return ErrNotCounsellor
+	}
+	student, err := s.repo.Get(studentID)
+	if err != nil {
+		return err
+	}
+	if student.Role != models.RoleStudent {
+		return ErrNotStudent
+	}
+	return s.repo.AssignStudent(counsellorID, studentID, time.Now().UTC())
+}
+
+func (s *counsellorService) UnassignStudent(ctx context.Context, counsellorID, studentID string) error {
+	return s.repo.UnassignStudent(counsellorID, studentID)
+}
+
+func (s *counsellorService) ListStudents(ctx context.Context, counsellorID string) ([]models.User, error) {
+	return s.repo.ListAssignedStudents(counsellorID)
+}
+
+func (s *counsellorService) IsAssigned(ctx context.Context, counsellorID, studentID string) (bool, error) {
+	return s.repo.IsStudentAssigned(counsellorID, studentID)
+}
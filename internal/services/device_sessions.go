@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"altoai_mvp/internal/models"
+	"altoai_mvp/internal/repository"
+)
+
+// ListSessions returns the user's signed-in devices, most recently seen first.
+// currentRefreshToken, if set, marks the device making the request.
+func (s *authService) ListSessions(ctx context.Context, userID, currentRefreshToken string) ([]models.DeviceSession, error) {
+	tokens, err := s.userRepo.ListRefreshTokens(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	currentFamily := ""
+	if currentRefreshToken != "" {
+		currentHash := hashToken(currentRefreshToken)
+		for _, t := range tokens {
+			if t.TokenHash == currentHash {
+				currentFamily = t.FamilyID
+			}
+		}
+	}
+
+	sessions := activeSessions(tokens, time.Now().UTC())
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentFamily
+	}
+	return sessions, nil
+}
+
+// RevokeSession signs out one of the user's devices. It returns repository.ErrNotFound
+// if the user has no active session with that ID.
+func (s *authService) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	tokens, err := s.userRepo.ListRefreshTokens(userID)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	for _, session := range activeSessions(tokens, now) {
+		if session.ID == sessionID {
+			return s.userRepo.RevokeRefreshTokenFamily(sessionID, now)
+		}
+	}
+	return repository.ErrNotFound
+}
+
+// LogoutAll revokes every refresh token of the user and bumps their token version,
+// so access tokens already handed out stop working on their next request
+func (s *authService) LogoutAll(ctx context.Context, userID string) error {
+	if err := s.userRepo.RevokeAllRefreshTokens(userID, time.Now().UTC()); err != nil {
+		return err
+	}
+	_, err := s.userRepo.IncrementTokenVersion(userID)
+	return err
+}
+
+// activeSessions groups refresh tokens by family and keeps the families whose latest
+// token can still be refreshed
+func activeSessions(tokens []models.RefreshToken, now time.Time) []models.DeviceSession {
+	families := make(map[string][]models.RefreshToken)
+	for _, t := range tokens {
+		families[t.FamilyID] = append(families[t.FamilyID], t)
+	}
+
+	var sessions []models.DeviceSession
+	for familyID, family := range families {
+		var head *models.RefreshToken
+		created := family[0].IssuedAt
+		for i, t := range family {
+			if t.IssuedAt.Before(created) {
+				created = t.IssuedAt
+			}
+			if t.ReplacedBy == "" {
+				head = &family[i]
+			}
+		}
+		if head == nil || head.RevokedAt != nil || now.After(head.ExpiresAt) {
+			continue
+		}
+
+		lastSeen := head.IssuedAt
+		if head.LastUsedAt != nil && head.LastUsedAt.After(lastSeen) {
+			lastSeen = *head.LastUsedAt
+		}
+		sessions = append(sessions, models.DeviceSession{
+			ID:         familyID,
+			Device:     head.Device,
+			UserAgent:  head.UserAgent,
+			IP:         head.IP,
+			CreatedAt:  created,
+			LastSeenAt: lastSeen,
+			ExpiresAt:  head.ExpiresAt,
+		})
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+	return sessions
+}
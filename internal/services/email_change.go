@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"altoai_mvp/internal/models"
+	"altoai_mvp/internal/repository"
+)
+
+// emailChangeCodeTTL is how long the new address has to confirm an email change
+const emailChangeCodeTTL = 15 * time.Minute
+
+var (
+	ErrSameEmail  = errors.New("new email must be different from the current one")
+	ErrEmailTaken = errors.New("an account with this email already exists")
+)
+
+// RequestEmailChange sends a code to the new address, after checking the current password.
+// Nothing changes until ConfirmEmailChange; a newer request replaces the pending one.
+func (s *authService) RequestEmailChange(ctx context.Context, userID string, dto models.ChangeEmailDTO) error {
+	user, err := s.userRepo.Get(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkCurrentPassword(ctx, "change_email", user, dto.CurrentPassword); err != nil {
+		return err
+	}
+
+	newEmail := strings.TrimSpace(dto.NewEmail)
+	if strings.EqualFold(newEmail, user.Email) {
+		return ErrSameEmail
+	}
+	if _, err := s.userRepo.GetByEmail(newEmail); err == nil {
+		return ErrEmailTaken
+	} else if err != repository.ErrNotFound {
+		return err
+	}
+
+	code, err := s.emailSvc.GenerateCode()
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	if err := s.userRepo.SetPendingEmail(userID, newEmail, now); err != nil {
+		return err
+	}
+	if err := s.userRepo.SetOneTimeCode(userID, models.CodePurposeChangeEmail, code, now.Add(emailChangeCodeTTL)); err != nil {
+		return err
+	}
+	if err := s.emailSvc.SendEmailChangeCode(newEmail, user.Name, code); err != nil {
+		return err
+	}
+	s.audit(ctx, models.AuditEvent{Type: "email.change_requested", UserID: userID, Email: user.Email, Detail: "to " + newEmail})
+	return nil
+}
+
+// ConfirmEmailChange moves the user to the pending email once the code sent there matches.
+// Wrong codes count towards the same lockout as email verification codes. The old address
+// is told about the change, and the returned tokens carry the new email.
+func (s *authService) ConfirmEmailChange(ctx context.Context, userID string, dto models.ConfirmEmailChangeDTO) (string, string, *models.User, error) {
+	user, err := s.userRepo.Get(userID)
+	if err != nil {
+		return "", "", nil, err
+	}
+	now := time.Now().UTC()
+	keys := attemptKeys(ctx, "change_email_code", user.Email, codeAccountPolicy)
+	if err := s.checkAttempts(keys, now); err != nil {
+		return "", "", nil, err
+	}
+
+	updated, err := s.userRepo.ConfirmEmailChange(userID, dto.Code, now)
+	if err == repository.ErrEmailTaken {
+		return "", "", nil, ErrEmailTaken
+	}
+	if err != nil {
+		return "", "", nil, s.codeFailed(ctx, keys, user.Email, models.CodePurposeChangeEmail, now, err)
+	}
+	s.clearAttempts(keys)
+
+	s.audit(ctx, models.AuditEvent{Type: "email.changed", UserID: userID, Email: updated.Email, Detail: "from " + user.Email})
+	if err := s.emailSvc.SendEmailChangedNotice(user.Email, user.Name, updated.Email); err != nil {
+		log.Printf("Failed to notify %s of the email change: %v", user.Email, err)
+	}
+
+	accessToken, refreshToken, err := s.IssueTokens(ctx, updated, "")
+	if err != nil {
+		return "", "", nil, err
+	}
+	return accessToken, refreshToken, &updated, nil
+}
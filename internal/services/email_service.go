@@ -11,6 +11,10 @@ import (
 type EmailService interface {
 	SendVerificationCode(email, name, code string) error
 	SendPasswordResetCode(email, name, code string) error
+	SendCohortInvite(email, cohortName, inviterName, code string) error
+	SendMagicLink(email, name, link string) error
+	SendEmailChangeCode(email, name, code string) error
+	SendEmailChangedNotice(oldEmail, name, newEmail string) error
 	GenerateCode() (string, error)
 }
 
@@ -97,3 +101,73 @@ AI Interviewer Team`, name, code)
 
 	return s.sendEmail(email, subject, body)
 }
+
+func (s *emailService) SendCohortInvite(email, cohortName, inviterName, code string) error {
+	subject := fmt.Sprintf("You're invited to join %s - AI Interviewer", cohortName)
+	body := fmt.Sprintf(`Hello,
+
+%s has invited you to join the cohort "%s" on AI Interviewer, where you can practise
+interviews together and share your results with your counsellor.
+
+Sign in with this email address and enter your invite code: %s
+
+This invite will expire in 7 days.
+
+If you weren't expecting this invite, please ignore this email.
+
+Best regards,
+AI Interviewer Team`, inviterName, cohortName, code)
+
+	return s.sendEmail(email, subject, body)
+}
+
+func (s *emailService) SendMagicLink(email, name, link string) error {
+	subject := "Your Sign-In Link - AI Interviewer"
+	body := fmt.Sprintf(`Hello %s,
+
+Use this link to sign in to AI Interviewer, no password needed:
+
+%s
+
+The link works once and will expire in 15 minutes.
+
+If you didn't ask to sign in, please ignore this email.
+
+Best regards,
+AI Interviewer Team`, name, link)
+
+	return s.sendEmail(email, subject, body)
+}
+
+func (s *emailService) SendEmailChangeCode(email, name, code string) error {
+	subject := "Confirm Your New Email - AI Interviewer"
+	body := fmt.Sprintf(`Hello %s,
+
+You asked to change the email address of your AI Interviewer account to this one.
+
+Your confirmation code is: %s
+
+This code will expire in 15 minutes.
+
+If you didn't ask for this, please ignore this email.
+
+Best regards,
+AI Interviewer Team`, name, code)
+
+	return s.sendEmail(email, subject, body)
+}
+
+func (s *emailService) SendEmailChangedNotice(oldEmail, name, newEmail string) error {
+	subject := "Your Email Was Changed - AI Interviewer"
+	body := fmt.Sprintf(`Hello %s,
+
+The email address of your AI Interviewer account was changed to %s.
+You will no longer receive account emails at this address.
+
+If you didn't make this change, please contact us right away.
+
+Best regards,
+AI Interviewer Team`, name, newEmail)
+
+	return s.sendEmail(oldEmail, subject, body)
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"altoai_mvp/internal/models"
+	"altoai_mvp/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// ErrUnverifiedProviderEmail is returned when a provider account that isn't linked yet
+// has no verified email, so it can't be matched to or registered as a user
+var ErrUnverifiedProviderEmail = errors.New("the login provider did not confirm your email address")
+
+// ExternalIdentity is who an external login provider says the user is
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string // the provider's stable account ID
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
+}
+
+// LoginWithIdentity returns the user behind a provider account. An account seen before
+// maps to the user it was linked to; a new one is linked to the user with the same
+// verified email, or to a newly created user, so one person never ends up with two users.
+func (s *authService) LoginWithIdentity(ctx context.Context, id ExternalIdentity) (models.User, error) {
+	link, err := s.userRepo.GetIdentity(id.Provider, id.Subject)
+	if err == nil {
+		user, err := s.userRepo.Get(link.UserID)
+		if err != nil {
+			return models.User{}, err
+		}
+		return s.refreshProfile(user, id), nil
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return models.User{}, err
+	}
+
+	// Only an address the provider verified may claim an existing user
+	email := strings.TrimSpace(id.Email)
+	if email == "" || !id.EmailVerified {
+		return models.User{}, ErrUnverifiedProviderEmail
+	}
+
+	user, err := s.userRepo.GetByEmail(email)
+	if errors.Is(err, repository.ErrNotFound) {
+		name := id.Name
+		if name == "" {
+			name = email
+		}
+		// Provider users have no password until they set one
+		user, err = s.userRepo.Create(email, name, "")
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+
+	if user, err = s.claimUnverifiedUser(ctx, user, id.Provider); err != nil {
+		return models.User{}, err
+	}
+
+	err = s.userRepo.CreateIdentity(models.UserIdentity{
+		ID:        uuid.NewString(),
+		UserID:    user.ID,
+		Provider:  id.Provider,
+		Subject:   id.Subject,
+		Email:     email,
+		CreatedAt: time.Now(),
+	})
+	// A concurrent login may have linked the same account first
+	if err != nil && !errors.Is(err, repository.ErrIdentityTaken) {
+		return models.User{}, err
+	}
+	return s.refreshProfile(user, id), nil
+}
+
+// claimUnverifiedUser marks user's email verified once its owner has proven control of the
+// address another way. Whoever registered the account without verifying it may not be that
+// owner, so their password, pending verification code and sessions are dropped first.
+func (s *authService) claimUnverifiedUser(ctx context.Context, user models.User, via string) (models.User, error) {
+	if user.EmailVerified {
+		return user, nil
+	}
+	if user.Password != "" {
+		if err := s.userRepo.UpdatePassword(user.ID, ""); err != nil {
+			return models.User{}, err
+		}
+		user.Password = ""
+	}
+	if err := s.userRepo.DeleteOneTimeCode(user.ID, models.CodePurposeVerifyEmail); err != nil {
+		return models.User{}, err
+	}
+	if err := s.LogoutAll(ctx, user.ID); err != nil {
+		return models.User{}, err
+	}
+	if err := s.userRepo.MarkEmailVerified(user.Email); err != nil {
+		return models.User{}, err
+	}
+	s.audit(ctx, models.AuditEvent{Type: "email.claimed", UserID: user.ID, Email: user.Email, Detail: "via " + via})
+	return s.userRepo.Get(user.ID)
+}
+
+// refreshProfile keeps the user's name in step with the provider; failures don't block login
+func (s *authService) refreshProfile(user models.User, id ExternalIdentity) models.User {
+	if id.Name == "" || id.Name == user.Name {
+		return user
+	}
+	if updated, err := s.userRepo.Update(user.ID, nil, &id.Name); err == nil {
+		return updated
+	}
+	return user
+}
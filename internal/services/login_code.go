@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"altoai_mvp/internal/models"
+	"altoai_mvp/internal/repository"
+	"altoai_mvp/internal/token"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// loginCodeTTL is how long the frontend has to exchange a login code after an OAuth redirect
+const loginCodeTTL = 30 * time.Second
+
+// ErrLoginCodeInvalid covers forged, expired and already used login codes
+var ErrLoginCodeInvalid = errors.New("invalid or expired code")
+
+// IssueLoginCode signs a code for the redirect back to the frontend after a provider login.
+// Like a magic link, its ID is stored as a one-time code, so it works once on any replica
+// and a newer login replaces it.
+func (s *authService) IssueLoginCode(ctx context.Context, user models.User, picture string) (string, error) {
+	nonce, err := newRandomCode()
+	if err != nil {
+		return "", err
+	}
+	signed, expiresAt, err := s.tokens.IssueLoginCode(token.Claims{
+		Picture:          picture,
+		Version:          user.TokenVersion,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: user.ID, ID: nonce},
+	}, loginCodeTTL)
+	if err != nil {
+		return "", err
+	}
+	if err := s.userRepo.SetOneTimeCode(user.ID, models.CodePurposeOAuthLogin, nonce, expiresAt.UTC()); err != nil {
+		return "", err
+	}
+	return signed, nil
+}
+
+// ExchangeLoginCode redeems a login code for the usual access and refresh tokens, or for an
+// MFAChallengeError when the user still has to give a second factor
+func (s *authService) ExchangeLoginCode(ctx context.Context, code string) (string, string, *models.User, error) {
+	claims, err := s.tokens.ParseLoginCode(code)
+	if err != nil {
+		return "", "", nil, ErrLoginCodeInvalid
+	}
+
+	err = s.userRepo.ConsumeOneTimeCode(claims.Subject, models.CodePurposeOAuthLogin, claims.ID, time.Now().UTC())
+	if errors.Is(err, repository.ErrInvalidCode) || errors.Is(err, repository.ErrCodeExpired) {
+		return "", "", nil, ErrLoginCodeInvalid
+	}
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	user, err := s.userRepo.Get(claims.Subject)
+	if err == repository.ErrNotFound || (err == nil && user.TokenVersion != claims.Version) {
+		// The account was deleted or signed out everywhere since the redirect
+		return "", "", nil, ErrLoginCodeInvalid
+	}
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	if err := s.MFAChallenge(ctx, user); err != nil {
+		return "", "", nil, err
+	}
+
+	accessToken, refreshToken, err := s.IssueTokens(ctx, user, claims.Picture)
+	if err != nil {
+		return "", "", nil, err
+	}
+	return accessToken, refreshToken, &user, nil
+}
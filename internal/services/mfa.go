@@ -0,0 +1,329 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"altoai_mvp/internal/models"
+	"altoai_mvp/internal/repository"
+	"altoai_mvp/internal/token"
+	"altoai_mvp/internal/totp"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// mfaChallengeTTL is how long a user has to enter their second factor after the password
+	mfaChallengeTTL = 5 * time.Minute
+	// recoveryCodeCount recovery codes are issued on enrolment and on every regeneration
+	recoveryCodeCount = 10
+	// totpIssuer labels the account in authenticator apps
+	totpIssuer = "AI Interviewer"
+)
+
+var (
+	// ErrMFARequired is matched by every MFAChallengeError
+	ErrMFARequired         = errors.New("two-factor authentication required")
+	ErrMFAChallengeInvalid = errors.New("this sign-in attempt is invalid or has expired, please log in again")
+	ErrInvalidMFACode      = errors.New("invalid authentication code")
+	ErrMFAAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled       = errors.New("two-factor authentication is not enabled")
+	ErrMFANotEnrolling     = errors.New("no authenticator app is being set up, please start again")
+	ErrMFARequiredByRole   = errors.New("your role requires two-factor authentication")
+)
+
+// MFAChallengeError is returned instead of tokens when a login needs a second factor. Token
+// is exchanged at /auth/mfa/verify; if SetupRequired, the user's role requires MFA and they
+// have to set up an authenticator app first, at /auth/mfa/setup.
+type MFAChallengeError struct {
+	Token         string
+	ExpiresAt     time.Time
+	SetupRequired bool
+}
+
+func (e *MFAChallengeError) Error() string {
+	return ErrMFARequired.Error()
+}
+
+func (e *MFAChallengeError) Is(target error) bool {
+	return target == ErrMFARequired
+}
+
+// MFAChallenge returns an MFAChallengeError if user has to pass a second factor before getting
+// tokens: they turned MFA on, or their role requires it. Every login path calls it after the
+// first factor succeeds.
+func (s *authService) MFAChallenge(ctx context.Context, user models.User) error {
+	enabled, err := s.mfaEnabled(user.ID)
+	if err != nil {
+		return err
+	}
+	required := false
+	if !enabled {
+		if required, err = s.mfaRequired(user.Role); err != nil {
+			return err
+		}
+	}
+	if !enabled && !required {
+		return nil
+	}
+
+	signed, expiresAt, err := s.tokens.IssueMFAChallenge(token.Claims{
+		Email:            user.Email,
+		Version:          user.TokenVersion,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: user.ID},
+	}, mfaChallengeTTL)
+	if err != nil {
+		return err
+	}
+	return &MFAChallengeError{Token: signed, ExpiresAt: expiresAt, SetupRequired: !enabled}
+}
+
+// MFAChallengeUser returns the user an MFA challenge token was issued to
+func (s *authService) MFAChallengeUser(ctx context.Context, mfaToken string) (models.User, error) {
+	claims, err := s.tokens.ParseMFAChallenge(mfaToken)
+	if err != nil {
+		return models.User{}, ErrMFAChallengeInvalid
+	}
+	user, err := s.userRepo.Get(claims.Subject)
+	if err == repository.ErrNotFound || (err == nil && user.TokenVersion != claims.Version) {
+		// Deleted, or signed out everywhere since the password step
+		return models.User{}, ErrMFAChallengeInvalid
+	}
+	return user, err
+}
+
+// VerifyMFA completes a login challenge with a code from the authenticator app or a recovery code
+func (s *authService) VerifyMFA(ctx context.Context, dto models.MFAChallengeDTO) (string, string, *models.User, error) {
+	user, err := s.MFAChallengeUser(ctx, dto.MFAToken)
+	if err != nil {
+		return "", "", nil, err
+	}
+	m, err := s.confirmedMFA(user.ID)
+	if err == ErrMFANotEnabled {
+		return "", "", nil, ErrMFAChallengeInvalid
+	}
+	if err != nil {
+		return "", "", nil, err
+	}
+	if err := s.verifySecondFactor(ctx, user, m, dto.Code); err != nil {
+		return "", "", nil, err
+	}
+
+	accessToken, refreshToken, err := s.IssueTokens(ctx, user, "")
+	if err != nil {
+		return "", "", nil, err
+	}
+	return accessToken, refreshToken, &user, nil
+}
+
+func (s *authService) MFAStatus(ctx context.Context, userID string) (models.MFAStatus, error) {
+	user, err := s.userRepo.Get(userID)
+	if err != nil {
+		return models.MFAStatus{}, err
+	}
+	var status models.MFAStatus
+	if status.Enabled, err = s.mfaEnabled(userID); err != nil {
+		return models.MFAStatus{}, err
+	}
+	if status.Required, err = s.mfaRequired(user.Role); err != nil {
+		return models.MFAStatus{}, err
+	}
+	if status.Enabled {
+		if status.RecoveryCodesLeft, err = s.userRepo.CountRecoveryCodes(userID); err != nil {
+			return models.MFAStatus{}, err
+		}
+	}
+	return status, nil
+}
+
+// BeginTOTPEnrollment generates a new authenticator secret. It doesn't protect logins until
+// ConfirmTOTPEnrollment proves the app has it. To replace an enabled app, disable MFA first.
+func (s *authService) BeginTOTPEnrollment(ctx context.Context, userID string) (models.MFAEnrollment, error) {
+	user, err := s.userRepo.Get(userID)
+	if err != nil {
+		return models.MFAEnrollment{}, err
+	}
+	enabled, err := s.mfaEnabled(userID)
+	if err != nil {
+		return models.MFAEnrollment{}, err
+	}
+	if enabled {
+		return models.MFAEnrollment{}, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return models.MFAEnrollment{}, err
+	}
+	if err := s.userRepo.SaveMFA(models.UserMFA{UserID: userID, Secret: secret, CreatedAt: time.Now().UTC()}); err != nil {
+		return models.MFAEnrollment{}, err
+	}
+	return models.MFAEnrollment{Secret: secret, URI: totp.URI(totpIssuer, user.Email, secret)}, nil
+}
+
+// ConfirmTOTPEnrollment turns MFA on once code matches the new secret, and returns the
+// recovery codes, which are shown only this once
+func (s *authService) ConfirmTOTPEnrollment(ctx context.Context, userID, code string) ([]string, error) {
+	m, err := s.userRepo.GetMFA(userID)
+	if err == repository.ErrNotFound {
+		return nil, ErrMFANotEnrolling
+	}
+	if err != nil {
+		return nil, err
+	}
+	if m.ConfirmedAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	now := time.Now().UTC()
+	step, ok := totp.Validate(m.Secret, code, now)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+	m.ConfirmedAt = &now
+	m.LastUsedStep = step
+	if err := s.userRepo.SaveMFA(m); err != nil {
+		return nil, err
+	}
+	codes, err := s.newRecoveryCodes(userID, now)
+	if err != nil {
+		return nil, err
+	}
+	s.audit(ctx, models.AuditEvent{Type: "mfa.enabled", UserID: userID})
+	return codes, nil
+}
+
+// DisableMFA turns MFA off after checking a current code, unless the user's role requires it
+func (s *authService) DisableMFA(ctx context.Context, userID, code string) error {
+	user, err := s.userRepo.Get(userID)
+	if err != nil {
+		return err
+	}
+	m, err := s.confirmedMFA(userID)
+	if err != nil {
+		return err
+	}
+	if required, err := s.mfaRequired(user.Role); err != nil {
+		return err
+	} else if required {
+		return ErrMFARequiredByRole
+	}
+	if err := s.verifySecondFactor(ctx, user, m, code); err != nil {
+		return err
+	}
+	if err := s.userRepo.DeleteMFA(userID); err != nil {
+		return err
+	}
+	s.audit(ctx, models.AuditEvent{Type: "mfa.disabled", UserID: userID, Email: user.Email})
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking a current code
+func (s *authService) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	user, err := s.userRepo.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	m, err := s.confirmedMFA(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifySecondFactor(ctx, user, m, code); err != nil {
+		return nil, err
+	}
+	return s.newRecoveryCodes(userID, time.Now().UTC())
+}
+
+func (s *authService) mfaEnabled(userID string) (bool, error) {
+	_, err := s.confirmedMFA(userID)
+	if err == ErrMFANotEnabled {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *authService) confirmedMFA(userID string) (models.UserMFA, error) {
+	m, err := s.userRepo.GetMFA(userID)
+	if err == repository.ErrNotFound || (err == nil && m.ConfirmedAt == nil) {
+		return models.UserMFA{}, ErrMFANotEnabled
+	}
+	return m, err
+}
+
+func (s *authService) mfaRequired(role models.Role) (bool, error) {
+	roles, err := s.userRepo.ListMFARequiredRoles()
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(roles, role), nil
+}
+
+// verifySecondFactor checks code as a TOTP code, then as a recovery code. Wrong codes count
+// towards the same lockouts as wrong passwords.
+func (s *authService) verifySecondFactor(ctx context.Context, user models.User, m models.UserMFA, code string) error {
+	now := time.Now().UTC()
+	keys := attemptKeys(ctx, "mfa", user.Email, loginAccountPolicy)
+	if err := s.checkAttempts(keys, now); err != nil {
+		return err
+	}
+
+	ok := false
+	if step, valid := totp.Validate(m.Secret, code, now); valid {
+		used, err := s.userRepo.MarkTOTPStepUsed(user.ID, step)
+		if err != nil {
+			return err
+		}
+		ok = used
+	} else {
+		err := s.userRepo.UseRecoveryCode(user.ID, hashRecoveryCode(code), now)
+		if err != nil && err != repository.ErrNotFound {
+			return err
+		}
+		if ok = err == nil; ok {
+			s.audit(ctx, models.AuditEvent{Type: "mfa.recovery_code_used", UserID: user.ID, Email: user.Email})
+		}
+	}
+
+	if !ok {
+		if _, err := s.recordFailure(ctx, keys, user.Email, now); err != nil {
+			return err
+		}
+		return ErrInvalidMFACode
+	}
+	s.clearAttempts(keys)
+	return nil
+}
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newRecoveryCodes replaces the user's recovery codes and returns them, formatted
+// xxxx-xxxx-xxxx-xxxx. Only their hashes are stored.
+func (s *authService) newRecoveryCodes(userID string, now time.Time) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(recoveryEncoding.EncodeToString(b))
+		codes[i] = raw[0:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:16]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	if err := s.userRepo.ReplaceRecoveryCodes(userID, hashes, now); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// hashRecoveryCode ignores case, dashes and spaces, so codes can be typed as read
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	return hashToken(code)
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxPasswordBytes is as much of a password as bcrypt looks at
+const maxPasswordBytes = 72
+
+// ErrWeakPassword is matched by every PasswordPolicyError
+var ErrWeakPassword = errors.New("password does not meet the password policy")
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+var commonPasswords = func() map[string]bool {
+	set := map[string]bool{}
+	for _, line := range strings.Split(commonPasswordList, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			set[line] = true
+		}
+	}
+	return set
+}()
+
+// PasswordPolicy is what a new password has to satisfy when registering, resetting or
+// changing it
+type PasswordPolicy struct {
+	MinLength  int // in characters
+	MinClasses int // how many of lower case letters, upper case letters, digits and symbols
+}
+
+var DefaultPasswordPolicy = PasswordPolicy{MinLength: 10, MinClasses: 2}
+
+// PasswordPolicyFromEnv reads PASSWORD_MIN_LENGTH and PASSWORD_MIN_CLASSES, falling back to
+// DefaultPasswordPolicy
+func PasswordPolicyFromEnv() (PasswordPolicy, error) {
+	p := DefaultPasswordPolicy
+	if v := os.Getenv("PASSWORD_MIN_LENGTH"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPasswordBytes {
+			return PasswordPolicy{}, fmt.Errorf("PASSWORD_MIN_LENGTH must be between 1 and %d", maxPasswordBytes)
+		}
+		p.MinLength = n
+	}
+	if v := os.Getenv("PASSWORD_MIN_CLASSES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 4 {
+			return PasswordPolicy{}, errors.New("PASSWORD_MIN_CLASSES must be between 1 and 4")
+		}
+		p.MinClasses = n
+	}
+	return p, nil
+}
+
+// PasswordPolicyError lists every way a password falls short, so they can be fixed at once
+type PasswordPolicyError struct {
+	Problems []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return strings.Join(e.Problems, "; ")
+}
+
+func (e *PasswordPolicyError) Is(target error) bool {
+	return target == ErrWeakPassword
+}
+
+// Check returns a PasswordPolicyError if password is too short, too simple, one of the
+// bundled common passwords, or the account's own email or name
+func (p PasswordPolicy) Check(password, email, name string) error {
+	var problems []string
+	if n := utf8.RuneCountInString(password); n < p.MinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters", p.MinLength))
+	}
+	if len(password) > maxPasswordBytes {
+		problems = append(problems, fmt.Sprintf("must be at most %d bytes", maxPasswordBytes))
+	}
+	if passwordClasses(password) < p.MinClasses {
+		problems = append(problems, fmt.Sprintf("must mix at least %d of lower case letters, upper case letters, digits and symbols", p.MinClasses))
+	}
+	if isCommonPassword(password) {
+		problems = append(problems, "is too common, it appears in lists of breached passwords")
+	}
+	if matchesAccount(password, email, name) {
+		problems = append(problems, "must not be your email address or name")
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return &PasswordPolicyError{Problems: problems}
+}
+
+func passwordClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	n := 0
+	for _, has := range []bool{lower, upper, digit, symbol} {
+		if has {
+			n++
+		}
+	}
+	return n
+}
+
+// isCommonPassword also catches list entries with digits or symbols added to the end,
+// such as "Summer2024!"
+func isCommonPassword(password string) bool {
+	p := strings.ToLower(password)
+	if commonPasswords[p] {
+		return true
+	}
+	base := strings.TrimRightFunc(p, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	return base != "" && base != p && commonPasswords[base]
+}
+
+func matchesAccount(password, email, name string) bool {
+	p := normalizeForCompare(password)
+	local, _, _ := strings.Cut(email, "@")
+	for _, s := range []string{email, local, name} {
+		if s = normalizeForCompare(s); s != "" && p == s {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeForCompare(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), ""))
+}
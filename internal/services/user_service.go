@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"errors"
+	"slices"
 	"time"
 
 	"altoai_mvp/internal/models"
@@ -16,6 +17,13 @@ type UserService interface {
 	Create(ctx context.Context, dto models.CreateUserDTO) (models.User, error)
 	Update(ctx context.Context, id string, dto models.UpdateUserDTO) (models.User, error)
 	Delete(ctx context.Context, id string) error
+	// SetRole changes the user's role and invalidates their access tokens, which carry the old role
+	SetRole(ctx context.Context, id string, role models.Role) (models.User, error)
+	// AuditEvents returns the newest security events, such as lockouts
+	AuditEvents(ctx context.Context, limit int) ([]models.AuditEvent, error)
+	// MFAPolicy lists the roles whose members must use two-factor authentication
+	MFAPolicy(ctx context.Context) ([]models.Role, error)
+	SetMFAPolicy(ctx context.Context, requiredRoles []models.Role) ([]models.Role, error)
 }
 
 type userService struct {
@@ -83,4 +91,39 @@ func (s *userService) Delete(ctx context.Context, id string) error {
 	return s.repo.Delete(id)
 }
 
+func (s *userService) SetRole(ctx context.Context, id string, role models.Role) (models.User, error) {
+	if !role.Valid() {
+		return models.User{}, errors.New("unknown role")
+	}
+	user, err := s.repo.SetRole(id, role)
+	if err != nil {
+		return user, err
+	}
+	if _, err := s.repo.IncrementTokenVersion(id); err != nil {
+		return user, err
+	}
+	return user, nil
+}
+
+func (s *userService) AuditEvents(ctx context.Context, limit int) ([]models.AuditEvent, error) {
+	return s.repo.ListAuditEvents(limit)
+}
+
+func (s *userService) MFAPolicy(ctx context.Context) ([]models.Role, error) {
+	return s.repo.ListMFARequiredRoles()
+}
+
+func (s *userService) SetMFAPolicy(ctx context.Context, requiredRoles []models.Role) ([]models.Role, error) {
+	roles := []models.Role{}
+	for _, r := range requiredRoles {
+		if !slices.Contains(roles, r) {
+			roles = append(roles, r)
+		}
+	}
+	if err := s.repo.SetMFARequiredRoles(roles); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
 var ErrNotFound = errors.New("not found") // you can map repo errors if needed
@@ -0,0 +1,158 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSABits is the smallest RSA key accepted for signing or verification
+const minRSABits = 2048
+
+// LoadKeyFile reads a PEM-encoded RSA or Ed25519 key. A private key (PKCS#1 or PKCS#8)
+// can sign and verify; a public key (PKIX) only verifies. RSA keys sign RS256 and
+// Ed25519 keys EdDSA. An empty id defaults to the key's RFC 7638 thumbprint.
+func LoadKeyFile(id, path string) (Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Key{}, err
+	}
+	key, err := ParseKeyPEM(id, data)
+	if err != nil {
+		return Key{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+// ParseKeyPEM is LoadKeyFile for PEM data already in memory
+func ParseKeyPEM(id string, data []byte) (Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, errors.New("no PEM block found")
+	}
+
+	var parsed any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return Key{}, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return Key{}, err
+	}
+
+	var key Key
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key = Key{Method: jwt.SigningMethodRS256, signing: k, verifying: &k.PublicKey}
+	case *rsa.PublicKey:
+		key = Key{Method: jwt.SigningMethodRS256, verifying: k}
+	case ed25519.PrivateKey:
+		key = Key{Method: jwt.SigningMethodEdDSA, signing: k, verifying: k.Public()}
+	case ed25519.PublicKey:
+		key = Key{Method: jwt.SigningMethodEdDSA, verifying: k}
+	default:
+		return Key{}, fmt.Errorf("unsupported key type %T; use RSA or Ed25519", parsed)
+	}
+	if pub, ok := key.verifying.(*rsa.PublicKey); ok && pub.N.BitLen() < minRSABits {
+		return Key{}, fmt.Errorf("RSA key is %d bits, need at least %d", pub.N.BitLen(), minRSABits)
+	}
+
+	key.ID = id
+	if key.ID == "" {
+		key.ID = key.thumbprint()
+	}
+	return key, nil
+}
+
+// JWK is a public key in JSON Web Key form (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // OKP curve
+	X   string `json:"x,omitempty"`   // OKP public key
+}
+
+// JWKSet is the document served at /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public halves of the asymmetric keys tokens can be verified with.
+// HMAC secrets are never published.
+func (s *Service) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, id := range s.order {
+		if jwk, ok := s.keys[id].jwk(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+// jwk converts an asymmetric key's public half; HMAC keys report false
+func (k Key) jwk() (JWK, bool) {
+	switch pub := k.verifying.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA", Kid: k.ID, Use: "sig", Alg: k.Method.Alg(),
+			N: b64(pub.N.Bytes()),
+			E: b64(big.NewInt(int64(pub.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return JWK{Kty: "OKP", Kid: k.ID, Use: "sig", Alg: k.Method.Alg(), Crv: "Ed25519", X: b64(pub)}, true
+	}
+	return JWK{}, false
+}
+
+// thumbprint is the RFC 7638 SHA-256 thumbprint of the public key, used as its default kid
+func (k Key) thumbprint() string {
+	jwk, ok := k.jwk()
+	if !ok {
+		return ""
+	}
+	// Required members only, in lexicographic order
+	var members any
+	if jwk.Kty == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+	raw, _ := json.Marshal(members)
+	sum := sha256.Sum256(raw)
+	return b64(sum[:])
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
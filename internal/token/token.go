@@ -0,0 +1,308 @@
+package token
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Token types, carried in the "type" claim so one can't stand in for the other
+const (
+	TypeAccess    = "access"
+	TypeRefresh   = "refresh"
+	TypeMagicLink = "magic_link"
+	// TypeLoginCode carries a provider login from the OAuth redirect to /auth/exchange
+	TypeLoginCode = "login_code"
+	// TypeMFAChallenge proves the password step of a login; it is exchanged, together with
+	// a second factor, for real tokens
+	TypeMFAChallenge = "mfa_challenge"
+)
+
+var (
+	// ErrInvalidToken is returned for any token that fails validation
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrWrongType is returned when an access token is presented as a refresh token or vice versa
+	ErrWrongType = errors.New("invalid token type")
+)
+
+// Claims are the claims of every token the API issues. Subject is the user ID.
+type Claims struct {
+	Email   string `json:"email,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Picture string `json:"picture,omitempty"`
+	Role    string `json:"role,omitempty"`
+	Type    string `json:"type"`
+	Version int    `json:"ver"` // the user's token version when the token was issued
+	jwt.RegisteredClaims
+}
+
+// Key is a signing or verification key, identified in token headers by its ID (kid).
+// Verification-only keys have no signing half.
+type Key struct {
+	ID        string
+	Method    jwt.SigningMethod
+	signing   any
+	verifying any
+}
+
+// HMACKey returns an HS256 key for secret
+func HMACKey(id string, secret []byte) Key {
+	return Key{ID: id, Method: jwt.SigningMethodHS256, signing: secret, verifying: secret}
+}
+
+// Config configures a Service
+type Config struct {
+	Issuer     string
+	Audience   string
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+	// SigningKey signs new tokens; VerificationKeys are older keys still accepted
+	// until the tokens they signed expire
+	SigningKey       Key
+	VerificationKeys []Key
+}
+
+// Service signs tokens with one key and verifies them against any configured key
+type Service struct {
+	cfg   Config
+	keys  map[string]Key
+	order []string // key IDs, signing key first
+	algs  []string
+}
+
+// New validates cfg and returns a Service
+func New(cfg Config) (*Service, error) {
+	if cfg.SigningKey.ID == "" || cfg.SigningKey.signing == nil {
+		return nil, errors.New("token: a signing key with an ID is required")
+	}
+	if cfg.AccessTTL <= 0 || cfg.RefreshTTL <= 0 {
+		return nil, errors.New("token: access and refresh lifetimes must be positive")
+	}
+
+	s := &Service{cfg: cfg, keys: map[string]Key{}}
+	for _, k := range append([]Key{cfg.SigningKey}, cfg.VerificationKeys...) {
+		if k.ID == "" || k.Method == nil || k.verifying == nil {
+			return nil, errors.New("token: verification keys need an ID, method and key")
+		}
+		if _, dup := s.keys[k.ID]; dup {
+			return nil, fmt.Errorf("token: duplicate key ID %q", k.ID)
+		}
+		s.keys[k.ID] = k
+		s.order = append(s.order, k.ID)
+		if !slices.Contains(s.algs, k.Method.Alg()) {
+			s.algs = append(s.algs, k.Method.Alg())
+		}
+	}
+	return s, nil
+}
+
+// FromEnv builds a Service from the environment:
+//
+//	JWT_SIGNING_KEY_FILE    PEM RSA or Ed25519 private key; tokens are signed RS256/EdDSA
+//	JWT_SIGNING_KEY_ID      its kid (default: the key's RFC 7638 thumbprint)
+//	JWT_VERIFICATION_KEY_FILES  retired keys still accepted, as kid=path,kid=path (kid optional)
+//	JWT_SECRET              HS256 signing secret when no key file is set
+//	JWT_KEY_ID              kid of JWT_SECRET (default "default")
+//	JWT_ACCEPT_HS256        "true" keeps accepting JWT_SECRET tokens after moving to a key file
+//	JWT_PREVIOUS_KEYS       retired secrets still accepted, as kid:secret,kid:secret
+//	JWT_ISSUER, JWT_AUDIENCE  default "altoai_mvp"
+//	ACCESS_TOKEN_EXPIRY     Go duration, default 30m
+//	REFRESH_TOKEN_EXPIRY    Go duration, default 720h
+func FromEnv() (*Service, error) {
+	accessTTL, err := durationEnv("ACCESS_TOKEN_EXPIRY", 30*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	refreshTTL, err := durationEnv("REFRESH_TOKEN_EXPIRY", 30*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := os.Getenv("JWT_SECRET")
+	secretKey := HMACKey(envOr("JWT_KEY_ID", "default"), []byte(secret))
+	var signing Key
+	var verification []Key
+	if path := os.Getenv("JWT_SIGNING_KEY_FILE"); path != "" {
+		signing, err = LoadKeyFile(os.Getenv("JWT_SIGNING_KEY_ID"), path)
+		if err != nil {
+			return nil, fmt.Errorf("JWT_SIGNING_KEY_FILE: %w", err)
+		}
+		if signing.signing == nil {
+			return nil, errors.New("JWT_SIGNING_KEY_FILE must hold a private key")
+		}
+		// Tokens signed with the shared secret before the switch, until they expire
+		if secret != "" && strings.EqualFold(os.Getenv("JWT_ACCEPT_HS256"), "true") {
+			verification = append(verification, secretKey)
+		}
+	} else {
+		if secret == "" {
+			return nil, errors.New("JWT_SECRET not set")
+		}
+		signing = secretKey
+	}
+
+	for _, entry := range splitList(os.Getenv("JWT_VERIFICATION_KEY_FILES")) {
+		kid, path, ok := strings.Cut(entry, "=")
+		if !ok {
+			kid, path = "", entry
+		}
+		key, err := LoadKeyFile(kid, path)
+		if err != nil {
+			return nil, fmt.Errorf("JWT_VERIFICATION_KEY_FILES: %w", err)
+		}
+		verification = append(verification, key)
+	}
+
+	for _, entry := range splitList(os.Getenv("JWT_PREVIOUS_KEYS")) {
+		kid, prevSecret, ok := strings.Cut(entry, ":")
+		if !ok || kid == "" || prevSecret == "" {
+			return nil, errors.New("JWT_PREVIOUS_KEYS entries must look like kid:secret")
+		}
+		verification = append(verification, HMACKey(kid, []byte(prevSecret)))
+	}
+
+	return New(Config{
+		Issuer:           envOr("JWT_ISSUER", "altoai_mvp"),
+		Audience:         envOr("JWT_AUDIENCE", "altoai_mvp"),
+		AccessTTL:        accessTTL,
+		RefreshTTL:       refreshTTL,
+		SigningKey:       signing,
+		VerificationKeys: verification,
+	})
+}
+
+func splitList(v string) []string {
+	var out []string
+	for _, entry := range strings.Split(v, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			out = append(out, entry)
+		}
+	}
+	return out
+}
+
+func durationEnv(key string, fallback time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s: %q is not a positive duration like 30m or 720h", key, v)
+	}
+	return d, nil
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// IssueAccess signs an access token carrying c and returns it with its expiry
+func (s *Service) IssueAccess(c Claims) (string, time.Time, error) {
+	return s.issue(TypeAccess, s.cfg.AccessTTL, c)
+}
+
+// IssueRefresh signs a refresh token carrying c and returns it with its expiry
+func (s *Service) IssueRefresh(c Claims) (string, time.Time, error) {
+	return s.issue(TypeRefresh, s.cfg.RefreshTTL, c)
+}
+
+// IssueMagicLink signs a passwordless sign-in token carrying c that expires after ttl
+func (s *Service) IssueMagicLink(c Claims, ttl time.Duration) (string, time.Time, error) {
+	return s.issue(TypeMagicLink, ttl, c)
+}
+
+// IssueLoginCode signs an OAuth login code carrying c that expires after ttl
+func (s *Service) IssueLoginCode(c Claims, ttl time.Duration) (string, time.Time, error) {
+	return s.issue(TypeLoginCode, ttl, c)
+}
+
+func (s *Service) issue(typ string, ttl time.Duration, c Claims) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	c.Type = typ
+	c.Issuer = s.cfg.Issuer
+	if s.cfg.Audience != "" {
+		c.Audience = jwt.ClaimStrings{s.cfg.Audience}
+	}
+	c.IssuedAt = jwt.NewNumericDate(now)
+	c.ExpiresAt = jwt.NewNumericDate(expiresAt)
+
+	key := s.cfg.SigningKey
+	t := jwt.NewWithClaims(key.Method, c)
+	t.Header["kid"] = key.ID
+	signed, err := t.SignedString(key.signing)
+	return signed, expiresAt, err
+}
+
+// ParseAccess validates an access token and returns its claims
+func (s *Service) ParseAccess(raw string) (*Claims, error) {
+	return s.parse(raw, TypeAccess)
+}
+
+// ParseRefresh validates a refresh token and returns its claims
+func (s *Service) ParseRefresh(raw string) (*Claims, error) {
+	return s.parse(raw, TypeRefresh)
+}
+
+// IssueMFAChallenge signs a token for the second step of a login that expires after ttl
+func (s *Service) IssueMFAChallenge(c Claims, ttl time.Duration) (string, time.Time, error) {
+	return s.issue(TypeMFAChallenge, ttl, c)
+}
+
+// ParseMFAChallenge validates an MFA challenge token and returns its claims
+func (s *Service) ParseMFAChallenge(raw string) (*Claims, error) {
+	return s.parse(raw, TypeMFAChallenge)
+}
+
+// ParseMagicLink validates a sign-in link token and returns its claims
+func (s *Service) ParseMagicLink(raw string) (*Claims, error) {
+	return s.parse(raw, TypeMagicLink)
+}
+
+// ParseLoginCode validates an OAuth login code and returns its claims
+func (s *Service) ParseLoginCode(raw string) (*Claims, error) {
+	return s.parse(raw, TypeLoginCode)
+}
+
+// parse checks the signature against the key named by kid, pinning the algorithm to that
+// key's, then the issuer, audience, expiry and token type
+func (s *Service) parse(raw, typ string) (*Claims, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(s.algs),
+		jwt.WithIssuer(s.cfg.Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if s.cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(s.cfg.Audience))
+	}
+
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, ok := s.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+		if t.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("key %q does not sign with %s", kid, t.Method.Alg())
+		}
+		return key.verifying, nil
+	}, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.Type != typ {
+		return nil, ErrWrongType
+	}
+	return claims, nil
+}
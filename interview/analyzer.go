@@ -240,6 +240,9 @@ func (va *VisaAnalyzer) AnalyzeAnswerWithSession(session *Session, question, ans
 	// Add previous Q&A pairs from the session for context
 	// These messages don't repeat the rules, just the conversation
 	for _, prevAnswer := range session.Answers {
+		if prevAnswer.Skipped {
+			continue
+		}
 		sessionMessages = append(sessionMessages, GPTMessage{
 			Role:    "user",
 			Content: fmt.Sprintf("Question: %s\nStudent's Answer: %s", prevAnswer.QuestionText, prevAnswer.Text),
@@ -271,6 +274,9 @@ func (va *VisaAnalyzer) GetSessionMessages(session *Session) []GPTMessage {
 	}
 
 	for _, prevAnswer := range session.Answers {
+		if prevAnswer.Skipped {
+			continue
+		}
 		messages = append(messages, GPTMessage{
 			Role:    "user",
 			Content: fmt.Sprintf("Question: %s\nStudent's Answer: %s", prevAnswer.QuestionText, prevAnswer.Text),
@@ -0,0 +1,152 @@
+package interview
+
+import (
+	"errors"
+	"time"
+)
+
+// Attempt is one try at answering a question. An Answer keeps every attempt;
+// its top-level Text, Analysis and Eval mirror the counted one.
+type Attempt struct {
+	Text      string            `json:"text"`
+	CreatedAt time.Time         `json:"created_at"`
+	LatencyMs int64             `json:"latency_ms,omitempty"`
+	Late      bool              `json:"late,omitempty"`
+	Eval      *EvalResult       `json:"eval,omitempty"`
+	Analysis  *AnalysisResponse `json:"analysis,omitempty"`
+}
+
+// AttemptImprovement compares a retry with the attempt before it
+type AttemptImprovement struct {
+	QuestionID    string `json:"question_id"`
+	Attempt       int    `json:"attempt"`        // 1-based number of the new attempt
+	PreviousScore int    `json:"previous_score"` // TotalScore of the previous graded attempt, 0 if none
+	Score         int    `json:"score"`
+	Delta         int    `json:"delta"`
+	BestScore     int    `json:"best_score"`
+	Counted       bool   `json:"counted"` // whether the new attempt now counts toward Scores and the summary
+}
+
+// ErrQuestionNotAnswered is returned when retrying a question that has no answer yet
+var ErrQuestionNotAnswered = errors.New("question has not been answered yet")
+
+// AttemptFromAnswer snapshots an answer's fields as an attempt
+func AttemptFromAnswer(a Answer) Attempt {
+	return Attempt{
+		Text:      a.Text,
+		CreatedAt: a.CreatedAt,
+		LatencyMs: a.LatencyMs,
+		Late:      a.Late,
+		Eval:      a.Eval,
+		Analysis:  a.Analysis,
+	}
+}
+
+// FindAnswer returns the session's answer to questionID, or nil
+func FindAnswer(s *Session, questionID string) *Answer {
+	for i := range s.Answers {
+		if s.Answers[i].QuestionID == questionID {
+			return &s.Answers[i]
+		}
+	}
+	return nil
+}
+
+// FindAnswerByID returns the answer submitted with the client-supplied answerID, or nil
+func FindAnswerByID(s *Session, answerID string) *Answer {
+	if answerID == "" {
+		return nil
+	}
+	for i := range s.Answers {
+		if s.Answers[i].AnswerID == answerID {
+			return &s.Answers[i]
+		}
+	}
+	return nil
+}
+
+// SkipQuestion records the current question as skipped. Skipped questions are not graded
+// and do not count toward Scores or the summary until a retry answers them.
+func SkipQuestion(s *Session, q Question, now time.Time) {
+	s.Answers = append(s.Answers, Answer{
+		QuestionID:   q.ID,
+		QuestionText: q.Text,
+		CreatedAt:    now,
+		LatencyMs:    CheckTiming(s, now).Latency.Milliseconds(),
+		Skipped:      true,
+	})
+}
+
+// RecordRetry adds a new attempt to an answered question.
+//
+// The first graded attempt counts toward Scores and SessionSummary. A retry is kept for
+// practice only, unless replace is set or the question has no graded attempt yet (it was
+// skipped or grading failed); then the retry becomes the counted attempt and the session
+// scores are recomputed from it. In timed sessions a retry timed by CheckRetryTiming never
+// counts once the session deadline has passed, nor when late under LatePolicySkip.
+func RecordRetry(s *Session, questionID string, attempt Attempt, replace bool) (*AttemptImprovement, error) {
+	ans := FindAnswer(s, questionID)
+	if ans == nil {
+		return nil, ErrQuestionNotAnswered
+	}
+
+	// Answers recorded before attempts existed (or skipped ones) start their history here
+	if len(ans.Attempts) == 0 && !ans.Skipped {
+		ans.Attempts = append(ans.Attempts, AttemptFromAnswer(*ans))
+	}
+
+	previous := 0
+	best := 0
+	for _, a := range ans.Attempts {
+		if a.Analysis != nil {
+			previous = a.Analysis.Scores.TotalScore
+			if previous > best {
+				best = previous
+			}
+		}
+	}
+
+	ans.Attempts = append(ans.Attempts, attempt)
+
+	imp := &AttemptImprovement{
+		QuestionID:    questionID,
+		Attempt:       len(ans.Attempts),
+		PreviousScore: previous,
+		BestScore:     best,
+	}
+	if attempt.Analysis != nil {
+		imp.Score = attempt.Analysis.Scores.TotalScore
+		if imp.Score > imp.BestScore {
+			imp.BestScore = imp.Score
+		}
+		if previous > 0 {
+			imp.Delta = imp.Score - previous
+		}
+	}
+
+	if attempt.Analysis != nil && retryMayCount(s, attempt) && (replace || ans.Skipped || ans.Analysis == nil) {
+		RevertEval(s, ans.Eval)
+		ans.Text = attempt.Text
+		ans.Eval = attempt.Eval
+		ans.Analysis = attempt.Analysis
+		ans.LatencyMs = attempt.LatencyMs
+		ans.Late = attempt.Late
+		ans.Skipped = false
+		ans.CountedAttempt = len(ans.Attempts) - 1
+		ApplyEval(s, attempt.Eval)
+		imp.Counted = true
+	}
+
+	return imp, nil
+}
+
+// retryMayCount holds a retry in a timed session to the same deadlines as a first answer
+func retryMayCount(s *Session, a Attempt) bool {
+	if s.TimeLimits == nil {
+		return true
+	}
+	if s.SessionDeadline != nil && a.CreatedAt.After(s.SessionDeadline.Add(timingGrace)) {
+		return false
+	}
+	return !a.Late || s.TimeLimits.LatePolicy != LatePolicySkip
+}
@@ -32,6 +32,17 @@ func ApplyEval(s *Session, eval *EvalResult) {
 	s.Scores.OverallRisk += eval.ScoreDelta.OverallRisk
 }
 
+// RevertEval undoes ApplyEval, used when a revised attempt replaces a graded answer
+func RevertEval(s *Session, eval *EvalResult) {
+	if eval == nil {
+		return
+	}
+	s.Scores.Academic -= eval.ScoreDelta.Academic
+	s.Scores.Financial -= eval.ScoreDelta.Financial
+	s.Scores.IntentToReturn -= eval.ScoreDelta.IntentToReturn
+	s.Scores.OverallRisk -= eval.ScoreDelta.OverallRisk
+}
+
 // ApplyAnalysis applies an AnalysisResponse to the session
 // This converts the analysis to EvalResult and applies it, maintaining backward compatibility
 func ApplyAnalysis(s *Session, analysis *AnalysisResponse, q Question) {
@@ -78,5 +89,61 @@ func GenerateSessionSummary(s *Session) (*SessionSummary, error) {
 	}
 
 	summary.SessionID = s.ID
+	applyAnswerStats(summary, s)
 	return summary, nil
 }
+
+// applyAnswerStats adds response latency, late/skipped counts and retry gains to a summary
+func applyAnswerStats(summary *SessionSummary, s *Session) {
+	var totalLatency int64
+	timed := 0
+	totalGain := 0
+	for _, answer := range s.Answers {
+		if first, best, ok := attemptRange(answer); ok {
+			summary.RetriedQuestions++
+			totalGain += best - first
+		}
+		if answer.LatencyMs > 0 {
+			totalLatency += answer.LatencyMs
+			timed++
+		}
+		if answer.Late {
+			summary.LateAnswers++
+		}
+		if answer.Skipped {
+			summary.SkippedQuestions++
+		}
+	}
+	if timed > 0 {
+		summary.AverageLatencySeconds = float64(totalLatency) / float64(timed) / 1000
+	}
+	if summary.RetriedQuestions > 0 {
+		summary.AverageImprovement = float64(totalGain) / float64(summary.RetriedQuestions)
+	}
+
+	if s.TimeLimits != nil && summary.LateAnswers > 0 {
+		summary.WeakAreas = append(summary.WeakAreas, "Answering within the time limit")
+		summary.Recommendation += fmt.Sprintf(" You went over time on %d of %d answers; practise giving your main point in the first sentence.",
+			summary.LateAnswers, len(s.Answers))
+	}
+}
+
+// attemptRange returns the first and best graded TotalScore of a retried answer.
+// ok is false unless the answer has at least two graded attempts.
+func attemptRange(a Answer) (first, best int, ok bool) {
+	graded := 0
+	for _, attempt := range a.Attempts {
+		if attempt.Analysis == nil {
+			continue
+		}
+		score := attempt.Analysis.Scores.TotalScore
+		if graded == 0 {
+			first = score
+		}
+		if score > best {
+			best = score
+		}
+		graded++
+	}
+	return first, best, graded > 1
+}
@@ -59,6 +59,10 @@ func SubmitAnswerHandler(c *gin.Context) {
 		return
 	}
 
+	// Answers to one session are processed one at a time
+	unlock := LockSession(sessionID)
+	defer unlock()
+
 	s, ok := GetSession(sessionID)
 	if !ok {
 		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
@@ -0,0 +1,151 @@
+package interview
+
+import (
+	"math"
+	"time"
+)
+
+// Tuning for history-aware selection
+const (
+	// unseenWeight is the selection weight of a question the user has never been asked.
+	// Seen questions weigh at most 1, so unseen ones are strongly preferred.
+	unseenWeight = 4.0
+	// recencyWindow is how long after being asked a question regains its full weight
+	recencyWindow = 14 * 24 * time.Hour
+	// minWeight keeps every question selectable once a category runs out of fresh ones
+	minWeight = 0.05
+	// weakCategoryThreshold is the average TotalScore below which a category counts as weak
+	// (below "Good" on the 3–15 scale)
+	weakCategoryThreshold = 13.0
+)
+
+// QuestionExposure records how often and how well a user answered one question
+type QuestionExposure struct {
+	Category    string    `json:"category"`
+	Text        string    `json:"text"`
+	TimesAsked  int       `json:"times_asked"`
+	LastAskedAt time.Time `json:"last_asked_at"`
+	LastScore   int       `json:"last_score"` // TotalScore of the latest graded answer, 0 if never graded
+}
+
+// QuestionHistory is a user's question exposure derived from their stored sessions
+type QuestionHistory struct {
+	Questions        map[string]QuestionExposure `json:"questions"`         // keyed by QuestionKey
+	CategoryAverages map[string]float64          `json:"category_averages"` // average TotalScore per category
+	now              time.Time
+}
+
+// QuestionKey identifies a bank question across sessions.
+// Question IDs are positional ("q3_Purpose_of_Study"), so category and text are used instead.
+func QuestionKey(category, text string) string {
+	return category + "|" + text
+}
+
+// BuildQuestionHistory collects exposure data from every stored session of userID
+func BuildQuestionHistory(userID string) *QuestionHistory {
+	return BuildQuestionHistoryFromSessions(ListUserSessions(userID), time.Now())
+}
+
+// BuildQuestionHistoryFromSessions aggregates exposure data from the given sessions
+func BuildQuestionHistoryFromSessions(list []*Session, now time.Time) *QuestionHistory {
+	h := &QuestionHistory{
+		Questions:        make(map[string]QuestionExposure),
+		CategoryAverages: make(map[string]float64),
+		now:              now,
+	}
+
+	categoryTotals := make(map[string]int)
+	categoryCounts := make(map[string]int)
+
+	for _, s := range list {
+		categories := make(map[string]string, len(s.SelectedQuestions))
+		for _, q := range s.SelectedQuestions {
+			categories[q.ID] = q.Category
+		}
+
+		for _, ans := range s.Answers {
+			category, ok := categories[ans.QuestionID]
+			if !ok || isFixedQuestion(ans.QuestionID) {
+				continue
+			}
+
+			key := QuestionKey(category, ans.QuestionText)
+			exp := h.Questions[key]
+			exp.Category = category
+			exp.Text = ans.QuestionText
+			exp.TimesAsked++
+			if !ans.CreatedAt.Before(exp.LastAskedAt) {
+				exp.LastAskedAt = ans.CreatedAt
+				if ans.Analysis != nil {
+					exp.LastScore = ans.Analysis.Scores.TotalScore
+				}
+			}
+			h.Questions[key] = exp
+
+			if ans.Analysis != nil && ans.Analysis.Scores.TotalScore > 0 {
+				categoryTotals[category] += ans.Analysis.Scores.TotalScore
+				categoryCounts[category]++
+			}
+		}
+	}
+
+	for category, total := range categoryTotals {
+		h.CategoryAverages[category] = float64(total) / float64(categoryCounts[category])
+	}
+
+	return h
+}
+
+// isFixedQuestion reports whether id is one of the college/major questions every session opens with
+func isFixedQuestion(id string) bool {
+	return id == "q0_college" || id == "q0_major"
+}
+
+// Weight returns the selection weight of a question for this history.
+// A nil history weighs every question equally.
+func (h *QuestionHistory) Weight(category, text string) float64 {
+	if h == nil {
+		return 1
+	}
+
+	exp, ok := h.Questions[QuestionKey(category, text)]
+	if !ok || exp.TimesAsked == 0 {
+		return unseenWeight
+	}
+
+	// Recently asked questions are suppressed, recovering linearly over recencyWindow,
+	// except poorly answered ones in the user's weakest category, which are worth retrying now
+	recency := float64(h.now.Sub(exp.LastAskedAt)) / float64(recencyWindow)
+	recency = math.Min(math.Max(recency, 0), 1)
+	if exp.LastScore > 0 && float64(exp.LastScore) < weakCategoryThreshold && category == h.WeakestCategory() {
+		recency = 1
+	}
+
+	// Poorly answered questions come back sooner: 1.0 at score 3, 0.2 at score 15
+	quality := 0.6
+	if exp.LastScore > 0 {
+		quality = 0.2 + 0.8*float64(15-exp.LastScore)/12
+	}
+
+	return math.Max(recency*quality, minWeight)
+}
+
+// WeakestCategory returns the category with the lowest average score below
+// weakCategoryThreshold, or "" when there is none (or no history)
+func (h *QuestionHistory) WeakestCategory() string {
+	if h == nil {
+		return ""
+	}
+
+	weakest := ""
+	lowest := weakCategoryThreshold
+	// Iterate in CategoryOrder so ties resolve deterministically
+	for _, category := range CategoryOrder {
+		avg, ok := h.CategoryAverages[category]
+		if ok && avg < lowest {
+			weakest = category
+			lowest = avg
+		}
+	}
+	return weakest
+}
@@ -0,0 +1,65 @@
+package interview
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// DefaultIdempotencyTTL is how long a stored response answers retries of the same key.
+// Override with IDEMPOTENCY_KEY_TTL (e.g. "1h").
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// ErrIdempotencyKeyReused is returned when a key comes back with a different request body
+var ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different request")
+
+// IdempotentResponse is the stored reply to a request that carried an idempotency key
+type IdempotentResponse struct {
+	RequestHash string          `json:"request_hash"` // sha256 of the original request body
+	Body        json.RawMessage `json:"body"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// IdempotencyTTL returns the configured lifetime of idempotency keys
+func IdempotencyTTL() time.Duration {
+	return durationFromEnv("IDEMPOTENCY_KEY_TTL", DefaultIdempotencyTTL)
+}
+
+// RequestHash fingerprints a request body so a reused key can be told apart from a retry
+func RequestHash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// StoredResponse returns the response recorded for key if it has not expired.
+// A key recorded for a different request body returns ErrIdempotencyKeyReused.
+func StoredResponse(s *Session, key, requestHash string, now time.Time) (json.RawMessage, error) {
+	stored, ok := s.IdempotencyKeys[key]
+	if key == "" || !ok || now.Sub(stored.CreatedAt) > IdempotencyTTL() {
+		return nil, nil
+	}
+	if stored.RequestHash != requestHash {
+		return nil, ErrIdempotencyKeyReused
+	}
+	return stored.Body, nil
+}
+
+// RememberResponse stores body as the response to key and drops expired keys.
+// Save the session afterwards so the response is kept with it.
+func RememberResponse(s *Session, key, requestHash string, body []byte, now time.Time) {
+	if key == "" {
+		return
+	}
+	ttl := IdempotencyTTL()
+	for k, stored := range s.IdempotencyKeys {
+		if now.Sub(stored.CreatedAt) > ttl {
+			delete(s.IdempotencyKeys, k)
+		}
+	}
+	if s.IdempotencyKeys == nil {
+		s.IdempotencyKeys = make(map[string]IdempotentResponse)
+	}
+	s.IdempotencyKeys[key] = IdempotentResponse{RequestHash: requestHash, Body: body, CreatedAt: now}
+}
@@ -0,0 +1,170 @@
+package interview
+
+import (
+	"context"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SessionArchive is durable storage for closed (finished or aborted) sessions
+type SessionArchive interface {
+	ArchiveSession(s *Session) error
+	GetArchivedSession(id string) (*Session, error)
+	ListArchivedSessions(userID string) ([]*Session, error)
+}
+
+var (
+	archive   SessionArchive
+	archiveMu sync.RWMutex
+)
+
+// SetSessionArchive sets where the janitor archives closed sessions before evicting them
+func SetSessionArchive(a SessionArchive) {
+	archiveMu.Lock()
+	defer archiveMu.Unlock()
+	archive = a
+}
+
+func getSessionArchive() SessionArchive {
+	archiveMu.RLock()
+	defer archiveMu.RUnlock()
+	return archive
+}
+
+// JanitorConfig controls the session store sweep
+type JanitorConfig struct {
+	Interval  time.Duration // time between sweeps (SESSION_JANITOR_INTERVAL, default 5m)
+	IdleTTL   time.Duration // open sessions untouched this long are aborted (SESSION_IDLE_TIMEOUT)
+	RetainTTL time.Duration // closed sessions stay in memory this long before eviction (SESSION_RETAIN_TTL, default 1h)
+}
+
+// JanitorConfigFromEnv reads the janitor configuration from the environment
+func JanitorConfigFromEnv() JanitorConfig {
+	return JanitorConfig{
+		Interval:  durationFromEnv("SESSION_JANITOR_INTERVAL", 5*time.Minute),
+		IdleTTL:   IdleTimeout(),
+		RetainTTL: durationFromEnv("SESSION_RETAIN_TTL", time.Hour),
+	}
+}
+
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return fallback
+}
+
+// JanitorStats counts what the janitor did, either in one sweep or since startup
+type JanitorStats struct {
+	Sweeps        uint64 `json:"sweeps"`
+	Expired       uint64 `json:"expired"`        // open sessions aborted for inactivity
+	Archived      uint64 `json:"archived"`       // closed sessions written to the archive
+	Evicted       uint64 `json:"evicted"`        // sessions removed from memory
+	ArchiveErrors uint64 `json:"archive_errors"` // archive failures; those sessions stay in memory
+}
+
+var janitorTotals struct {
+	sweeps, expired, archived, evicted, archiveErrors atomic.Uint64
+}
+
+// JanitorMetrics returns the janitor's cumulative counters
+func JanitorMetrics() JanitorStats {
+	return JanitorStats{
+		Sweeps:        janitorTotals.sweeps.Load(),
+		Expired:       janitorTotals.expired.Load(),
+		Archived:      janitorTotals.archived.Load(),
+		Evicted:       janitorTotals.evicted.Load(),
+		ArchiveErrors: janitorTotals.archiveErrors.Load(),
+	}
+}
+
+// SessionCount returns how many sessions are held in memory
+func SessionCount() int {
+	sessionsMu.RLock()
+	defer sessionsMu.RUnlock()
+	return len(sessions)
+}
+
+// RunJanitor sweeps the session store every cfg.Interval until ctx is cancelled
+func RunJanitor(ctx context.Context, cfg JanitorConfig) {
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			stats := SweepSessions(now, cfg)
+			if stats.Expired+stats.Evicted+stats.ArchiveErrors > 0 {
+				log.Printf("session janitor: expired=%d archived=%d evicted=%d archive_errors=%d live=%d",
+					stats.Expired, stats.Archived, stats.Evicted, stats.ArchiveErrors, SessionCount())
+			}
+		}
+	}
+}
+
+// SweepSessions aborts idle open sessions, archives closed sessions older than RetainTTL
+// and evicts them from memory. Archiving happens outside the store lock; a session that
+// was saved again in the meantime (its Version moved on) is kept for the next sweep.
+func SweepSessions(now time.Time, cfg JanitorConfig) JanitorStats {
+	stats := JanitorStats{Sweeps: 1}
+
+	type candidate struct {
+		session *Session // copy taken under the lock
+		version int64
+	}
+	var closed []candidate
+
+	sessionsMu.Lock()
+	for _, s := range sessions {
+		if ExpireIfIdle(s, now, cfg.IdleTTL) {
+			// Bump the version so a request still holding the open session can't save over the abort
+			s.Version++
+			s.UpdatedAt = now
+			stats.Expired++
+		}
+		if !IsOpen(s) && now.Sub(s.UpdatedAt) > cfg.RetainTTL {
+			closed = append(closed, candidate{session: cloneSession(s), version: s.Version})
+		}
+	}
+	sessionsMu.Unlock()
+
+	a := getSessionArchive()
+	for _, c := range closed {
+		if a != nil {
+			if err := a.ArchiveSession(c.session); err != nil {
+				log.Printf("session janitor: archive %s: %v", c.session.ID, err)
+				stats.ArchiveErrors++
+				continue
+			}
+			stats.Archived++
+		}
+
+		sessionsMu.Lock()
+		if cur, ok := sessions[c.session.ID]; ok && cur.Version == c.version {
+			delete(sessions, c.session.ID)
+			stats.Evicted++
+		}
+		sessionsMu.Unlock()
+	}
+
+	janitorTotals.sweeps.Add(stats.Sweeps)
+	janitorTotals.expired.Add(stats.Expired)
+	janitorTotals.archived.Add(stats.Archived)
+	janitorTotals.evicted.Add(stats.Evicted)
+	janitorTotals.archiveErrors.Add(stats.ArchiveErrors)
+	return stats
+}
+
+// UserSessionHistory returns every session of userID, in memory or archived, newest first
+func UserSessionHistory(userID string) []*Session {
+	list := ListUserSessions(userID)
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	return list
+}
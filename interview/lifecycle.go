@@ -0,0 +1,100 @@
+package interview
+
+import (
+	"errors"
+	"time"
+)
+
+// DefaultIdleTimeout is how long an untouched active or paused session survives
+// before it is aborted. Override with SESSION_IDLE_TIMEOUT (e.g. "12h").
+const DefaultIdleTimeout = 24 * time.Hour
+
+var (
+	// ErrSessionNotActive is returned when pausing a session that is not active
+	ErrSessionNotActive = errors.New("session is not active")
+	// ErrSessionNotResumable is returned when resuming a session that is closed
+	ErrSessionNotResumable = errors.New("session is finished or aborted")
+)
+
+// IdleTimeout returns the configured idle timeout for abandoned sessions
+func IdleTimeout() time.Duration {
+	return durationFromEnv("SESSION_IDLE_TIMEOUT", DefaultIdleTimeout)
+}
+
+// IsOpen reports whether the session can still be continued
+func IsOpen(s *Session) bool {
+	return s.Status == SessionStatusActive || s.Status == SessionStatusPaused
+}
+
+// ExpireIfIdle aborts an open session that has not been touched for longer than idle.
+// It reports whether the session was aborted.
+func ExpireIfIdle(s *Session, now time.Time, idle time.Duration) bool {
+	if !IsOpen(s) || now.Sub(s.UpdatedAt) <= idle {
+		return false
+	}
+	s.Status = SessionStatusAborted
+	s.PausedAt = nil
+	return true
+}
+
+// PauseSession stops the clock on an active session
+func PauseSession(s *Session, now time.Time) error {
+	if s.Status == SessionStatusPaused {
+		return nil
+	}
+	if s.Status != SessionStatusActive {
+		return ErrSessionNotActive
+	}
+	s.Status = SessionStatusPaused
+	s.PausedAt = &now
+	return nil
+}
+
+// ResumeSession reactivates a paused session. The question and session deadlines, and
+// when the question was shown, move back by the time spent paused, so the session gets
+// back exactly the time it had left when paused.
+func ResumeSession(s *Session, now time.Time) error {
+	if s.Status == SessionStatusActive {
+		return nil
+	}
+	if s.Status != SessionStatusPaused {
+		return ErrSessionNotResumable
+	}
+
+	if s.PausedAt != nil {
+		paused := now.Sub(*s.PausedAt)
+		if !s.QuestionAskedAt.IsZero() {
+			s.QuestionAskedAt = s.QuestionAskedAt.Add(paused)
+		}
+		s.QuestionDeadline = shiftDeadline(s.QuestionDeadline, paused)
+		s.SessionDeadline = shiftDeadline(s.SessionDeadline, paused)
+	}
+	s.Status = SessionStatusActive
+	s.PausedAt = nil
+	return nil
+}
+
+func shiftDeadline(deadline *time.Time, by time.Duration) *time.Time {
+	if deadline == nil {
+		return nil
+	}
+	shifted := deadline.Add(by)
+	return &shifted
+}
+
+// LatestOpenSession returns the user's most recently updated active or paused session.
+// Sessions that have sat idle past the timeout are aborted on the way.
+func LatestOpenSession(userID string, now time.Time) (*Session, bool) {
+	idle := IdleTimeout()
+	var latest *Session
+	for _, s := range ListUserSessions(userID) {
+		if ExpireIfIdle(s, now, idle) {
+			SaveSession(s)
+			continue
+		}
+		if IsOpen(s) && (latest == nil || s.UpdatedAt.After(latest.UpdatedAt)) {
+			latest = s
+		}
+	}
+	return latest, latest != nil
+}
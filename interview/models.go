@@ -18,10 +18,19 @@ type Answer struct {
 	QuestionText string    `json:"question_text"`
 	Text         string    `json:"text"`
 	CreatedAt    time.Time `json:"created_at"`
+	// Client-supplied ID; resubmitting the same answer ID returns the recorded result
+	AnswerID string `json:"answer_id,omitempty"`
+	// Time from the question being shown to the answer arriving, measured server-side
+	LatencyMs int64 `json:"latency_ms,omitempty"`
+	Late      bool  `json:"late,omitempty"`    // submitted after the question deadline (timed mode)
+	Skipped   bool  `json:"skipped,omitempty"` // not graded; excluded from scores and summary
 	// Optional: store AI eval snapshot per answer for analytics
 	Eval *EvalResult `json:"eval,omitempty"`
 	// New grading system analysis
 	Analysis *AnalysisResponse `json:"analysis,omitempty"`
+	// Every attempt at this question, oldest first; the fields above mirror Attempts[CountedAttempt]
+	Attempts       []Attempt `json:"attempts,omitempty"`
+	CountedAttempt int       `json:"counted_attempt"`
 }
 
 // Scores are cumulative across the entire session.
@@ -32,29 +41,54 @@ type Scores struct {
 	OverallRisk    int `json:"overall_risk"`
 }
 
-// SessionStatus allows you to extend states later (paused, aborted, etc.).
+// SessionStatus is the lifecycle state of a session.
+// Active and paused sessions can be resumed; finished and aborted ones are closed.
 type SessionStatus string
 
 const (
 	SessionStatusActive   SessionStatus = "active"
+	SessionStatusPaused   SessionStatus = "paused"
 	SessionStatusFinished SessionStatus = "finished"
-	SessionStatusAborted  SessionStatus = "aborted"
+	SessionStatusAborted  SessionStatus = "aborted" // abandoned past the idle timeout
+)
+
+// SessionType distinguishes full mock interviews from spaced-repetition drills.
+type SessionType string
+
+const (
+	SessionTypeMock     SessionType = "mock"
+	SessionTypePractice SessionType = "practice"
 )
 
 // Session holds the state of one full interview attempt.
 type Session struct {
 	ID                string        `json:"id"`
-	UserID            string        `json:"user_id,omitempty"`  // if you later have accounts
+	UserID            string        `json:"user_id,omitempty"` // if you later have accounts
+	Type              SessionType   `json:"type"`
 	CurrentQuestion   string        `json:"current_question"`   // question ID
 	SelectedQuestions []Question    `json:"selected_questions"` // questions selected for this session
 	QuestionIndex     int           `json:"question_index"`     // current question index in SelectedQuestions
+	Seed              *int64        `json:"seed,omitempty"`     // RNG seed used to select SelectedQuestions; nil when it can't replay them
+	BankVersion       string        `json:"bank_version"`       // QuestionBankVersion at selection time
 	Answers           []Answer      `json:"answers"`
 	Scores            Scores        `json:"scores"`
 	Status            SessionStatus `json:"status"`
 	CreatedAt         time.Time     `json:"created_at"`
 	UpdatedAt         time.Time     `json:"updated_at"`
+	// Incremented by every successful SaveSession; a save from an older version is rejected
+	Version int64 `json:"version"`
+	// Set when the session follows a cohort's assigned practice
+	CohortID string `json:"cohort_id,omitempty"`
 	// Session summary for completed interviews
 	Summary *SessionSummary `json:"summary,omitempty"`
+	// Timed mode; nil for untimed sessions. Deadlines are enforced when answers arrive.
+	TimeLimits       *TimeLimits `json:"time_limits,omitempty"`
+	QuestionAskedAt  time.Time   `json:"question_asked_at"`
+	QuestionDeadline *time.Time  `json:"question_deadline,omitempty"`
+	SessionDeadline  *time.Time  `json:"session_deadline,omitempty"`
+	PausedAt         *time.Time  `json:"paused_at,omitempty"`
+	// Responses to requests sent with an idempotency key, replayed when the key is retried
+	IdempotencyKeys map[string]IdempotentResponse `json:"idempotency_keys,omitempty"`
 }
 
 // AnalysisScores represents the 3–15 grading system for a single answer
@@ -107,4 +141,11 @@ type SessionSummary struct {
 	CommonRedFlags []string  `json:"commonRedFlags"`
 	Recommendation string    `json:"recommendation"`
 	CompletedAt    time.Time `json:"completedAt"`
+	// Response latency across all answers, including skipped ones
+	AverageLatencySeconds float64 `json:"averageLatencySeconds"`
+	LateAnswers           int     `json:"lateAnswers"`
+	SkippedQuestions      int     `json:"skippedQuestions"`
+	// Questions answered more than once, and the average gain from first to best graded attempt
+	RetriedQuestions   int     `json:"retriedQuestions"`
+	AverageImprovement float64 `json:"averageImprovement"`
 }
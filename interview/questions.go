@@ -1,17 +1,24 @@
 package interview
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"math"
 	"math/rand"
 	"os"
 	"path/filepath"
-	"time"
+	"sort"
 )
 
 // QuestionsByCategory stores questions organized by category
 var QuestionsByCategory map[string][]string
 
+// QuestionBankVersion identifies the loaded questions file (short sha256 of its contents).
+// A seed only reproduces a session when it is replayed against the same bank version.
+var QuestionBankVersion string
+
 // InitQuestions tries to load questions from the questions.json file
 // It tries multiple possible paths to find the file
 func InitQuestions() error {
@@ -94,6 +101,9 @@ func LoadQuestions(path string) error {
 		QuestionsByCategory[category] = questions
 	}
 
+	sum := sha256.Sum256(data)
+	QuestionBankVersion = hex.EncodeToString(sum[:])[:12]
+
 	// Validate that all required categories exist
 	for category := range QuestionSelectionRules {
 		if _, ok := QuestionsByCategory[category]; !ok {
@@ -104,12 +114,32 @@ func LoadQuestions(path string) error {
 	return nil
 }
 
-// SelectQuestionsForSession selects questions according to the rules
+// NewSeed returns a random seed for question selection
+// The global math/rand source is safe for concurrent use and seeded automatically
+func NewSeed() int64 {
+	return rand.Int63()
+}
+
+// SelectQuestionsForSession selects questions according to the rules using a fresh random seed
 // level can be "easy", "medium", "hard", or "" for default
 // Always includes college and major questions at the start
 func SelectQuestionsForSession(level string) []Question {
+	return SelectQuestionsWithSeed(level, NewSeed())
+}
+
+// SelectQuestionsWithSeed selects questions like SelectQuestionsForSession but draws from
+// an RNG seeded with seed, so the same seed and QuestionBankVersion always yield the same questions
+func SelectQuestionsWithSeed(level string, seed int64) []Question {
+	return SelectQuestionsForUser(level, seed, nil)
+}
+
+// SelectQuestionsForUser selects questions for a level, weighting each category's pool by the
+// user's history: unseen questions first, then ones answered long ago or poorly. The level's
+// question counts never change; in the weakest category (by past AnalysisScores) poorly answered
+// questions come back sooner. A nil history means uniform selection.
+func SelectQuestionsForUser(level string, seed int64, history *QuestionHistory) []Question {
 	var selectedQuestions []Question
-	rand.Seed(time.Now().UnixNano())
+	rng := rand.New(rand.NewSource(seed))
 
 	// Always add college and major questions first
 	selectedQuestions = append(selectedQuestions, Question{
@@ -123,117 +153,97 @@ func SelectQuestionsForSession(level string) []Question {
 		Text:     "What is your major?",
 	})
 
-	// For easy level, select exactly 1 question from each of 4 specific categories
-	if level == "easy" {
-		easyCategories := []string{
-			"Purpose of Study",
-			"University Choice",
-			"Financial Capability",
-			"Post-Graduation Plans",
+	for _, quota := range levelPlan(level) {
+		questions, ok := QuestionsByCategory[quota.Category]
+		if !ok || len(questions) == 0 {
+			continue
 		}
 
-		for _, category := range easyCategories {
-			questions, ok := QuestionsByCategory[category]
-			if !ok || len(questions) == 0 {
-				continue
-			}
-
-			// Select one random question from this category
-			available := make([]string, len(questions))
-			copy(available, questions)
-			
-			// Shuffle and take 1 question
-			rand.Shuffle(len(available), func(i, j int) {
-				available[i], available[j] = available[j], available[i]
-			})
-
-			questionID := fmt.Sprintf("q%d_%s", len(selectedQuestions)+1, sanitizeCategory(category))
+		for _, text := range pickWeighted(rng, quota.Category, questions, quota.Count, history) {
+			questionID := fmt.Sprintf("q%d_%s", len(selectedQuestions)+1, sanitizeCategory(quota.Category))
 			selectedQuestions = append(selectedQuestions, Question{
 				ID:       questionID,
-				Category: category,
-				Text:     available[0],
+				Category: quota.Category,
+				Text:     text,
 			})
 		}
-
-		return selectedQuestions
 	}
 
-	// For medium level, select exactly 1 question from each of all 7 categories
-	if level == "medium" {
-		mediumCategories := []string{
+	return selectedQuestions
+}
+
+// categoryQuota is how many questions to draw from one category
+type categoryQuota struct {
+	Category string
+	Count    int
+}
+
+// levelPlan returns the categories (in asking order) and counts for a level
+func levelPlan(level string) []categoryQuota {
+	var plan []categoryQuota
+	switch level {
+	case "easy":
+		// Exactly 1 question from each of 4 specific categories
+		for _, category := range []string{
 			"Purpose of Study",
-			"Academic Background",
 			"University Choice",
 			"Financial Capability",
-			"Family/Sponsor Info",
 			"Post-Graduation Plans",
-			"Immigration Intent",
+		} {
+			plan = append(plan, categoryQuota{Category: category, Count: 1})
 		}
-
-		for _, category := range mediumCategories {
-			questions, ok := QuestionsByCategory[category]
-			if !ok || len(questions) == 0 {
-				continue
+	case "medium":
+		// Exactly 1 question from each of all 7 categories
+		for _, category := range CategoryOrder {
+			plan = append(plan, categoryQuota{Category: category, Count: 1})
+		}
+	default:
+		// Hard level or empty level (12 questions total)
+		for _, category := range CategoryOrder {
+			if count, ok := QuestionSelectionRules[category]; ok {
+				plan = append(plan, categoryQuota{Category: category, Count: count})
 			}
-
-			// Select one random question from this category
-			available := make([]string, len(questions))
-			copy(available, questions)
-			
-			// Shuffle and take 1 question
-			rand.Shuffle(len(available), func(i, j int) {
-				available[i], available[j] = available[j], available[i]
-			})
-
-			questionID := fmt.Sprintf("q%d_%s", len(selectedQuestions)+1, sanitizeCategory(category))
-			selectedQuestions = append(selectedQuestions, Question{
-				ID:       questionID,
-				Category: category,
-				Text:     available[0],
-			})
 		}
-
-		return selectedQuestions
 	}
+	return plan
+}
 
-	// Default behavior for hard level or empty level (12 questions total)
-	for _, category := range CategoryOrder {
-		count, ok := QuestionSelectionRules[category]
-		if !ok {
-			continue
-		}
-
-		questions, ok := QuestionsByCategory[category]
-		if !ok || len(questions) == 0 {
-			continue
-		}
-
-		// Select random questions from this category
+// pickWeighted draws up to n distinct questions using weighted sampling without replacement
+// (Efraimidis-Spirakis: highest u^(1/w) wins), so the draw is fully determined by rng.
+// Without history it shuffles and takes the first n, as seeded sessions have always done,
+// so a stored seed keeps replaying the same questions.
+func pickWeighted(rng *rand.Rand, category string, questions []string, n int, history *QuestionHistory) []string {
+	if n > len(questions) {
+		n = len(questions)
+	}
+	if history == nil {
 		available := make([]string, len(questions))
 		copy(available, questions)
-		
-		// Shuffle and take the required count
-		rand.Shuffle(len(available), func(i, j int) {
+		rng.Shuffle(len(available), func(i, j int) {
 			available[i], available[j] = available[j], available[i]
 		})
+		return available[:n]
+	}
 
-		// Take up to 'count' questions
-		toTake := count
-		if toTake > len(available) {
-			toTake = len(available)
-		}
+	type candidate struct {
+		text string
+		key  float64
+	}
 
-		for i := 0; i < toTake; i++ {
-			questionID := fmt.Sprintf("q%d_%s", len(selectedQuestions)+1, sanitizeCategory(category))
-			selectedQuestions = append(selectedQuestions, Question{
-				ID:       questionID,
-				Category: category,
-				Text:     available[i],
-			})
-		}
+	candidates := make([]candidate, len(questions))
+	for i, text := range questions {
+		w := history.Weight(category, text)
+		candidates[i] = candidate{text: text, key: math.Pow(rng.Float64(), 1/w)}
 	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].key > candidates[j].key
+	})
 
-	return selectedQuestions
+	out := make([]string, n)
+	for i := 0; i < n; i++ {
+		out[i] = candidates[i].text
+	}
+	return out
 }
 
 // sanitizeCategory converts category name to a valid ID suffix
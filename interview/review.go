@@ -0,0 +1,187 @@
+package interview
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ReviewItem is one question's spaced-repetition schedule for a user (SM-2)
+type ReviewItem struct {
+	UserID      string    `json:"user_id"`
+	Category    string    `json:"category"`
+	Text        string    `json:"text"`
+	EaseFactor  float64   `json:"ease_factor"`
+	Repetitions int       `json:"repetitions"`   // consecutive successful reviews
+	Interval    int       `json:"interval_days"` // days until the next review
+	DueAt       time.Time `json:"due_at"`
+	LastScore   int       `json:"last_score"` // TotalScore 3–15 of the latest graded answer
+	ReviewedAt  time.Time `json:"reviewed_at"`
+}
+
+const (
+	defaultEaseFactor = 2.5
+	minEaseFactor     = 1.3
+	// DefaultPracticeSize is how many due questions a drill session asks
+	DefaultPracticeSize = 5
+)
+
+// ReviewStore is durable storage for users' review schedules, one item per question
+type ReviewStore interface {
+	ListReviews(userID string) ([]ReviewItem, error)
+	// SaveReview inserts or replaces the item for its user, category and text
+	SaveReview(item ReviewItem) error
+}
+
+var (
+	reviewStore   ReviewStore
+	reviewStoreMu sync.RWMutex
+	// reviewsMu serialises RecordReview's read-modify-write of an item
+	reviewsMu sync.Mutex
+)
+
+// SetReviewStore sets where review schedules are kept. Without one, answers aren't scheduled.
+func SetReviewStore(s ReviewStore) {
+	reviewStoreMu.Lock()
+	defer reviewStoreMu.Unlock()
+	reviewStore = s
+}
+
+func getReviewStore() ReviewStore {
+	reviewStoreMu.RLock()
+	defer reviewStoreMu.RUnlock()
+	return reviewStore
+}
+
+// reviewQuality maps a 3–15 TotalScore onto SM-2's 0–5 recall quality
+func reviewQuality(totalScore int) int {
+	if totalScore < 3 {
+		totalScore = 3
+	}
+	if totalScore > 15 {
+		totalScore = 15
+	}
+	return int(math.Round(float64(totalScore-3) * 5 / 12))
+}
+
+// RecordReview updates the user's schedule for a question after a graded answer.
+// Answers below quality 3 (TotalScore under ~10) reset the question to be reviewed tomorrow.
+func RecordReview(userID string, q Question, totalScore int, at time.Time) (*ReviewItem, error) {
+	store := getReviewStore()
+	if store == nil || userID == "" || isFixedQuestion(q.ID) || totalScore == 0 {
+		return nil, nil
+	}
+
+	reviewsMu.Lock()
+	defer reviewsMu.Unlock()
+
+	items, err := store.ListReviews(userID)
+	if err != nil {
+		return nil, err
+	}
+	item := &ReviewItem{
+		UserID:     userID,
+		Category:   q.Category,
+		Text:       q.Text,
+		EaseFactor: defaultEaseFactor,
+	}
+	key := QuestionKey(q.Category, q.Text)
+	for i := range items {
+		if QuestionKey(items[i].Category, items[i].Text) == key {
+			item = &items[i]
+			break
+		}
+	}
+
+	quality := reviewQuality(totalScore)
+	if quality < 3 {
+		item.Repetitions = 0
+		item.Interval = 1
+	} else {
+		switch item.Repetitions {
+		case 0:
+			item.Interval = 1
+		case 1:
+			item.Interval = 6
+		default:
+			item.Interval = int(math.Round(float64(item.Interval) * item.EaseFactor))
+		}
+		item.Repetitions++
+	}
+
+	diff := float64(5 - quality)
+	item.EaseFactor += 0.1 - diff*(0.08+diff*0.02)
+	if item.EaseFactor < minEaseFactor {
+		item.EaseFactor = minEaseFactor
+	}
+
+	item.LastScore = totalScore
+	item.ReviewedAt = at
+	item.DueAt = at.AddDate(0, 0, item.Interval)
+
+	if err := store.SaveReview(*item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// DueReviews returns the user's questions due today, that is before the end of now's day in
+// now's location, most overdue and weakest first
+func DueReviews(userID string, now time.Time) ([]ReviewItem, error) {
+	store := getReviewStore()
+	if store == nil || userID == "" {
+		return nil, nil
+	}
+	items, err := store.ListReviews(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	endOfDay := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+	var due []ReviewItem
+	for _, item := range items {
+		if item.DueAt.Before(endOfDay) {
+			due = append(due, item)
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].DueAt.Equal(due[j].DueAt) {
+			return due[i].DueAt.Before(due[j].DueAt)
+		}
+		if due[i].LastScore != due[j].LastScore {
+			return due[i].LastScore < due[j].LastScore
+		}
+		return QuestionKey(due[i].Category, due[i].Text) < QuestionKey(due[j].Category, due[j].Text)
+	})
+	return due, nil
+}
+
+// NewPracticeSession builds a drill session from up to size questions due for review.
+// It returns a nil session when nothing is due.
+func NewPracticeSession(userID string, size int, now time.Time) (*Session, error) {
+	if size <= 0 {
+		size = DefaultPracticeSize
+	}
+
+	due, err := DueReviews(userID, now)
+	if err != nil || len(due) == 0 {
+		return nil, err
+	}
+	if len(due) > size {
+		due = due[:size]
+	}
+
+	questions := make([]Question, 0, len(due))
+	for i, item := range due {
+		questions = append(questions, Question{
+			ID:       fmt.Sprintf("p%d_%s", i+1, sanitizeCategory(item.Category)),
+			Category: item.Category,
+			Text:     item.Text,
+		})
+	}
+
+	return NewSessionWithOptions(userID, SessionOptions{Type: SessionTypePractice, Questions: questions}), nil
+}
@@ -1,6 +1,9 @@
 package interview
 
 import (
+	"errors"
+	"log"
+	"maps"
 	"sync"
 	"time"
 
@@ -10,48 +13,234 @@ import (
 var (
 	sessions   = make(map[string]*Session)
 	sessionsMu sync.RWMutex
+
+	sessionLocks   = make(map[string]*sessionLock)
+	sessionLocksMu sync.Mutex
 )
 
+// ErrSessionConflict is returned by SaveSession when the stored session was saved by
+// someone else after the caller read it
+var ErrSessionConflict = errors.New("session was modified by another request")
+
+// sessionLock serialises requests for one session; refs lets idle locks be dropped
+type sessionLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// SessionOptions controls how a new session is built
+type SessionOptions struct {
+	Type  SessionType // defaults to SessionTypeMock
+	Level string      // "easy", "medium", "hard" or "" for default
+	// Questions, when set, are asked as-is instead of selecting from the bank
+	Questions []Question
+	// Seed pins question selection; nil picks a random seed.
+	// The seed is stored on the session so it can be replayed later.
+	Seed *int64
+	// History makes selection prefer questions the user has not practised recently.
+	// Leave nil for shared drills: a seed only reproduces a session without history, so
+	// history-weighted sessions don't record one.
+	History *QuestionHistory
+	// TimeLimits enables timed mode; zero fields use the defaults
+	TimeLimits *TimeLimits
+	// CohortID records the cohort whose assigned practice the session follows
+	CohortID string
+}
+
 func NewSession(userID string) *Session {
 	return NewSessionWithLevel(userID, "")
 }
 
 func NewSessionWithLevel(userID string, level string) *Session {
+	return NewSessionWithOptions(userID, SessionOptions{Level: level})
+}
+
+func NewSessionWithOptions(userID string, opts SessionOptions) *Session {
 	now := time.Now()
-	
+
+	seed := NewSeed()
+	if opts.Seed != nil {
+		seed = *opts.Seed
+	}
+
 	// Select questions for this session based on level
-	selectedQuestions := SelectQuestionsForSession(level)
-	
+	selectedQuestions := opts.Questions
+	if selectedQuestions == nil {
+		selectedQuestions = SelectQuestionsForUser(opts.Level, seed, opts.History)
+	}
+
+	sessionType := opts.Type
+	if sessionType == "" {
+		sessionType = SessionTypeMock
+	}
+
+	// Only a uniform draw from the bank can be replayed from its seed
+	var storedSeed *int64
+	if opts.Questions == nil && opts.History == nil {
+		storedSeed = &seed
+	}
+
 	session := &Session{
-		ID:               uuid.NewString(),
-		UserID:           userID,
+		ID:                uuid.NewString(),
+		UserID:            userID,
+		Type:              sessionType,
+		CohortID:          opts.CohortID,
 		SelectedQuestions: selectedQuestions,
-		QuestionIndex:    0,
-		Answers:          []Answer{},
-		Scores:           Scores{},
-		Status:           SessionStatusActive,
-		CreatedAt:        now,
-		UpdatedAt:        now,
-	}
-	
+		QuestionIndex:     0,
+		Seed:              storedSeed,
+		BankVersion:       QuestionBankVersion,
+		Answers:           []Answer{},
+		Scores:            Scores{},
+		Status:            SessionStatusActive,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+
+	if opts.TimeLimits != nil {
+		limits := opts.TimeLimits.withDefaults()
+		deadline := now.Add(limits.Total())
+		session.TimeLimits = &limits
+		session.SessionDeadline = &deadline
+	}
+
 	// Set current question to first selected question
 	if len(selectedQuestions) > 0 {
 		session.CurrentQuestion = selectedQuestions[0].ID
+		StartQuestion(session, now)
 	}
-	
+
 	return session
 }
 
-func SaveSession(s *Session) {
+// CurrentQuestion returns the question the session is waiting on, or nil
+func CurrentQuestion(s *Session) *Question {
+	for i, q := range s.SelectedQuestions {
+		if q.ID == s.CurrentQuestion {
+			return &s.SelectedQuestions[i]
+		}
+	}
+	return nil
+}
+
+// AdvanceQuestion moves s to its next selected question and returns it.
+// It returns nil once every question has been asked; the caller then finishes the session.
+func AdvanceQuestion(s *Session, now time.Time) *Question {
+	s.QuestionIndex++
+	if s.QuestionIndex >= len(s.SelectedQuestions) {
+		return nil
+	}
+	next := &s.SelectedQuestions[s.QuestionIndex]
+	s.CurrentQuestion = next.ID
+	StartQuestion(s, now)
+	return next
+}
+
+// SaveSession stores a copy of s, provided nobody else saved the session since s was read
+// (its Version still matches the stored one), and bumps s.Version.
+// A stale write returns ErrSessionConflict and leaves the store unchanged.
+func SaveSession(s *Session) error {
 	sessionsMu.Lock()
 	defer sessionsMu.Unlock()
+	if cur, ok := sessions[s.ID]; ok && cur.Version != s.Version {
+		return ErrSessionConflict
+	}
+	s.Version++
 	s.UpdatedAt = time.Now()
-	sessions[s.ID] = s
+	sessions[s.ID] = cloneSession(s)
+	return nil
 }
 
+// GetSession returns a private copy of a session from memory, falling back to the archive
+// for sessions the janitor has already evicted. Changes to the copy only take effect
+// through SaveSession.
 func GetSession(id string) (*Session, bool) {
 	sessionsMu.RLock()
-	defer sessionsMu.RUnlock()
 	s, ok := sessions[id]
-	return s, ok
+	if ok {
+		s = cloneSession(s)
+	}
+	sessionsMu.RUnlock()
+	if ok {
+		return s, true
+	}
+
+	a := getSessionArchive()
+	if a == nil {
+		return nil, false
+	}
+	s, err := a.GetArchivedSession(id)
+	if err != nil || s == nil {
+		return nil, false
+	}
+	return s, true
+}
+
+// LockSession serialises work on one session across goroutines, so a read-modify-save
+// cycle (including the slow grading call in between) is not interleaved with another
+// request for the same session. Call the returned function to release the lock.
+func LockSession(id string) func() {
+	sessionLocksMu.Lock()
+	l, ok := sessionLocks[id]
+	if !ok {
+		l = &sessionLock{}
+		sessionLocks[id] = l
+	}
+	l.refs++
+	sessionLocksMu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		sessionLocksMu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(sessionLocks, id)
+		}
+		sessionLocksMu.Unlock()
+	}
+}
+
+// cloneSession copies s deeply enough that answers and attempts can be changed on the copy.
+// Analyses, evals and the summary are shared; they are replaced, never modified in place.
+func cloneSession(s *Session) *Session {
+	c := *s
+	c.SelectedQuestions = append([]Question(nil), s.SelectedQuestions...)
+	c.Answers = make([]Answer, len(s.Answers))
+	for i, a := range s.Answers {
+		a.Attempts = append([]Attempt(nil), a.Attempts...)
+		c.Answers[i] = a
+	}
+	c.IdempotencyKeys = maps.Clone(s.IdempotencyKeys)
+	return &c
+}
+
+// ListUserSessions returns copies of all sessions belonging to userID, in memory and archived
+func ListUserSessions(userID string) []*Session {
+	if userID == "" {
+		return nil
+	}
+
+	sessionsMu.RLock()
+	var out []*Session
+	seen := make(map[string]bool)
+	for _, s := range sessions {
+		if s.UserID == userID {
+			out = append(out, cloneSession(s))
+			seen[s.ID] = true
+		}
+	}
+	sessionsMu.RUnlock()
+
+	if a := getSessionArchive(); a != nil {
+		archived, err := a.ListArchivedSessions(userID)
+		if err != nil {
+			log.Printf("list archived sessions for %s: %v", userID, err)
+		}
+		for _, s := range archived {
+			if !seen[s.ID] {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
 }
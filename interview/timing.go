@@ -0,0 +1,158 @@
+package interview
+
+import (
+	"fmt"
+	"time"
+)
+
+// LatePolicy decides what happens to an answer submitted after its question deadline.
+type LatePolicy string
+
+const (
+	// LatePolicyPenalize grades the answer but deducts LatePenaltyPoints from its TotalScore
+	LatePolicyPenalize LatePolicy = "penalize"
+	// LatePolicySkip records the answer as skipped without grading it
+	LatePolicySkip LatePolicy = "skip"
+)
+
+const (
+	// DefaultQuestionSeconds is roughly how long a consular officer lets an answer run
+	DefaultQuestionSeconds = 45
+	// DefaultTotalSeconds is the overall budget of a timed session
+	DefaultTotalSeconds = 5 * 60
+	// LatePenaltyPoints is deducted from a late answer's TotalScore under LatePolicyPenalize
+	LatePenaltyPoints = 2
+	// timingGrace absorbs network and rendering delay before an answer counts as late
+	timingGrace = 2 * time.Second
+)
+
+// TimeLimits configures timed mode. Zero values fall back to the defaults above.
+type TimeLimits struct {
+	PerQuestionSeconds int        `json:"per_question_seconds"`
+	TotalSeconds       int        `json:"total_seconds"`
+	LatePolicy         LatePolicy `json:"late_policy"`
+}
+
+// withDefaults fills unset limits
+func (t TimeLimits) withDefaults() TimeLimits {
+	if t.PerQuestionSeconds <= 0 {
+		t.PerQuestionSeconds = DefaultQuestionSeconds
+	}
+	if t.TotalSeconds <= 0 {
+		t.TotalSeconds = DefaultTotalSeconds
+	}
+	if t.LatePolicy != LatePolicySkip {
+		t.LatePolicy = LatePolicyPenalize
+	}
+	return t
+}
+
+// PerQuestion returns the per-question limit as a duration
+func (t TimeLimits) PerQuestion() time.Duration {
+	return time.Duration(t.PerQuestionSeconds) * time.Second
+}
+
+// Total returns the session budget as a duration
+func (t TimeLimits) Total() time.Duration {
+	return time.Duration(t.TotalSeconds) * time.Second
+}
+
+// AnswerTiming is the server-side timing verdict for one submitted answer
+type AnswerTiming struct {
+	Latency time.Duration
+	Late    bool // past the question deadline
+	TimeUp  bool // past the session deadline; the answer is not accepted
+}
+
+// StartQuestion stamps when the current question was shown and, in timed mode, its deadline
+func StartQuestion(s *Session, now time.Time) {
+	s.QuestionAskedAt = now
+	if s.TimeLimits != nil {
+		deadline := now.Add(s.TimeLimits.PerQuestion())
+		if s.SessionDeadline != nil && deadline.After(*s.SessionDeadline) {
+			deadline = *s.SessionDeadline
+		}
+		s.QuestionDeadline = &deadline
+	}
+}
+
+// CheckTiming measures an answer submitted at now against the session's deadlines
+func CheckTiming(s *Session, now time.Time) AnswerTiming {
+	t := AnswerTiming{}
+	if !s.QuestionAskedAt.IsZero() {
+		t.Latency = now.Sub(s.QuestionAskedAt)
+	}
+	if s.TimeLimits == nil {
+		return t
+	}
+	if s.SessionDeadline != nil && now.After(s.SessionDeadline.Add(timingGrace)) {
+		t.TimeUp = true
+	}
+	if s.QuestionDeadline != nil && now.After(s.QuestionDeadline.Add(timingGrace)) {
+		t.Late = true
+	}
+	return t
+}
+
+// CheckRetryTiming measures a retry of ans submitted at now against the deadline its question
+// had when first asked, so retrying an answer can't get round the clock. Untimed sessions
+// have nothing to check.
+func CheckRetryTiming(s *Session, ans *Answer, now time.Time) AnswerTiming {
+	t := AnswerTiming{}
+	if s.TimeLimits == nil {
+		return t
+	}
+	askedAt := ans.CreatedAt.Add(-time.Duration(ans.LatencyMs) * time.Millisecond)
+	t.Latency = now.Sub(askedAt)
+	deadline := askedAt.Add(s.TimeLimits.PerQuestion())
+	if s.SessionDeadline != nil {
+		if deadline.After(*s.SessionDeadline) {
+			deadline = *s.SessionDeadline
+		}
+		t.TimeUp = now.After(s.SessionDeadline.Add(timingGrace))
+	}
+	t.Late = now.After(deadline.Add(timingGrace))
+	return t
+}
+
+// ApplyTimingFeedback penalises a late answer and adds latency advice to its feedback.
+// Call it on graded answers in timed sessions before the analysis is applied to scores.
+func ApplyTimingFeedback(analysis *AnalysisResponse, limits *TimeLimits, timing AnswerTiming) {
+	if analysis == nil || limits == nil {
+		return
+	}
+
+	seconds := int(timing.Latency.Round(time.Second) / time.Second)
+	if timing.Late {
+		if limits.LatePolicy == LatePolicyPenalize {
+			analysis.Scores.TotalScore -= LatePenaltyPoints
+			if analysis.Scores.TotalScore < 3 {
+				analysis.Scores.TotalScore = 3
+			}
+			analysis.Classification = classificationFromScore(analysis.Scores.TotalScore)
+		}
+		analysis.Feedback.Improvements = append(analysis.Feedback.Improvements,
+			fmt.Sprintf("You took %ds to answer, over the %ds limit. Officers cut long pauses short, so lead with your main point.", seconds, limits.PerQuestionSeconds))
+		return
+	}
+
+	// Slow but on time: nudge towards a quicker start
+	if timing.Latency > limits.PerQuestion()*3/4 {
+		analysis.Feedback.Improvements = append(analysis.Feedback.Improvements,
+			fmt.Sprintf("You answered in %ds of the %ds allowed. Practise starting your answer sooner.", seconds, limits.PerQuestionSeconds))
+	}
+}
+
+// classificationFromScore mirrors the analyzer's TotalScore bands
+func classificationFromScore(score int) string {
+	switch {
+	case score >= 15:
+		return "Excellent"
+	case score >= 13:
+		return "Good"
+	case score >= 11:
+		return "Average"
+	default:
+		return "Weak"
+	}
+}
@@ -0,0 +1,476 @@
+package tests
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"altoai_mvp/internal/auth"
+	"altoai_mvp/internal/models"
+	"altoai_mvp/internal/repository"
+	"altoai_mvp/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// fakeOIDC stands in for an OpenID Connect provider: discovery, JWKS, userinfo and a token
+// endpoint that only accepts a code_verifier matching the challenge sent to the authorize
+// endpoint and returns an RS256 ID token carrying the login's nonce.
+type fakeOIDC struct {
+	*httptest.Server
+	key       *rsa.PrivateKey
+	mu        sync.Mutex
+	challenge string
+	nonce     string
+	// claims overrides or adds ID token claims; a nil value deletes the claim
+	claims map[string]any
+	// userinfo is what the userinfo endpoint returns
+	userinfo map[string]any
+}
+
+func newFakeOIDC(t *testing.T) *fakeOIDC {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeOIDC{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                 f.URL,
+			"authorization_endpoint": f.URL + "/authorize",
+			"token_endpoint":         f.URL + "/token",
+			"jwks_uri":               f.URL + "/jwks",
+			"userinfo_endpoint":      f.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		json.NewEncoder(w).Encode(f.userinfo)
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]any{{
+			"kty": "RSA", "kid": "k1", "use": "sig", "alg": "RS256",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if r.Form.Get("code") != "good-code" || base64.RawURLEncoding.EncodeToString(sum[:]) != f.challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		claims := jwt.MapClaims{
+			"iss": f.URL, "aud": "client-id", "sub": "oidc-1",
+			"exp": time.Now().Add(time.Hour).Unix(), "iat": time.Now().Unix(), "nonce": f.nonce,
+			"email": "oidc-user@example.com", "email_verified": true, "name": "OIDC User",
+		}
+		for k, v := range f.claims {
+			if v == nil {
+				delete(claims, k)
+			} else {
+				claims[k] = v
+			}
+		}
+		tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		tok.Header["kid"] = "k1"
+		idToken, _ := tok.SignedString(f.key)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "provider-access", "token_type": "Bearer", "expires_in": 3600, "id_token": idToken,
+		})
+	})
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeOIDC) setClaims(claims map[string]any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.claims = claims
+}
+
+// newOIDCRouter registers the fake as provider "google" and returns the router and user repo
+func newOIDCRouter(t *testing.T) (*gin.Engine, *fakeOIDC, repository.UserRepo) {
+	t.Helper()
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("FRONTEND_URL", "http://frontend.test")
+	gin.SetMode(gin.TestMode)
+
+	fake := newFakeOIDC(t)
+	if err := auth.RegisterProvider(auth.ProviderConfig{
+		Name:        "google",
+		Issuer:      fake.URL,
+		ClientID:    "client-id",
+		RedirectURL: "http://api.test/auth/google/callback",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	repo := repository.NewUserMemoryRepo()
+	auth.SetAuthService(services.NewAuthService(repo, newTestTokens(t)))
+
+	r := gin.New()
+	r.GET("/auth/:provider", auth.HandleLogin)
+	r.GET("/auth/:provider/callback", auth.HandleCallback)
+	r.POST("/api/v1/auth/exchange", auth.HandleCodeExchange)
+	return r, fake, repo
+}
+
+func newGoogleRouter(t *testing.T) (*gin.Engine, *fakeOIDC) {
+	r, fake, _ := newOIDCRouter(t)
+	return r, fake
+}
+
+// startLogin hits /auth/<provider> and returns the state sent to the provider and the state cookie
+func startLogin(t *testing.T, r *gin.Engine, fake *fakeOIDC, provider, query string) (string, *http.Cookie) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/"+provider+query, nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("Expected redirect to the provider, got %d: %s", w.Code, w.Body.String())
+	}
+
+	loc, _ := url.Parse(w.Header().Get("Location"))
+	params := loc.Query()
+	if params.Get("state") == "" || params.Get("state") == "state-123" {
+		t.Fatalf("Expected a random state, got %q", params.Get("state"))
+	}
+	if params.Get("code_challenge_method") != "S256" || params.Get("code_challenge") == "" {
+		t.Fatal("Expected a PKCE S256 challenge")
+	}
+	if params.Get("nonce") == "" {
+		t.Fatal("Expected a nonce")
+	}
+	fake.mu.Lock()
+	fake.challenge = params.Get("code_challenge")
+	fake.nonce = params.Get("nonce")
+	fake.mu.Unlock()
+
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "oauth_state" {
+			return params.Get("state"), c
+		}
+	}
+	t.Fatal("Expected an oauth_state cookie")
+	return "", nil
+}
+
+func startGoogleLogin(t *testing.T, r *gin.Engine, fake *fakeOIDC, query string) (string, *http.Cookie) {
+	t.Helper()
+	return startLogin(t, r, fake, "google", query)
+}
+
+func providerCallback(r *gin.Engine, provider, state string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/auth/"+provider+"/callback?code=good-code&state="+url.QueryEscape(state), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func googleCallback(r *gin.Engine, state string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	return providerCallback(r, "google", state, cookie)
+}
+
+func TestGoogleLoginRoundTrip(t *testing.T) {
+	r, fake := newGoogleRouter(t)
+	state, cookie := startGoogleLogin(t, r, fake, "?redirect_to="+url.QueryEscape("/practice?tab=due"))
+
+	w := googleCallback(r, state, cookie)
+	if w.Code != http.StatusFound {
+		t.Fatalf("Expected redirect to the frontend, got %d: %s", w.Code, w.Body.String())
+	}
+	loc, _ := url.Parse(w.Header().Get("Location"))
+	if loc.Host != "frontend.test" || loc.Path != "/practice" || loc.Query().Get("tab") != "due" {
+		t.Errorf("Expected to return to /practice?tab=due, got %s", loc)
+	}
+	if loc.Query().Get("access_token") != "" {
+		t.Error("Expected no access token on the redirect")
+	}
+	code := loc.Query().Get("auth_code")
+	if code == "" {
+		t.Fatal("Expected a login code on the redirect")
+	}
+	if hasRefreshCookie(w) {
+		t.Error("Expected the refresh cookie to wait for the exchange")
+	}
+
+	w = exchangeLoginCode(r, code)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected exchange to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	if !hasRefreshCookie(w) {
+		t.Error("Expected the exchange to set the refresh cookie")
+	}
+	if w.Header().Get("Cache-Control") != "no-store" {
+		t.Error("Expected the exchange response not to be cached")
+	}
+	var resp struct {
+		Data struct {
+			AccessToken string `json:"access_token"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Data.AccessToken == "" {
+		t.Fatalf("Expected an access token from the exchange, got %s", w.Body.String())
+	}
+
+	if w := exchangeLoginCode(r, code); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a used login code to be rejected, got %d", w.Code)
+	}
+}
+
+func hasRefreshCookie(w *httptest.ResponseRecorder) bool {
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "refresh_token" && c.Value != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func exchangeLoginCode(r *gin.Engine, code string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]string{"code": code})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/exchange", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCodeExchangeRejectsUnknownCode(t *testing.T) {
+	r, _ := newGoogleRouter(t)
+	if w := exchangeLoginCode(r, "not-a-code"); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for an unknown code, got %d", w.Code)
+	}
+	if w := exchangeLoginCode(r, ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a missing code, got %d", w.Code)
+	}
+}
+
+func TestGoogleLoginLegacyTokenRedirect(t *testing.T) {
+	r, fake := newGoogleRouter(t)
+	t.Setenv("OAUTH_LEGACY_TOKEN_REDIRECT", "true")
+	state, cookie := startGoogleLogin(t, r, fake, "")
+
+	w := googleCallback(r, state, cookie)
+	loc, _ := url.Parse(w.Header().Get("Location"))
+	if loc.Query().Get("access_token") == "" {
+		t.Error("Expected the legacy redirect to carry the access token")
+	}
+	if loc.Query().Get("auth_code") != "" {
+		t.Error("Expected no login code on the legacy redirect")
+	}
+	if !hasRefreshCookie(w) {
+		t.Error("Expected the legacy redirect to set the refresh cookie")
+	}
+}
+
+func TestOIDCLoginDeliversMFAChallengeThroughExchange(t *testing.T) {
+	r, fake, repo := newOIDCRouter(t)
+	repo.SetMFARequiredRoles([]models.Role{models.RoleStudent})
+
+	for _, legacy := range []string{"false", "true"} {
+		t.Setenv("OAUTH_LEGACY_TOKEN_REDIRECT", legacy)
+		state, cookie := startGoogleLogin(t, r, fake, "")
+		w := googleCallback(r, state, cookie)
+		loc, _ := url.Parse(w.Header().Get("Location"))
+		if loc.Query().Get("mfa_token") != "" || loc.Query().Get("access_token") != "" || hasRefreshCookie(w) {
+			t.Fatalf("legacy=%s: expected no token on the redirect, got %s", legacy, loc)
+		}
+
+		w = exchangeLoginCode(r, loc.Query().Get("auth_code"))
+		var resp struct {
+			Data map[string]any `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if w.Code != http.StatusOK || resp.Data["mfa_setup_required"] != true || resp.Data["mfa_token"] == "" {
+			t.Fatalf("legacy=%s: expected an MFA setup challenge from the exchange, got %d: %s", legacy, w.Code, w.Body.String())
+		}
+		if _, ok := resp.Data["access_token"]; ok || hasRefreshCookie(w) {
+			t.Errorf("legacy=%s: expected no tokens before the second factor", legacy)
+		}
+	}
+}
+
+func TestGoogleCallbackRejectsBadState(t *testing.T) {
+	r, fake := newGoogleRouter(t)
+	state, cookie := startGoogleLogin(t, r, fake, "")
+
+	if w := googleCallback(r, "forged-state", cookie); w.Code != http.StatusBadRequest {
+		t.Errorf("Forged state: expected 400, got %d", w.Code)
+	}
+	if w := googleCallback(r, state, nil); w.Code != http.StatusBadRequest {
+		t.Errorf("Missing cookie: expected 400, got %d", w.Code)
+	}
+
+	tampered := *cookie
+	tampered.Value = strings.Replace(cookie.Value, ".", ".x", 1)
+	if w := googleCallback(r, state, &tampered); w.Code != http.StatusBadRequest {
+		t.Errorf("Tampered cookie: expected 400, got %d", w.Code)
+	}
+}
+
+func TestGoogleCallbackRequiresMatchingVerifier(t *testing.T) {
+	r, fake := newGoogleRouter(t)
+	state, cookie := startGoogleLogin(t, r, fake, "")
+	// A second login replaces the challenge the provider saw, so the first verifier no longer matches
+	startGoogleLogin(t, r, fake, "")
+
+	if w := googleCallback(r, state, cookie); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected token exchange to fail without the matching verifier, got %d", w.Code)
+	}
+}
+
+func TestGoogleLoginIgnoresOffsiteRedirects(t *testing.T) {
+	r, fake := newGoogleRouter(t)
+	for _, target := range []string{"https://evil.example", "//evil.example/x", "/\\evil.example", "javascript:alert(1)"} {
+		state, cookie := startGoogleLogin(t, r, fake, "?redirect_to="+url.QueryEscape(target))
+		w := googleCallback(r, state, cookie)
+		loc, _ := url.Parse(w.Header().Get("Location"))
+		if loc == nil || loc.Host != "frontend.test" || loc.Path != "/" {
+			t.Errorf("redirect_to %q should fall back to /, got %v", target, loc)
+		}
+	}
+}
+
+func TestOIDCLoginLinksExistingUserByVerifiedEmail(t *testing.T) {
+	r, fake, repo := newOIDCRouter(t)
+	existing, err := repo.Create("oidc-user@example.com", "Existing User", "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state, cookie := startGoogleLogin(t, r, fake, "")
+	if w := googleCallback(r, state, cookie); w.Code != http.StatusFound {
+		t.Fatalf("Expected login to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+
+	users, _ := repo.List()
+	if len(users) != 1 {
+		t.Fatalf("Expected the provider account to join the existing user, got %d users", len(users))
+	}
+	link, err := repo.GetIdentity("google", "oidc-1")
+	if err != nil || link.UserID != existing.ID {
+		t.Fatalf("Expected identity linked to %s, got %+v (%v)", existing.ID, link, err)
+	}
+	if u, _ := repo.Get(existing.ID); !u.EmailVerified || u.Password != "" {
+		t.Error("Expected the provider-verified email to be marked verified and the unverified password dropped")
+	}
+
+	// A second provider with the same verified email links to the same user too
+	if err := auth.RegisterProvider(auth.ProviderConfig{
+		Name:     "campus",
+		Issuer:   fake.URL,
+		ClientID: "client-id",
+		Claims:   auth.ClaimMapping{Subject: "oid", Email: "upn"},
+		// Campus SSO owns its domain but doesn't send email_verified
+		TrustEmail: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	fake.setClaims(map[string]any{"oid": "campus-7", "upn": "OIDC-User@example.com", "email": nil, "email_verified": nil})
+	state, cookie = startLogin(t, r, fake, "campus", "")
+	if w := providerCallback(r, "campus", state, cookie); w.Code != http.StatusFound {
+		t.Fatalf("Expected campus login to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	if ids, _ := repo.ListIdentities(existing.ID); len(ids) != 2 {
+		t.Errorf("Expected two linked identities, got %d", len(ids))
+	}
+	if users, _ := repo.List(); len(users) != 1 {
+		t.Errorf("Expected still one user, got %d", len(users))
+	}
+}
+
+func TestOIDCUserinfoMatchedOnMappedSubject(t *testing.T) {
+	r, fake, repo := newOIDCRouter(t)
+	if err := auth.RegisterProvider(auth.ProviderConfig{
+		Name:     "campus",
+		Issuer:   fake.URL,
+		ClientID: "client-id",
+		Claims:   auth.ClaimMapping{Subject: "oid"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// The ID token leaves the email to userinfo, which names the account by oid too
+	fake.setClaims(map[string]any{"oid": "campus-9", "email": nil, "email_verified": nil})
+	fake.mu.Lock()
+	fake.userinfo = map[string]any{"oid": "campus-9", "sub": "pairwise-1", "email": "campus@example.com", "email_verified": true}
+	fake.mu.Unlock()
+
+	state, cookie := startLogin(t, r, fake, "campus", "")
+	if w := providerCallback(r, "campus", state, cookie); w.Code != http.StatusFound {
+		t.Fatalf("Expected the userinfo email to be used, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, err := repo.GetByEmail("campus@example.com"); err != nil {
+		t.Errorf("Expected a user for the userinfo email, got %v", err)
+	}
+}
+
+func TestOIDCLoginRejectsUnverifiedEmail(t *testing.T) {
+	r, fake, repo := newOIDCRouter(t)
+	repo.Create("oidc-user@example.com", "Existing User", "hash")
+	fake.setClaims(map[string]any{"email_verified": false})
+
+	state, cookie := startGoogleLogin(t, r, fake, "")
+	if w := googleCallback(r, state, cookie); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected 403 for an unverified provider email, got %d", w.Code)
+	}
+	if _, err := repo.GetIdentity("google", "oidc-1"); err != repository.ErrNotFound {
+		t.Error("Expected no identity to be linked")
+	}
+}
+
+func TestOIDCCallbackValidatesIDToken(t *testing.T) {
+	cases := map[string]map[string]any{
+		"wrong audience": {"aud": "someone-else"},
+		"wrong issuer":   {"iss": "https://evil.example"},
+		"wrong nonce":    {"nonce": "replayed"},
+		"expired":        {"exp": time.Now().Add(-time.Hour).Unix()},
+	}
+	for name, claims := range cases {
+		t.Run(name, func(t *testing.T) {
+			r, fake, _ := newOIDCRouter(t)
+			fake.setClaims(claims)
+			state, cookie := startGoogleLogin(t, r, fake, "")
+			if w := googleCallback(r, state, cookie); w.Code != http.StatusUnauthorized {
+				t.Errorf("Expected 401, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestOIDCRejectsUnknownProviderAndCrossProviderState(t *testing.T) {
+	r, fake, _ := newOIDCRouter(t)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/nope", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown provider, got %d", w.Code)
+	}
+
+	if err := auth.RegisterProvider(auth.ProviderConfig{Name: "other", Issuer: fake.URL, ClientID: "client-id"}); err != nil {
+		t.Fatal(err)
+	}
+	state, cookie := startGoogleLogin(t, r, fake, "")
+	if w := providerCallback(r, "other", state, cookie); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected a google login state to be rejected at another provider, got %d", w.Code)
+	}
+}
@@ -0,0 +1,177 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"altoai_mvp/internal/handlers"
+	"altoai_mvp/internal/middleware"
+	"altoai_mvp/internal/repository"
+	"altoai_mvp/internal/services"
+	"altoai_mvp/interview"
+
+	"github.com/gin-gonic/gin"
+)
+
+type chatEnvelope struct {
+	Data  handlers.ChatResponse `json:"data"`
+	Error string                `json:"error"`
+}
+
+// newChatRouter serves ChatHandler.Chat for a single signed-in user.
+// No analyzer is configured, so answers are recorded without grading.
+func newChatRouter(t *testing.T, email string) *gin.Engine {
+	t.Helper()
+	if err := interview.LoadQuestions("../interview/questions.json"); err != nil {
+		t.Fatalf("LoadQuestions failed: %v", err)
+	}
+	gin.SetMode(gin.TestMode)
+
+	repo := repository.NewUserMemoryRepo()
+	if _, err := repo.Create(email, "Student", "hash"); err != nil {
+		t.Fatalf("Create user failed: %v", err)
+	}
+	h := handlers.NewChatHandler(services.NewUserService(repo), services.NewCohortService(repo, services.NewEmailService()))
+
+	r := gin.New()
+	r.POST("/chat", func(c *gin.Context) {
+		c.Set("user", &middleware.MyClaims{Email: email})
+	}, h.Chat)
+	return r
+}
+
+func postChat(r *gin.Engine, body map[string]any) (int, chatEnvelope) {
+	w := postChatWithKey(r, body, "")
+
+	var env chatEnvelope
+	json.Unmarshal(w.Body.Bytes(), &env)
+	return w.Code, env
+}
+
+func postChatWithKey(r *gin.Engine, body map[string]any, key string) *httptest.ResponseRecorder {
+	raw, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/chat", bytes.NewReader(raw))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func answerBody(sessionID, questionID, answerID, text string) map[string]any {
+	return map[string]any{
+		"session_id":  sessionID,
+		"question_id": questionID,
+		"answer_id":   answerID,
+		"messages":    []map[string]string{{"role": "user", "content": text}},
+	}
+}
+
+func TestChatDuplicateAnswerIDIsIdempotent(t *testing.T) {
+	r := newChatRouter(t, "double-click@example.com")
+
+	code, start := postChat(r, map[string]any{})
+	if code != http.StatusOK {
+		t.Fatalf("Starting a session failed: %d %s", code, start.Error)
+	}
+	sessionID, firstQ := start.Data.SessionID, start.Data.QuestionID
+
+	const clicks = 20
+	var wg sync.WaitGroup
+	nextQuestions := make([]string, clicks)
+	for i := 0; i < clicks; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			code, resp := postChat(r, answerBody(sessionID, firstQ, "answer-1", "State University"))
+			if code != http.StatusOK {
+				t.Errorf("Duplicate submit %d got %d: %s", i, code, resp.Error)
+			}
+			nextQuestions[i] = resp.Data.QuestionID
+		}(i)
+	}
+	wg.Wait()
+
+	s, _ := interview.GetSession(sessionID)
+	if len(s.Answers) != 1 || s.QuestionIndex != 1 {
+		t.Fatalf("Expected one recorded answer, got %d answers at index %d", len(s.Answers), s.QuestionIndex)
+	}
+	for i, q := range nextQuestions {
+		if q != s.CurrentQuestion {
+			t.Errorf("Submit %d was told to answer %q, session is on %q", i, q, s.CurrentQuestion)
+		}
+	}
+}
+
+func TestChatConcurrentAnswersToSameQuestion(t *testing.T) {
+	r := newChatRouter(t, "two-tabs@example.com")
+
+	_, start := postChat(r, map[string]any{})
+	sessionID, firstQ := start.Data.SessionID, start.Data.QuestionID
+
+	// Two tabs answer the same question with different answer IDs
+	const tabs = 20
+	var wg sync.WaitGroup
+	codes := make([]int, tabs)
+	for i := 0; i < tabs; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i], _ = postChat(r, answerBody(sessionID, firstQ, "", "State University"))
+		}(i)
+	}
+	wg.Wait()
+
+	accepted := 0
+	for _, code := range codes {
+		switch code {
+		case http.StatusOK:
+			accepted++
+		case http.StatusConflict:
+		default:
+			t.Errorf("Unexpected status %d", code)
+		}
+	}
+	s, _ := interview.GetSession(sessionID)
+	if accepted != 1 || len(s.Answers) != 1 || s.QuestionIndex != 1 {
+		t.Errorf("Expected exactly one accepted answer, got %d accepted, %d answers at index %d",
+			accepted, len(s.Answers), s.QuestionIndex)
+	}
+}
+
+func TestChatIdempotencyKeyReplaysStoredResponse(t *testing.T) {
+	r := newChatRouter(t, "flaky-network@example.com")
+
+	_, start := postChat(r, map[string]any{})
+	sessionID, firstQ := start.Data.SessionID, start.Data.QuestionID
+	body := answerBody(sessionID, firstQ, "", "State University")
+
+	first := postChatWithKey(r, body, "key-1")
+	if first.Code != http.StatusOK {
+		t.Fatalf("First submit failed: %d %s", first.Code, first.Body.String())
+	}
+	retry := postChatWithKey(r, body, "key-1")
+	if retry.Code != http.StatusOK || retry.Body.String() != first.Body.String() {
+		t.Errorf("Retry should return the stored response, got %d %s", retry.Code, retry.Body.String())
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("Replayed responses should be marked with Idempotent-Replayed")
+	}
+
+	s, _ := interview.GetSession(sessionID)
+	if len(s.Answers) != 1 || s.QuestionIndex != 1 {
+		t.Errorf("Retry was processed again: %d answers at index %d", len(s.Answers), s.QuestionIndex)
+	}
+
+	// Reusing the key for a different request is an error, not a replay
+	other := postChatWithKey(r, answerBody(sessionID, s.CurrentQuestion, "", "Computer Science"), "key-1")
+	if other.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for a reused key, got %d", other.Code)
+	}
+}
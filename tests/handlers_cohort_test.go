@@ -0,0 +1,295 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"altoai_mvp/internal/handlers"
+	"altoai_mvp/internal/middleware"
+	"altoai_mvp/internal/models"
+	"altoai_mvp/internal/services"
+	"altoai_mvp/interview"
+)
+
+// recordingEmail is an EmailService that keeps the codes it would have sent, by recipient
+type recordingEmail struct {
+	mu    sync.Mutex
+	codes map[string]string
+}
+
+func (e *recordingEmail) record(email, code string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.codes == nil {
+		e.codes = map[string]string{}
+	}
+	e.codes[email] = code
+	return nil
+}
+
+func (e *recordingEmail) code(email string) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.codes[email]
+}
+
+func (e *recordingEmail) SendVerificationCode(email, name, code string) error {
+	return e.record(email, code)
+}
+
+func (e *recordingEmail) SendPasswordResetCode(email, name, code string) error {
+	return e.record(email, code)
+}
+
+func (e *recordingEmail) SendCohortInvite(email, cohortName, inviterName, code string) error {
+	return e.record(email, code)
+}
+
+func (e *recordingEmail) SendMagicLink(email, name, link string) error {
+	return e.record(email, link)
+}
+
+func (e *recordingEmail) SendEmailChangeCode(email, name, code string) error {
+	return e.record(email, code)
+}
+
+// SendEmailChangedNotice records the new address as the old one's "code"
+func (e *recordingEmail) SendEmailChangedNotice(oldEmail, name, newEmail string) error {
+	return e.record(oldEmail, newEmail)
+}
+
+func (e *recordingEmail) GenerateCode() (string, error) {
+	return services.NewEmailService().GenerateCode()
+}
+
+// newCohortRouter adds the cohort and chat routes, as router.New wires them, to an RBAC fixture
+func newCohortRouter(t *testing.T) (*rbacFixture, *recordingEmail) {
+	t.Helper()
+	f := newRBACRouter(t)
+	for _, u := range append([]models.User{f.student}, f.users[models.RoleStudent], f.users[models.RoleCounsellor], f.users[models.RoleAdmin]) {
+		f.repo.MarkEmailVerified(u.Email)
+	}
+
+	email := &recordingEmail{}
+	userSvc := services.NewUserService(f.repo)
+	cohortSvc := services.NewCohortService(f.repo, email)
+	cohortH := handlers.NewCohortHandler(userSvc, cohortSvc)
+	chatH := handlers.NewChatHandler(userSvc, cohortSvc)
+	authRequired := middleware.JWTAuth(newTestTokens(t))
+
+	f.r.GET("/cohorts", authRequired, cohortH.List)
+	f.r.POST("/cohorts", authRequired, middleware.RequirePermission(middleware.PermManageCohorts), cohortH.Create)
+	f.r.POST("/cohorts/join", authRequired, cohortH.Join)
+	f.r.GET("/cohorts/:id", authRequired, cohortH.Get)
+	f.r.GET("/cohorts/:id/members", authRequired, cohortH.Members)
+	f.r.POST("/cohorts/:id/invites", authRequired, cohortH.Invite)
+	f.r.PUT("/cohorts/:id/assignment", authRequired, cohortH.SetAssignment)
+	f.r.GET("/cohorts/:id/dashboard", authRequired, cohortH.Dashboard)
+	f.r.POST("/chat", authRequired, chatH.Chat)
+	return f, email
+}
+
+// createCohort creates a cohort as the fixture's counsellor and returns its ID
+func createCohort(t *testing.T, f *rbacFixture) string {
+	t.Helper()
+	w := f.do(http.MethodPost, "/cohorts", f.token(t, f.users[models.RoleCounsellor]), `{"name":"Fall intake","organization":"Northside High"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Create cohort: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data models.Cohort `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return resp.Data.ID
+}
+
+// joinCohort invites the student to the cohort and accepts the emailed code as them
+func joinCohort(t *testing.T, f *rbacFixture, email *recordingEmail, cohortID string, student models.User) {
+	t.Helper()
+	counsellor := f.token(t, f.users[models.RoleCounsellor])
+	if w := f.do(http.MethodPost, "/cohorts/"+cohortID+"/invites", counsellor, `{"email":"`+student.Email+`"}`); w.Code != http.StatusCreated {
+		t.Fatalf("Invite: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	code := email.code(student.Email)
+	if w := f.do(http.MethodPost, "/cohorts/join", f.token(t, student), `{"code":"`+code+`"}`); w.Code != http.StatusOK {
+		t.Fatalf("Join: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestCohortInviteFlow(t *testing.T) {
+	f, email := newCohortRouter(t)
+	cohortID := createCohort(t, f)
+	student := f.users[models.RoleStudent]
+	counsellor := f.token(t, f.users[models.RoleCounsellor])
+
+	if w := f.do(http.MethodPost, "/cohorts", f.token(t, student), `{"name":"Mine"}`); w.Code != http.StatusForbidden {
+		t.Errorf("Student creating a cohort: expected 403, got %d", w.Code)
+	}
+
+	if w := f.do(http.MethodPost, "/cohorts/"+cohortID+"/invites", counsellor, `{"email":"`+student.Email+`"}`); w.Code != http.StatusCreated {
+		t.Fatalf("Invite: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	code := email.code(student.Email)
+	if code == "" {
+		t.Fatal("Expected the invite code to be emailed")
+	}
+
+	// Only the invited address can redeem the code
+	if w := f.do(http.MethodPost, "/cohorts/join", f.token(t, f.student), `{"code":"`+code+`"}`); w.Code != http.StatusForbidden {
+		t.Errorf("Join with someone else's invite: expected 403, got %d", w.Code)
+	}
+	if w := f.do(http.MethodPost, "/cohorts/join", f.token(t, student), `{"code":"`+code+`"}`); w.Code != http.StatusOK {
+		t.Fatalf("Join: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := f.do(http.MethodPost, "/cohorts/join", f.token(t, student), `{"code":"`+code+`"}`); w.Code != http.StatusNotFound {
+		t.Errorf("Reused invite: expected 404, got %d", w.Code)
+	}
+
+	w := f.do(http.MethodGet, "/cohorts", f.token(t, student), "")
+	var list struct {
+		Data []models.Cohort `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Data) != 1 || list.Data[0].ID != cohortID {
+		t.Errorf("Expected the student to see the cohort they joined, got %s", w.Body.String())
+	}
+
+	w = f.do(http.MethodGet, "/cohorts/"+cohortID+"/members", counsellor, "")
+	var members struct {
+		Data []models.CohortMember `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &members)
+	if len(members.Data) != 2 {
+		t.Errorf("Expected the counsellor and the student as members, got %s", w.Body.String())
+	}
+}
+
+func TestCohortAccessIsScopedByRole(t *testing.T) {
+	f, email := newCohortRouter(t)
+	cohortID := createCohort(t, f)
+	student := f.users[models.RoleStudent]
+	joinCohort(t, f, email, cohortID, student)
+
+	// Another counsellor, outside the cohort
+	other, _ := f.repo.Create("counsellor2@example.com", "Other Counsellor", "hash")
+	other, _ = f.repo.SetRole(other.ID, models.RoleCounsellor)
+
+	cases := []struct {
+		name  string
+		user  models.User
+		path  string
+		wants int
+	}{
+		{"member student reads cohort", student, "/cohorts/" + cohortID, http.StatusOK},
+		{"member student reads dashboard", student, "/cohorts/" + cohortID + "/dashboard", http.StatusNotFound},
+		{"outside student reads cohort", f.student, "/cohorts/" + cohortID, http.StatusNotFound},
+		{"outside counsellor reads dashboard", other, "/cohorts/" + cohortID + "/dashboard", http.StatusNotFound},
+		{"cohort counsellor reads dashboard", f.users[models.RoleCounsellor], "/cohorts/" + cohortID + "/dashboard", http.StatusOK},
+		{"admin reads dashboard", f.users[models.RoleAdmin], "/cohorts/" + cohortID + "/dashboard", http.StatusOK},
+	}
+	for _, tc := range cases {
+		if w := f.do(http.MethodGet, tc.path, f.token(t, tc.user), ""); w.Code != tc.wants {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.wants, w.Code)
+		}
+	}
+
+	if w := f.do(http.MethodPut, "/cohorts/"+cohortID+"/assignment", f.token(t, student), `{"level":"hard"}`); w.Code != http.StatusNotFound {
+		t.Errorf("Student setting the assignment: expected 404, got %d", w.Code)
+	}
+}
+
+func TestCohortDashboardAggregatesScores(t *testing.T) {
+	f, email := newCohortRouter(t)
+	cohortID := createCohort(t, f)
+	student := f.users[models.RoleStudent]
+	joinCohort(t, f, email, cohortID, student)
+
+	graded := func(mi, gu, al int) interview.Answer {
+		return interview.Answer{QuestionID: "q", Text: "answer", Analysis: &interview.AnalysisResponse{
+			Scores: interview.AnalysisScores{MigrationIntent: mi, GoalUnderstanding: gu, AnswerLength: al, TotalScore: mi + gu + al},
+		}}
+	}
+	older := interview.NewSessionWithOptions(student.ID, interview.SessionOptions{Questions: []interview.Question{{ID: "q"}}})
+	older.CreatedAt = time.Now().Add(-time.Hour)
+	older.Answers = []interview.Answer{graded(2, 3, 4), {QuestionID: "q2", Skipped: true}}
+	older.Status = interview.SessionStatusFinished
+	older.Summary = &interview.SessionSummary{SessionID: older.ID, OverallGrade: "C"}
+	newer := interview.NewSessionWithOptions(student.ID, interview.SessionOptions{Questions: []interview.Question{{ID: "q"}}})
+	newer.Answers = []interview.Answer{graded(4, 5, 4)}
+	for _, s := range []*interview.Session{older, newer} {
+		if err := interview.SaveSession(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := f.do(http.MethodGet, "/cohorts/"+cohortID+"/dashboard", f.token(t, f.users[models.RoleCounsellor]), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Dashboard: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data services.CohortDashboard `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+
+	if len(resp.Data.Students) != 1 {
+		t.Fatalf("Expected only the student member on the dashboard, got %d rows", len(resp.Data.Students))
+	}
+	row := resp.Data.Students[0]
+	if len(row.Sessions) != 2 || row.Sessions[0].ID != newer.ID {
+		t.Errorf("Expected both sessions, newest first, got %+v", row.Sessions)
+	}
+	want := services.CriterionAverages{MigrationIntent: 3, GoalUnderstanding: 4, AnswerLength: 4, TotalScore: 11, GradedAnswers: 2}
+	if row.Averages != want {
+		t.Errorf("Expected averages %+v, got %+v", want, row.Averages)
+	}
+	if resp.Data.Averages != want {
+		t.Errorf("Expected cohort averages %+v, got %+v", want, resp.Data.Averages)
+	}
+	if row.LatestSummary == nil || row.LatestSummary.SessionID != older.ID {
+		t.Errorf("Expected the latest summary to come from the finished session, got %+v", row.LatestSummary)
+	}
+}
+
+func TestCohortAssignmentStartsSessions(t *testing.T) {
+	f, email := newCohortRouter(t)
+	cohortID := createCohort(t, f)
+	student := f.users[models.RoleStudent]
+	joinCohort(t, f, email, cohortID, student)
+	counsellor := f.token(t, f.users[models.RoleCounsellor])
+
+	if w := f.do(http.MethodPut, "/cohorts/"+cohortID+"/assignment", counsellor, `{"question_set":[{"category":"Hobbies","text":"What do you do for fun?"}]}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Unknown category: expected 422, got %d", w.Code)
+	}
+	body := `{"level":"hard","question_set":[
+		{"category":"Purpose of Study","text":"Why this course?"},
+		{"category":"Financial Capability","text":"Who is funding your studies?"}]}`
+	if w := f.do(http.MethodPut, "/cohorts/"+cohortID+"/assignment", counsellor, body); w.Code != http.StatusOK {
+		t.Fatalf("Set assignment: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w := f.do(http.MethodPost, "/chat", f.token(t, student), `{"messages":[],"cohort_id":"`+cohortID+`"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Cohort session: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data handlers.ChatResponse `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	s, ok := interview.GetSession(resp.Data.SessionID)
+	if !ok {
+		t.Fatal("Expected the session to be stored")
+	}
+	if s.CohortID != cohortID || len(s.SelectedQuestions) != 2 || s.SelectedQuestions[0].Text != "Why this course?" {
+		t.Errorf("Expected the cohort's question set, got cohort %q and %+v", s.CohortID, s.SelectedQuestions)
+	}
+	if resp.Data.Content != "Why this course?" {
+		t.Errorf("Expected the first assigned question, got %q", resp.Data.Content)
+	}
+
+	if w := f.do(http.MethodPost, "/chat", f.token(t, f.student), `{"messages":[],"cohort_id":"`+cohortID+`"}`); w.Code != http.StatusNotFound {
+		t.Errorf("Non-member starting a cohort session: expected 404, got %d", w.Code)
+	}
+}
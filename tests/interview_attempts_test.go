@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"altoai_mvp/interview"
+)
+
+func gradedAttempt(text string, total int, q interview.Question) interview.Attempt {
+	analysis := &interview.AnalysisResponse{
+		Scores:         interview.AnalysisScores{TotalScore: total},
+		Classification: "Weak",
+	}
+	return interview.Attempt{
+		Text:      text,
+		CreatedAt: time.Now(),
+		Analysis:  analysis,
+		Eval:      interview.ConvertAnalysisToEval(analysis, q),
+	}
+}
+
+func TestRetryKeepsFirstAttemptUnlessReplaced(t *testing.T) {
+	q := interview.Question{ID: "q3_Financial_Capability", Category: "Financial Capability", Text: "Who pays?"}
+	session := interview.NewSession("retry-user")
+	session.SelectedQuestions = []interview.Question{q}
+
+	first := gradedAttempt("my uncle", 5, q)
+	answer := interview.Answer{QuestionID: q.ID, QuestionText: q.Text, Text: first.Text, Analysis: first.Analysis, Eval: first.Eval}
+	answer.Attempts = []interview.Attempt{interview.AttemptFromAnswer(answer)}
+	session.Answers = append(session.Answers, answer)
+	interview.ApplyEval(session, first.Eval)
+	scoresAfterFirst := session.Scores
+
+	imp, err := interview.RecordRetry(session, q.ID, gradedAttempt("my father, a doctor earning $80k", 14, q), false)
+	if err != nil {
+		t.Fatalf("RecordRetry failed: %v", err)
+	}
+	if imp.Counted || imp.PreviousScore != 5 || imp.Score != 14 || imp.Delta != 9 || imp.Attempt != 2 {
+		t.Errorf("Unexpected improvement: %+v", imp)
+	}
+	got := interview.FindAnswer(session, q.ID)
+	if got.Text != "my uncle" || len(got.Attempts) != 2 || got.CountedAttempt != 0 {
+		t.Errorf("Retry without replace should keep the first attempt counted, got %+v", got)
+	}
+	if session.Scores != scoresAfterFirst {
+		t.Errorf("Scores should not change on a practice retry")
+	}
+
+	imp, err = interview.RecordRetry(session, q.ID, gradedAttempt("revised", 15, q), true)
+	if err != nil {
+		t.Fatalf("RecordRetry failed: %v", err)
+	}
+	if !imp.Counted || imp.BestScore != 15 {
+		t.Errorf("Replacing retry should be counted, got %+v", imp)
+	}
+	got = interview.FindAnswer(session, q.ID)
+	if got.Text != "revised" || got.CountedAttempt != 2 {
+		t.Errorf("Expected revised attempt to count, got text %q counted %d", got.Text, got.CountedAttempt)
+	}
+	if session.Scores.Financial != -3 || session.Scores.OverallRisk != -3 {
+		t.Errorf("Scores should be recomputed from the revised attempt, got %+v", session.Scores)
+	}
+}
+
+func TestSkippedQuestionCountsOnceRetried(t *testing.T) {
+	q := interview.Question{ID: "q4_Purpose_of_Study", Category: "Purpose of Study", Text: "Why study?"}
+	session := interview.NewSession("skip-user")
+	session.SelectedQuestions = []interview.Question{q}
+
+	interview.SkipQuestion(session, q, time.Now())
+	if !session.Answers[0].Skipped {
+		t.Fatal("Expected skipped answer")
+	}
+
+	imp, err := interview.RecordRetry(session, q.ID, gradedAttempt("to learn", 12, q), false)
+	if err != nil {
+		t.Fatalf("RecordRetry failed: %v", err)
+	}
+	if !imp.Counted {
+		t.Error("First graded attempt at a skipped question should count")
+	}
+	if session.Answers[0].Skipped {
+		t.Error("Answer should no longer be skipped")
+	}
+
+	if _, err := interview.RecordRetry(session, "unknown", interview.Attempt{}, false); err != interview.ErrQuestionNotAnswered {
+		t.Errorf("Expected ErrQuestionNotAnswered, got %v", err)
+	}
+}
+
+func TestTimedRetryKeepsTheDeadline(t *testing.T) {
+	q := interview.Question{ID: "q5_Immigration_Intent", Category: "Immigration Intent", Text: "Will you return?"}
+	session := interview.NewSessionWithOptions("timed-retry-user", interview.SessionOptions{
+		Questions:  []interview.Question{q},
+		TimeLimits: &interview.TimeLimits{PerQuestionSeconds: 30, TotalSeconds: 300, LatePolicy: interview.LatePolicySkip},
+	})
+	askedAt := session.QuestionAskedAt
+
+	// The answer came in 40s after the question, so the skip policy recorded it ungraded
+	session.Answers = append(session.Answers, interview.Answer{
+		QuestionID: q.ID, QuestionText: q.Text, Text: "slow", CreatedAt: askedAt.Add(40 * time.Second),
+		LatencyMs: 40000, Late: true, Skipped: true,
+	})
+
+	retryAt := askedAt.Add(time.Minute)
+	timing := interview.CheckRetryTiming(session, &session.Answers[0], retryAt)
+	if !timing.Late || timing.Latency != time.Minute {
+		t.Fatalf("Expected the retry to be late against the original deadline, got %+v", timing)
+	}
+	attempt := gradedAttempt("quick and perfect", 15, q)
+	attempt.CreatedAt, attempt.LatencyMs, attempt.Late = retryAt, timing.Latency.Milliseconds(), timing.Late
+	imp, err := interview.RecordRetry(session, q.ID, attempt, true)
+	if err != nil {
+		t.Fatalf("RecordRetry failed: %v", err)
+	}
+	if imp.Counted || !session.Answers[0].Skipped {
+		t.Errorf("Expected a late retry under the skip policy to stay practice only, got %+v", imp)
+	}
+
+	// Under the penalize policy it counts, and the answer takes on the retry's timing
+	session.TimeLimits.LatePolicy = interview.LatePolicyPenalize
+	if imp, _ = interview.RecordRetry(session, q.ID, attempt, true); !imp.Counted {
+		t.Fatalf("Expected the retry to count under the penalize policy, got %+v", imp)
+	}
+	if got := session.Answers[0]; got.LatencyMs != 60000 || !got.Late {
+		t.Errorf("Expected the counted retry's latency and lateness, got %d %v", got.LatencyMs, got.Late)
+	}
+
+	// Nothing counts once the session is over
+	attempt.CreatedAt = session.SessionDeadline.Add(time.Minute)
+	if imp, _ = interview.RecordRetry(session, q.ID, attempt, true); imp.Counted {
+		t.Error("Expected a retry after the session deadline not to count")
+	}
+}
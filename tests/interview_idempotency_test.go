@@ -0,0 +1,50 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"altoai_mvp/interview"
+)
+
+func TestRememberAndReplayResponse(t *testing.T) {
+	s := interview.NewSession("idempotency-user")
+	now := time.Now()
+	hash := interview.RequestHash([]byte(`{"answer":"yes"}`))
+
+	if body, err := interview.StoredResponse(s, "k1", hash, now); body != nil || err != nil {
+		t.Fatalf("Unknown key should not match, got %s %v", body, err)
+	}
+
+	interview.RememberResponse(s, "k1", hash, []byte(`{"data":{}}`), now)
+	body, err := interview.StoredResponse(s, "k1", hash, now.Add(time.Minute))
+	if err != nil || string(body) != `{"data":{}}` {
+		t.Errorf("Expected stored body, got %s %v", body, err)
+	}
+
+	other := interview.RequestHash([]byte(`{"answer":"no"}`))
+	if _, err := interview.StoredResponse(s, "k1", other, now); err != interview.ErrIdempotencyKeyReused {
+		t.Errorf("Expected ErrIdempotencyKeyReused, got %v", err)
+	}
+}
+
+func TestIdempotencyKeysExpire(t *testing.T) {
+	t.Setenv("IDEMPOTENCY_KEY_TTL", "10m")
+	s := interview.NewSession("idempotency-ttl-user")
+	now := time.Now()
+	hash := interview.RequestHash(nil)
+
+	interview.RememberResponse(s, "old", hash, []byte(`{}`), now)
+	if body, _ := interview.StoredResponse(s, "old", hash, now.Add(11*time.Minute)); body != nil {
+		t.Error("Expired keys should not replay")
+	}
+
+	// Storing a new key prunes expired ones
+	interview.RememberResponse(s, "new", hash, []byte(`{}`), now.Add(11*time.Minute))
+	if _, ok := s.IdempotencyKeys["old"]; ok {
+		t.Error("Expired key should have been pruned")
+	}
+	if len(s.IdempotencyKeys) != 1 {
+		t.Errorf("Expected 1 stored key, got %d", len(s.IdempotencyKeys))
+	}
+}
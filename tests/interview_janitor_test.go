@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"altoai_mvp/interview"
+)
+
+// fakeArchive is an in-memory interview.SessionArchive
+type fakeArchive struct {
+	mu       sync.Mutex
+	sessions map[string]interview.Session
+	fail     bool
+}
+
+func newFakeArchive() *fakeArchive {
+	return &fakeArchive{sessions: map[string]interview.Session{}}
+}
+
+func (a *fakeArchive) ArchiveSession(s *interview.Session) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.fail {
+		return errors.New("archive unavailable")
+	}
+	a.sessions[s.ID] = *s
+	return nil
+}
+
+func (a *fakeArchive) GetArchivedSession(id string) (*interview.Session, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	s, ok := a.sessions[id]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return &s, nil
+}
+
+func (a *fakeArchive) ListArchivedSessions(userID string) ([]*interview.Session, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var out []*interview.Session
+	for _, s := range a.sessions {
+		if s.UserID == userID {
+			s := s
+			out = append(out, &s)
+		}
+	}
+	return out, nil
+}
+
+func TestSweepSessionsArchivesAndEvicts(t *testing.T) {
+	archive := newFakeArchive()
+	interview.SetSessionArchive(archive)
+	defer interview.SetSessionArchive(nil)
+
+	cfg := interview.JanitorConfig{Interval: time.Minute, IdleTTL: time.Hour, RetainTTL: 10 * time.Minute}
+
+	finished := interview.NewSession("janitor-user")
+	finished.Status = interview.SessionStatusFinished
+	interview.SaveSession(finished)
+
+	active := interview.NewSession("janitor-user")
+	interview.SaveSession(active)
+
+	stats := interview.SweepSessions(time.Now().Add(30*time.Minute), cfg)
+	if stats.Expired != 0 {
+		t.Errorf("Expected no expired sessions yet, got %d", stats.Expired)
+	}
+	if stats.Archived < 1 || stats.Evicted < 1 {
+		t.Errorf("Expected the finished session to be archived and evicted, got %+v", stats)
+	}
+
+	// Evicted sessions are still readable through the archive
+	got, ok := interview.GetSession(finished.ID)
+	if !ok || got.Status != interview.SessionStatusFinished {
+		t.Errorf("Expected archived session to be returned, got %v", got)
+	}
+	if _, ok := interview.GetSession(active.ID); !ok {
+		t.Error("Active session should stay in memory")
+	}
+
+	// Past the idle timeout the open session is aborted but kept for RetainTTL
+	interview.SweepSessions(time.Now().Add(2*time.Hour), cfg)
+	got, ok = interview.GetSession(active.ID)
+	if !ok || got.Status != interview.SessionStatusAborted {
+		t.Errorf("Idle session should be aborted, got %v", got)
+	}
+	if _, err := archive.GetArchivedSession(active.ID); err == nil {
+		t.Error("Aborted session should not be archived before RetainTTL passes")
+	}
+	// The request that still holds the open session can't save over the abort
+	if err := interview.SaveSession(active); err != interview.ErrSessionConflict {
+		t.Errorf("Expected a conflict saving a stale copy, got %v", err)
+	}
+
+	interview.SweepSessions(time.Now().Add(3*time.Hour), cfg)
+	if _, err := archive.GetArchivedSession(active.ID); err != nil {
+		t.Errorf("Expected the aborted session to be archived, got %v", err)
+	}
+	if len(interview.ListUserSessions("janitor-user")) != 2 {
+		t.Errorf("History should include archived sessions")
+	}
+}
+
+func TestSweepKeepsSessionsWhenArchiveFails(t *testing.T) {
+	archive := newFakeArchive()
+	archive.fail = true
+	interview.SetSessionArchive(archive)
+	defer interview.SetSessionArchive(nil)
+
+	s := interview.NewSession("archive-fail-user")
+	s.Status = interview.SessionStatusFinished
+	interview.SaveSession(s)
+
+	stats := interview.SweepSessions(time.Now().Add(2*time.Hour), interview.JanitorConfig{IdleTTL: time.Hour, RetainTTL: time.Hour})
+	if stats.ArchiveErrors == 0 {
+		t.Error("Expected archive errors to be counted")
+	}
+	if _, ok := interview.GetSession(s.ID); !ok {
+		t.Error("Session must stay in memory when archiving fails")
+	}
+}
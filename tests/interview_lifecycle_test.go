@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"altoai_mvp/interview"
+)
+
+func TestPauseAndResumeShiftsDeadlines(t *testing.T) {
+	err := interview.LoadQuestions("../interview/questions.json")
+	if err != nil {
+		t.Fatalf("LoadQuestions failed: %v", err)
+	}
+
+	session := interview.NewSessionWithOptions("pause-user", interview.SessionOptions{
+		Level:      "easy",
+		TimeLimits: &interview.TimeLimits{PerQuestionSeconds: 30, TotalSeconds: 300},
+	})
+	start := session.QuestionAskedAt
+	originalDeadline := *session.SessionDeadline
+
+	if err := interview.PauseSession(session, start.Add(20*time.Second)); err != nil {
+		t.Fatalf("PauseSession failed: %v", err)
+	}
+	if session.Status != interview.SessionStatusPaused {
+		t.Fatalf("Expected paused status, got %s", session.Status)
+	}
+
+	resumeAt := start.Add(2 * time.Hour)
+	if err := interview.ResumeSession(session, resumeAt); err != nil {
+		t.Fatalf("ResumeSession failed: %v", err)
+	}
+	if session.Status != interview.SessionStatusActive {
+		t.Errorf("Expected active status, got %s", session.Status)
+	}
+	if got := session.SessionDeadline.Sub(originalDeadline); got != 2*time.Hour-20*time.Second {
+		t.Errorf("Session deadline should move by the paused time, moved %s", got)
+	}
+	if timing := interview.CheckTiming(session, resumeAt.Add(5*time.Second)); timing.Late || timing.TimeUp || timing.Latency != 25*time.Second {
+		t.Errorf("Answer right after resuming should be on time with the pause left out of its latency, got %+v", timing)
+	}
+}
+
+func TestPauseAndResumeDoesNotAddQuestionTime(t *testing.T) {
+	err := interview.LoadQuestions("../interview/questions.json")
+	if err != nil {
+		t.Fatalf("LoadQuestions failed: %v", err)
+	}
+
+	session := interview.NewSessionWithOptions("pause-user", interview.SessionOptions{
+		Level:      "easy",
+		TimeLimits: &interview.TimeLimits{PerQuestionSeconds: 30, TotalSeconds: 300},
+	})
+	now := session.QuestionAskedAt
+
+	// Pausing every 20s would restart a 30s clock forever if resuming reset it
+	for i := 0; i < 3; i++ {
+		now = now.Add(10 * time.Second)
+		interview.PauseSession(session, now)
+		now = now.Add(time.Minute)
+		interview.ResumeSession(session, now)
+	}
+	if left := session.QuestionDeadline.Sub(now); left != 0 {
+		t.Errorf("Expected no question time left after 30s of answering, got %s", left)
+	}
+	if timing := interview.CheckTiming(session, now.Add(5*time.Second)); !timing.Late {
+		t.Errorf("Expected the answer to be late, got %+v", timing)
+	}
+}
+
+func TestIdleSessionsAreAborted(t *testing.T) {
+	session := interview.NewSession("idle-user")
+	now := session.UpdatedAt.Add(25 * time.Hour)
+
+	if !interview.ExpireIfIdle(session, now, 24*time.Hour) {
+		t.Fatal("Expected idle session to be aborted")
+	}
+	if session.Status != interview.SessionStatusAborted {
+		t.Errorf("Expected aborted status, got %s", session.Status)
+	}
+	if err := interview.ResumeSession(session, now); err != interview.ErrSessionNotResumable {
+		t.Errorf("Aborted sessions should not resume, got %v", err)
+	}
+}
+
+func TestLatestOpenSession(t *testing.T) {
+	older := interview.NewSession("resume-user")
+	interview.SaveSession(older)
+	time.Sleep(time.Millisecond)
+	newer := interview.NewSession("resume-user")
+	interview.PauseSession(newer, time.Now())
+	interview.SaveSession(newer)
+
+	finished := interview.NewSession("resume-user")
+	finished.Status = interview.SessionStatusFinished
+	interview.SaveSession(finished)
+
+	got, ok := interview.LatestOpenSession("resume-user", time.Now())
+	if !ok || got.ID != newer.ID {
+		t.Errorf("Expected the latest paused session %s, got %v", newer.ID, got)
+	}
+}
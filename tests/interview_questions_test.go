@@ -1,7 +1,10 @@
 package tests
 
 import (
+	"fmt"
+	"math/rand"
 	"testing"
+	"time"
 	"altoai_mvp/interview"
 )
 
@@ -244,3 +247,178 @@ func TestHardLevelSelection(t *testing.T) {
 	}
 }
 
+
+func TestSeededSelectionIsReproducible(t *testing.T) {
+	err := interview.LoadQuestions("../interview/questions.json")
+	if err != nil {
+		t.Fatalf("LoadQuestions failed: %v", err)
+	}
+
+	for _, level := range []string{"easy", "medium", "hard", ""} {
+		first := interview.SelectQuestionsWithSeed(level, 42)
+		second := interview.SelectQuestionsWithSeed(level, 42)
+		if len(first) != len(second) {
+			t.Fatalf("level %q: expected same length, got %d and %d", level, len(first), len(second))
+		}
+		for i := range first {
+			if first[i].ID != second[i].ID || first[i].Text != second[i].Text {
+				t.Errorf("level %q: question %d differs: %q vs %q", level, i, first[i].Text, second[i].Text)
+			}
+		}
+	}
+}
+
+// Seeds stored before history-weighted selection must keep replaying the same questions
+func TestSeededSelectionKeepsShuffleDraw(t *testing.T) {
+	err := interview.LoadQuestions("../interview/questions.json")
+	if err != nil {
+		t.Fatalf("LoadQuestions failed: %v", err)
+	}
+
+	rng := rand.New(rand.NewSource(7))
+	var want []string
+	for _, category := range interview.CategoryOrder {
+		available := append([]string(nil), interview.QuestionsByCategory[category]...)
+		rng.Shuffle(len(available), func(i, j int) {
+			available[i], available[j] = available[j], available[i]
+		})
+		want = append(want, available[0])
+	}
+
+	got := interview.SelectQuestionsWithSeed("medium", 7)[2:]
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d questions, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i].Text != want[i] {
+			t.Errorf("question %d: expected %q, got %q", i, want[i], got[i].Text)
+		}
+	}
+}
+
+func TestSessionStoresSeed(t *testing.T) {
+	err := interview.LoadQuestions("../interview/questions.json")
+	if err != nil {
+		t.Fatalf("LoadQuestions failed: %v", err)
+	}
+
+	seed := int64(2024)
+	a := interview.NewSessionWithOptions("student-a", interview.SessionOptions{Level: "hard", Seed: &seed})
+	b := interview.NewSessionWithOptions("student-b", interview.SessionOptions{Level: "hard", Seed: &seed})
+
+	if a.Seed == nil || b.Seed == nil || *a.Seed != seed || *b.Seed != seed {
+		t.Errorf("Expected seed %d on both sessions, got %v and %v", seed, a.Seed, b.Seed)
+	}
+	if a.BankVersion == "" || a.BankVersion != interview.QuestionBankVersion {
+		t.Errorf("Expected bank version %q, got %q", interview.QuestionBankVersion, a.BankVersion)
+	}
+	for i := range a.SelectedQuestions {
+		if a.SelectedQuestions[i].Text != b.SelectedQuestions[i].Text {
+			t.Errorf("Question %d differs between sessions with the same seed", i)
+		}
+	}
+
+	// Sessions without an explicit seed still record the one they used
+	c := interview.NewSession("student-c")
+	if c.Seed == nil {
+		t.Fatal("Expected a uniform session to record its seed")
+	}
+	replay := interview.SelectQuestionsWithSeed("", *c.Seed)
+	for i := range c.SelectedQuestions {
+		if c.SelectedQuestions[i].Text != replay[i].Text {
+			t.Errorf("Replaying stored seed should reproduce question %d", i)
+		}
+	}
+
+	// History changes the draw, so its seed would replay different questions
+	d := interview.NewSessionWithOptions("student-d", interview.SessionOptions{History: &interview.QuestionHistory{}})
+	if d.Seed != nil {
+		t.Errorf("Expected no seed on a history-weighted session, got %d", *d.Seed)
+	}
+}
+
+func TestHistoryAwareSelectionPrefersUnseenQuestions(t *testing.T) {
+	err := interview.LoadQuestions("../interview/questions.json")
+	if err != nil {
+		t.Fatalf("LoadQuestions failed: %v", err)
+	}
+
+	// Student already answered three of the four "Purpose of Study" questions today
+	category := "Purpose of Study"
+	pool := interview.QuestionsByCategory[category]
+	past := interview.NewSession("returning-student")
+	past.SelectedQuestions = nil
+	past.Answers = nil
+	for i, text := range pool[:3] {
+		id := fmt.Sprintf("q%d_past", i)
+		past.SelectedQuestions = append(past.SelectedQuestions, interview.Question{ID: id, Category: category, Text: text})
+		past.Answers = append(past.Answers, interview.Answer{
+			QuestionID:   id,
+			QuestionText: text,
+			Text:         "answer",
+			CreatedAt:    time.Now(),
+			Analysis:     &interview.AnalysisResponse{Scores: interview.AnalysisScores{TotalScore: 14}},
+		})
+	}
+
+	history := interview.BuildQuestionHistoryFromSessions([]*interview.Session{past}, time.Now())
+	unseen := pool[3]
+
+	hits := 0
+	for seed := int64(0); seed < 50; seed++ {
+		for _, q := range interview.SelectQuestionsForUser("easy", seed, history) {
+			if q.Category == category && q.Text == unseen {
+				hits++
+			}
+		}
+	}
+	if hits < 45 {
+		t.Errorf("Expected the unseen question to be picked almost always, got %d/50", hits)
+	}
+}
+
+func TestHistoryAwareSelectionBiasesWeakestCategory(t *testing.T) {
+	err := interview.LoadQuestions("../interview/questions.json")
+	if err != nil {
+		t.Fatalf("LoadQuestions failed: %v", err)
+	}
+
+	// Today the student answered one question poorly in each of two categories, and scored
+	// well elsewhere in the second, so only the first is their weakest
+	weak, other := "Financial Capability", "Purpose of Study"
+	past := interview.NewSession("returning-student")
+	past.SelectedQuestions = nil
+	past.Answers = nil
+	answer := func(category, text string, score int) {
+		id := fmt.Sprintf("q%d_past", len(past.Answers))
+		past.SelectedQuestions = append(past.SelectedQuestions, interview.Question{ID: id, Category: category, Text: text})
+		past.Answers = append(past.Answers, interview.Answer{
+			QuestionID:   id,
+			QuestionText: text,
+			Text:         "answer",
+			CreatedAt:    time.Now(),
+			Analysis:     &interview.AnalysisResponse{Scores: interview.AnalysisScores{TotalScore: score}},
+		})
+	}
+	weakText := interview.QuestionsByCategory[weak][0]
+	otherText := interview.QuestionsByCategory[other][0]
+	answer(weak, weakText, 5)
+	answer(other, otherText, 5)
+	answer(other, interview.QuestionsByCategory[other][1], 15)
+	answer(other, interview.QuestionsByCategory[other][2], 15)
+
+	history := interview.BuildQuestionHistoryFromSessions([]*interview.Session{past}, time.Now())
+	if got := history.WeakestCategory(); got != weak {
+		t.Fatalf("Expected weakest category %s, got %q", weak, got)
+	}
+	if w, o := history.Weight(weak, weakText), history.Weight(other, otherText); w <= o {
+		t.Errorf("Expected the weak category's poor answer to come back sooner: weight %v vs %v", w, o)
+	}
+
+	// The level's question counts stay fixed
+	for _, level := range []string{"easy", "medium", "hard"} {
+		if got, want := len(interview.SelectQuestionsForUser(level, 1, history)), len(interview.SelectQuestionsWithSeed(level, 1)); got != want {
+			t.Errorf("level %q: expected %d questions, got %d", level, want, got)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"altoai_mvp/internal/repository"
+	"altoai_mvp/interview"
+)
+
+// useReviewStore keeps review schedules in a fresh memory repo for the test and returns
+// the ID of a user in it
+func useReviewStore(t *testing.T) string {
+	t.Helper()
+	repo := repository.NewUserMemoryRepo()
+	user, err := repo.Create("reviewer@example.com", "Reviewer", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	interview.SetReviewStore(repo)
+	t.Cleanup(func() { interview.SetReviewStore(nil) })
+	return user.ID
+}
+
+func TestRecordReviewSchedulesWeakAnswersSooner(t *testing.T) {
+	userID := useReviewStore(t)
+	now := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	weakQ := interview.Question{ID: "q3_Financial_Capability", Category: "Financial Capability", Text: "Who is sponsoring you?"}
+	strongQ := interview.Question{ID: "q4_Purpose_of_Study", Category: "Purpose of Study", Text: "Why this major?"}
+
+	weak, err := interview.RecordReview(userID, weakQ, 6, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	strong, _ := interview.RecordReview(userID, strongQ, 15, now)
+
+	if weak.Interval != 1 || weak.Repetitions != 0 {
+		t.Errorf("Weak answer should be due tomorrow with no repetitions, got interval %d reps %d", weak.Interval, weak.Repetitions)
+	}
+	if strong.Repetitions != 1 {
+		t.Errorf("Strong answer should count as a successful repetition, got %d", strong.Repetitions)
+	}
+
+	// Second strong review moves to the 6-day interval
+	strong, _ = interview.RecordReview(userID, strongQ, 15, now.AddDate(0, 0, 1))
+	if strong.Interval != 6 {
+		t.Errorf("Expected 6 day interval after two good reviews, got %d", strong.Interval)
+	}
+
+	due, err := interview.DueReviews(userID, now.AddDate(0, 0, 2))
+	if err != nil || len(due) != 1 || due[0].Text != weakQ.Text {
+		t.Fatalf("Expected only the weak question to be due, got %+v, %v", due, err)
+	}
+
+	// College/major and ungraded answers are never scheduled
+	if item, _ := interview.RecordReview(userID, interview.Question{ID: "q0_major", Text: "What is your major?"}, 5, now); item != nil {
+		t.Error("Fixed questions should not be scheduled")
+	}
+}
+
+func TestDueReviewsIncludesLaterToday(t *testing.T) {
+	userID := useReviewStore(t)
+	q := interview.Question{ID: "q2_University_Choice", Category: "University Choice", Text: "Why this university?"}
+	// A weak answer the evening before is due at the same time today
+	interview.RecordReview(userID, q, 6, time.Date(2025, 3, 1, 21, 0, 0, 0, time.UTC))
+
+	if due, _ := interview.DueReviews(userID, time.Date(2025, 3, 1, 23, 0, 0, 0, time.UTC)); len(due) != 0 {
+		t.Errorf("Expected nothing due on the day of the answer, got %+v", due)
+	}
+	if due, _ := interview.DueReviews(userID, time.Date(2025, 3, 2, 8, 0, 0, 0, time.UTC)); len(due) != 1 {
+		t.Errorf("Expected the question due this evening to be listed this morning, got %+v", due)
+	}
+}
+
+func TestRecordReviewNeedsAKnownUser(t *testing.T) {
+	useReviewStore(t)
+	q := interview.Question{ID: "q1_Immigration_Intent", Category: "Immigration Intent", Text: "Will you return home?"}
+	if _, err := interview.RecordReview("no-such-user", q, 6, time.Now()); err != repository.ErrNotFound {
+		t.Errorf("Expected ErrNotFound for an unknown user, got %v", err)
+	}
+}
+
+func TestNewPracticeSessionUsesDueQuestions(t *testing.T) {
+	userID := useReviewStore(t)
+	now := time.Now()
+	if s, err := interview.NewPracticeSession(userID, 5, now); s != nil || err != nil {
+		t.Fatalf("Expected no practice session when nothing is due, got %v", err)
+	}
+
+	for i, text := range []string{"Q one", "Q two", "Q three"} {
+		q := interview.Question{ID: "q" + string(rune('1'+i)), Category: "Immigration Intent", Text: text}
+		interview.RecordReview(userID, q, 5, now.AddDate(0, 0, -2))
+	}
+
+	s, err := interview.NewPracticeSession(userID, 2, now)
+	if err != nil || s == nil {
+		t.Fatalf("Expected a practice session, got %v", err)
+	}
+	if s.Type != interview.SessionTypePractice {
+		t.Errorf("Expected session type %s, got %s", interview.SessionTypePractice, s.Type)
+	}
+	if len(s.SelectedQuestions) != 2 {
+		t.Errorf("Expected 2 questions, got %d", len(s.SelectedQuestions))
+	}
+	if s.CurrentQuestion != s.SelectedQuestions[0].ID {
+		t.Error("Practice session should start at its first question")
+	}
+}
@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"altoai_mvp/interview"
+)
+
+func TestTimedSessionDeadlines(t *testing.T) {
+	err := interview.LoadQuestions("../interview/questions.json")
+	if err != nil {
+		t.Fatalf("LoadQuestions failed: %v", err)
+	}
+
+	session := interview.NewSessionWithOptions("timed-user", interview.SessionOptions{
+		Level:      "easy",
+		TimeLimits: &interview.TimeLimits{PerQuestionSeconds: 30, TotalSeconds: 120},
+	})
+	if session.TimeLimits == nil || session.TimeLimits.LatePolicy != interview.LatePolicyPenalize {
+		t.Fatalf("Expected timed session with default penalize policy, got %+v", session.TimeLimits)
+	}
+	if session.QuestionDeadline == nil || session.SessionDeadline == nil {
+		t.Fatal("Timed session should have question and session deadlines")
+	}
+
+	asked := session.QuestionAskedAt
+	onTime := interview.CheckTiming(session, asked.Add(10*time.Second))
+	if onTime.Late || onTime.TimeUp {
+		t.Errorf("Answer after 10s should be on time, got %+v", onTime)
+	}
+	if onTime.Latency != 10*time.Second {
+		t.Errorf("Expected latency 10s, got %s", onTime.Latency)
+	}
+
+	late := interview.CheckTiming(session, asked.Add(40*time.Second))
+	if !late.Late || late.TimeUp {
+		t.Errorf("Answer after 40s should be late but within budget, got %+v", late)
+	}
+
+	over := interview.CheckTiming(session, asked.Add(3*time.Minute))
+	if !over.TimeUp {
+		t.Error("Answer after the session budget should be rejected as time up")
+	}
+
+	// The next question gets a fresh deadline
+	next := interview.AdvanceQuestion(session, asked.Add(20*time.Second))
+	if next == nil {
+		t.Fatal("Expected a next question")
+	}
+	if !session.QuestionDeadline.Equal(asked.Add(50 * time.Second)) {
+		t.Errorf("Expected question deadline 30s after it was shown, got %s", session.QuestionDeadline.Sub(asked))
+	}
+}
+
+func TestUntimedSessionIsNeverLate(t *testing.T) {
+	session := interview.NewSession("untimed-user")
+	timing := interview.CheckTiming(session, session.QuestionAskedAt.Add(time.Hour))
+	if timing.Late || timing.TimeUp {
+		t.Errorf("Untimed sessions should never be late, got %+v", timing)
+	}
+	if timing.Latency != time.Hour {
+		t.Errorf("Latency should still be recorded, got %s", timing.Latency)
+	}
+}
+
+func TestApplyTimingFeedbackPenalizesLateAnswers(t *testing.T) {
+	limits := &interview.TimeLimits{PerQuestionSeconds: 30, TotalSeconds: 300, LatePolicy: interview.LatePolicyPenalize}
+	analysis := &interview.AnalysisResponse{
+		Scores:         interview.AnalysisScores{MigrationIntent: 5, GoalUnderstanding: 4, AnswerLength: 4, TotalScore: 13},
+		Classification: "Good",
+	}
+
+	interview.ApplyTimingFeedback(analysis, limits, interview.AnswerTiming{Latency: 45 * time.Second, Late: true})
+
+	if analysis.Scores.TotalScore != 13-interview.LatePenaltyPoints {
+		t.Errorf("Expected penalised score %d, got %d", 13-interview.LatePenaltyPoints, analysis.Scores.TotalScore)
+	}
+	if analysis.Classification != "Average" {
+		t.Errorf("Expected classification to drop to Average, got %s", analysis.Classification)
+	}
+	if len(analysis.Feedback.Improvements) != 1 {
+		t.Errorf("Expected latency feedback, got %v", analysis.Feedback.Improvements)
+	}
+}
@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"altoai_mvp/internal/middleware"
+	"altoai_mvp/internal/token"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestJWTAuthRejectsOutdatedTokenVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tokens := newTestTokens(t)
+
+	versions := map[string]int{"user-1": 1}
+	middleware.SetTokenVersionSource(func(ctx context.Context, userID string) (int, error) {
+		v, ok := versions[userID]
+		if !ok {
+			return 0, errors.New("not found")
+		}
+		return v, nil
+	})
+	defer middleware.SetTokenVersionSource(nil)
+
+	r := gin.New()
+	r.GET("/me", middleware.JWTAuth(tokens), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	cases := []struct {
+		name    string
+		claims  token.Claims
+		refresh bool
+		want    int
+	}{
+		{"current version", token.Claims{Version: 1, RegisteredClaims: jwt.RegisteredClaims{Subject: "user-1"}}, false, http.StatusOK},
+		{"logged out everywhere", token.Claims{Version: 0, RegisteredClaims: jwt.RegisteredClaims{Subject: "user-1"}}, false, http.StatusUnauthorized},
+		{"no subject", token.Claims{Email: "a@example.com"}, false, http.StatusUnauthorized},
+		{"unknown user", token.Claims{Version: 5, RegisteredClaims: jwt.RegisteredClaims{Subject: "ghost"}}, false, http.StatusUnauthorized},
+		{"refresh token", token.Claims{Version: 1, RegisteredClaims: jwt.RegisteredClaims{Subject: "user-1"}}, true, http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		issue := tokens.IssueAccess
+		if tc.refresh {
+			issue = tokens.IssueRefresh
+		}
+		raw, _, err := issue(tc.claims)
+		if err != nil {
+			t.Fatalf("%s: signing failed: %v", tc.name, err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+raw)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, w.Code)
+		}
+	}
+}
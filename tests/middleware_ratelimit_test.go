@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"altoai_mvp/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMemoryRateLimitStoreRefills(t *testing.T) {
+	store := middleware.NewMemoryRateLimitStore()
+	limit := middleware.RateLimit{Burst: 3, Period: 3 * time.Second}
+	ctx := context.Background()
+	start := time.Now()
+
+	for i := 0; i < 3; i++ {
+		res, _ := store.Take(ctx, "k", limit, start)
+		if !res.Allowed || res.Remaining != 2-i {
+			t.Fatalf("Request %d: expected allowed with %d remaining, got %+v", i+1, 2-i, res)
+		}
+	}
+	res, _ := store.Take(ctx, "k", limit, start)
+	if res.Allowed || res.RetryAfter != time.Second {
+		t.Fatalf("Expected the fourth request to wait one second, got %+v", res)
+	}
+	if res.Reset != 3*time.Second {
+		t.Errorf("Expected the bucket to be full again in 3s, got %v", res.Reset)
+	}
+
+	// One token comes back per second
+	if res, _ := store.Take(ctx, "k", limit, start.Add(time.Second)); !res.Allowed {
+		t.Errorf("Expected a refilled token after a second, got %+v", res)
+	}
+	if res, _ := store.Take(ctx, "k", limit, start.Add(time.Second)); res.Allowed {
+		t.Errorf("Expected only one token to have refilled, got %+v", res)
+	}
+	if res, _ := store.Take(ctx, "other", limit, start); !res.Allowed {
+		t.Error("Expected other keys to have their own bucket")
+	}
+}
+
+func newRateLimitedRouter(limit middleware.RateLimit) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	store := middleware.NewMemoryRateLimitStore()
+	r := gin.New()
+	setUser := func(c *gin.Context) {
+		if id := c.GetHeader("X-Test-User"); id != "" {
+			claims := &middleware.MyClaims{}
+			claims.Subject = id
+			c.Set("user", claims)
+		}
+	}
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.POST("/email", middleware.RateLimiter(store, "email", limit, middleware.ByIP), ok)
+	r.POST("/login", middleware.RateLimiter(store, "auth", limit, middleware.ByIP), ok)
+	r.POST("/chat", setUser, middleware.RateLimiter(store, "chat", limit, middleware.ByUser), ok)
+	return r
+}
+
+func hit(r *gin.Engine, path, ip, user string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, nil)
+	req.RemoteAddr = ip + ":1234"
+	if user != "" {
+		req.Header.Set("X-Test-User", user)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimiterHeaders(t *testing.T) {
+	r := newRateLimitedRouter(middleware.RateLimit{Burst: 2, Period: time.Minute})
+
+	w := hit(r, "/email", "192.0.2.1", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the first request through, got %d", w.Code)
+	}
+	for header, want := range map[string]string{
+		"RateLimit-Limit":     "2",
+		"RateLimit-Remaining": "1",
+		"RateLimit-Reset":     "30",
+		"RateLimit-Policy":    "2;w=60",
+	} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("%s: expected %q, got %q", header, want, got)
+		}
+	}
+
+	hit(r, "/email", "192.0.2.1", "")
+	w = hit(r, "/email", "192.0.2.1", "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 once the bucket is empty, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("Expected Retry-After 30, got %q", got)
+	}
+	if got := w.Header().Get("RateLimit-Remaining"); got != "0" {
+		t.Errorf("Expected RateLimit-Remaining 0, got %q", got)
+	}
+}
+
+func TestRateLimiterKeysByIPUserAndGroup(t *testing.T) {
+	r := newRateLimitedRouter(middleware.RateLimit{Burst: 1, Period: time.Minute})
+
+	hit(r, "/email", "192.0.2.1", "")
+	if w := hit(r, "/email", "192.0.2.1", ""); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Same IP: expected 429, got %d", w.Code)
+	}
+	if w := hit(r, "/email", "192.0.2.2", ""); w.Code != http.StatusOK {
+		t.Errorf("Other IP: expected 200, got %d", w.Code)
+	}
+	if w := hit(r, "/login", "192.0.2.1", ""); w.Code != http.StatusOK {
+		t.Errorf("Other route group: expected 200, got %d", w.Code)
+	}
+
+	// Chat is budgeted per user, wherever they connect from
+	hit(r, "/chat", "192.0.2.1", "alice")
+	if w := hit(r, "/chat", "192.0.2.9", "alice"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Same user, other IP: expected 429, got %d", w.Code)
+	}
+	if w := hit(r, "/chat", "192.0.2.1", "bob"); w.Code != http.StatusOK {
+		t.Errorf("Other user, same IP: expected 200, got %d", w.Code)
+	}
+}
+
+func TestParseRateLimit(t *testing.T) {
+	l, err := middleware.ParseRateLimit("5/15m")
+	if err != nil || l.Burst != 5 || l.Period != 15*time.Minute {
+		t.Errorf("Expected 5 per 15m, got %+v, %v", l, err)
+	}
+	for _, bad := range []string{"", "5", "0/1m", "x/1m", "5/soon", "5/-1m"} {
+		if _, err := middleware.ParseRateLimit(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+
+	t.Setenv("RATE_LIMIT_CHAT", "50/1h")
+	fallback := middleware.RateLimit{Burst: 20, Period: time.Minute}
+	if l, _ := middleware.RateLimitFromEnv("chat", fallback); l.Burst != 50 || l.Period != time.Hour {
+		t.Errorf("Expected RATE_LIMIT_CHAT to override the default, got %+v", l)
+	}
+	if l, _ := middleware.RateLimitFromEnv("email", fallback); l != fallback {
+		t.Errorf("Expected the default without an env var, got %+v", l)
+	}
+}
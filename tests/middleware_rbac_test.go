@@ -0,0 +1,187 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"altoai_mvp/internal/handlers"
+	"altoai_mvp/internal/middleware"
+	"altoai_mvp/internal/models"
+	"altoai_mvp/internal/repository"
+	"altoai_mvp/internal/services"
+	"altoai_mvp/interview"
+
+	"github.com/gin-gonic/gin"
+)
+
+type rbacFixture struct {
+	r       *gin.Engine
+	repo    repository.UserRepo
+	auth    services.AuthService
+	users   map[models.Role]models.User
+	student models.User // a second student, not assigned to anyone
+}
+
+// newRBACRouter wires the user and student routes as router.New does, with one user per role
+func newRBACRouter(t *testing.T) *rbacFixture {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	repo := repository.NewUserMemoryRepo()
+	tokens := newTestTokens(t)
+	middleware.SetTokenVersionSource(func(ctx context.Context, userID string) (int, error) {
+		u, err := repo.Get(userID)
+		return u.TokenVersion, err
+	})
+	t.Cleanup(func() { middleware.SetTokenVersionSource(nil) })
+
+	f := &rbacFixture{repo: repo, auth: services.NewAuthService(repo, tokens), users: map[models.Role]models.User{}}
+	for _, role := range []models.Role{models.RoleStudent, models.RoleCounsellor, models.RoleAdmin} {
+		u, _ := repo.Create(string(role)+"@example.com", string(role), "hash")
+		u, _ = repo.SetRole(u.ID, role)
+		f.users[role] = u
+	}
+	f.student, _ = repo.Create("other@example.com", "Other", "hash")
+
+	userSvc := services.NewUserService(repo)
+	userH := handlers.NewUserHandler(userSvc)
+	studentH := handlers.NewStudentHandler(userSvc, services.NewCounsellorService(repo))
+	authRequired := middleware.JWTAuth(tokens)
+	adminOnly := middleware.RequirePermission(middleware.PermManageUsers)
+
+	r := gin.New()
+	r.GET("/users", authRequired, adminOnly, userH.List)
+	r.DELETE("/users/:id", authRequired, adminOnly, userH.Delete)
+	r.PUT("/users/:id/role", authRequired, adminOnly, userH.SetRole)
+	r.GET("/students", authRequired, middleware.RequirePermission(middleware.PermViewStudents), studentH.List)
+	r.GET("/students/:id/interviews", authRequired, middleware.RequirePermission(middleware.PermViewStudents), studentH.Interviews)
+	r.PUT("/counsellors/:id/students/:studentId", authRequired, middleware.RequirePermission(middleware.PermManageAssignment), studentH.Assign)
+	f.r = r
+	return f
+}
+
+func (f *rbacFixture) token(t *testing.T, u models.User) string {
+	t.Helper()
+	u, _ = f.repo.Get(u.ID)
+	access, _, err := f.auth.IssueTokens(context.Background(), u, "")
+	if err != nil {
+		t.Fatalf("IssueTokens failed: %v", err)
+	}
+	return access
+}
+
+func (f *rbacFixture) do(method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	f.r.ServeHTTP(w, req)
+	return w
+}
+
+func TestUserManagementIsAdminOnly(t *testing.T) {
+	f := newRBACRouter(t)
+
+	if w := f.do(http.MethodGet, "/users", "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Anonymous: expected 401, got %d", w.Code)
+	}
+	for _, role := range []models.Role{models.RoleStudent, models.RoleCounsellor} {
+		tok := f.token(t, f.users[role])
+		if w := f.do(http.MethodGet, "/users", tok, ""); w.Code != http.StatusForbidden {
+			t.Errorf("%s listing users: expected 403, got %d", role, w.Code)
+		}
+		if w := f.do(http.MethodDelete, "/users/"+f.student.ID, tok, ""); w.Code != http.StatusForbidden {
+			t.Errorf("%s deleting a user: expected 403, got %d", role, w.Code)
+		}
+	}
+	if w := f.do(http.MethodGet, "/users", f.token(t, f.users[models.RoleAdmin]), ""); w.Code != http.StatusOK {
+		t.Errorf("Admin listing users: expected 200, got %d", w.Code)
+	}
+}
+
+func TestSetRoleInvalidatesOldTokens(t *testing.T) {
+	f := newRBACRouter(t)
+	admin := f.token(t, f.users[models.RoleAdmin])
+	before := f.token(t, f.student)
+
+	w := f.do(http.MethodPut, "/users/"+f.student.ID+"/role", admin, `{"role":"counsellor"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected role change to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	if u, _ := f.repo.Get(f.student.ID); u.Role != models.RoleCounsellor {
+		t.Errorf("Expected counsellor role, got %q", u.Role)
+	}
+	if w := f.do(http.MethodGet, "/students", before, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the token carrying the old role to be rejected, got %d", w.Code)
+	}
+	if w := f.do(http.MethodGet, "/students", f.token(t, f.student), ""); w.Code != http.StatusOK {
+		t.Errorf("Expected a fresh token to carry the counsellor role, got %d", w.Code)
+	}
+
+	if w := f.do(http.MethodPut, "/users/"+f.student.ID+"/role", admin, `{"role":"superuser"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Unknown role: expected 400, got %d", w.Code)
+	}
+	self := f.users[models.RoleAdmin].ID
+	if w := f.do(http.MethodPut, "/users/"+self+"/role", admin, `{"role":"student"}`); w.Code != http.StatusConflict {
+		t.Errorf("Self-demotion: expected 409, got %d", w.Code)
+	}
+}
+
+func TestCounsellorSeesOnlyAssignedStudents(t *testing.T) {
+	f := newRBACRouter(t)
+	student := f.users[models.RoleStudent]
+	counsellor := f.users[models.RoleCounsellor]
+
+	s := interview.NewSessionWithOptions(student.ID, interview.SessionOptions{
+		Questions: []interview.Question{{ID: "q1", Category: "Purpose", Text: "Why this university?"}},
+	})
+	if err := interview.SaveSession(s); err != nil {
+		t.Fatal(err)
+	}
+
+	cTok := f.token(t, counsellor)
+	if w := f.do(http.MethodGet, "/students/"+student.ID+"/interviews", cTok, ""); w.Code != http.StatusNotFound {
+		t.Errorf("Unassigned student: expected 404, got %d", w.Code)
+	}
+	if w := f.do(http.MethodPut, "/counsellors/"+counsellor.ID+"/students/"+student.ID, cTok, ""); w.Code != http.StatusForbidden {
+		t.Errorf("Counsellor assigning: expected 403, got %d", w.Code)
+	}
+
+	admin := f.token(t, f.users[models.RoleAdmin])
+	if w := f.do(http.MethodPut, "/counsellors/"+counsellor.ID+"/students/"+student.ID, admin, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("Admin assigning: expected 204, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := f.do(http.MethodPut, "/counsellors/"+student.ID+"/students/"+f.student.ID, admin, ""); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Assigning to a non-counsellor: expected 422, got %d", w.Code)
+	}
+
+	w := f.do(http.MethodGet, "/students/"+student.ID+"/interviews", cTok, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Assigned student: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data struct {
+			Student  models.User         `json:"student"`
+			Sessions []interview.Session `json:"sessions"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Data.Student.ID != student.ID || len(resp.Data.Sessions) != 1 || resp.Data.Sessions[0].ID != s.ID {
+		t.Errorf("Expected the student's session, got %s", w.Body.String())
+	}
+
+	if w := f.do(http.MethodGet, "/students/"+f.student.ID+"/interviews", cTok, ""); w.Code != http.StatusNotFound {
+		t.Errorf("Other student: expected 404, got %d", w.Code)
+	}
+	if w := f.do(http.MethodGet, "/students/"+student.ID+"/interviews", f.token(t, student), ""); w.Code != http.StatusForbidden {
+		t.Errorf("Student reading results through the counsellor route: expected 403, got %d", w.Code)
+	}
+	if w := f.do(http.MethodGet, "/students/"+f.student.ID+"/interviews", admin, ""); w.Code != http.StatusOK {
+		t.Errorf("Admin reading any student: expected 200, got %d", w.Code)
+	}
+}
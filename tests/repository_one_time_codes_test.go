@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"altoai_mvp/internal/models"
+	"altoai_mvp/internal/repository"
+)
+
+func newCodeTestUser(t *testing.T) (repository.UserRepo, models.User) {
+	t.Helper()
+	repo := repository.NewUserMemoryRepo()
+	user, err := repo.Create("codes@example.com", "Codes", "hash")
+	if err != nil {
+		t.Fatalf("Create user failed: %v", err)
+	}
+	return repo, user
+}
+
+func TestOneTimeCodeWorksOnce(t *testing.T) {
+	repo, user := newCodeTestUser(t)
+	repo.SetOneTimeCode(user.ID, models.CodePurposeVerifyEmail, "123456", time.Now().UTC().Add(15*time.Minute))
+
+	if err := repo.VerifyEmail(user.Email, "123456"); err != nil {
+		t.Fatalf("Expected the code to verify the email, got %v", err)
+	}
+	if u, _ := repo.Get(user.ID); !u.EmailVerified {
+		t.Error("Expected the email to be verified")
+	}
+	if err := repo.VerifyEmail(user.Email, "123456"); !errors.Is(err, repository.ErrInvalidVerificationCode) {
+		t.Errorf("Expected a used code to be rejected, got %v", err)
+	}
+}
+
+func TestOneTimeCodeDeletedAfterMaxAttempts(t *testing.T) {
+	repo, user := newCodeTestUser(t)
+	now := time.Now().UTC()
+	repo.SetOneTimeCode(user.ID, models.CodePurposeResetPassword, "654321", now.Add(15*time.Minute))
+
+	for i := 0; i < repository.MaxCodeAttempts; i++ {
+		if err := repo.ConsumeOneTimeCode(user.ID, models.CodePurposeResetPassword, "000000", now); !errors.Is(err, repository.ErrInvalidCode) {
+			t.Fatalf("Guess %d: expected an invalid code, got %v", i+1, err)
+		}
+	}
+	if err := repo.ConsumeOneTimeCode(user.ID, models.CodePurposeResetPassword, "654321", now); !errors.Is(err, repository.ErrInvalidCode) {
+		t.Errorf("Expected the code to be gone after %d wrong guesses, got %v", repository.MaxCodeAttempts, err)
+	}
+}
+
+func TestOneTimeCodeReplacedAndScopedToPurpose(t *testing.T) {
+	repo, user := newCodeTestUser(t)
+	now := time.Now().UTC()
+	repo.SetOneTimeCode(user.ID, models.CodePurposeResetPassword, "111111", now.Add(15*time.Minute))
+	repo.SetOneTimeCode(user.ID, models.CodePurposeResetPassword, "222222", now.Add(15*time.Minute))
+	repo.SetOneTimeCode(user.ID, models.CodePurposeVerifyEmail, "333333", now.Add(15*time.Minute))
+
+	if err := repo.ResetPassword(user.Email, "111111", "new hash"); !errors.Is(err, repository.ErrInvalidResetCode) {
+		t.Errorf("Expected the replaced code to be rejected, got %v", err)
+	}
+	if err := repo.ResetPassword(user.Email, "333333", "new hash"); !errors.Is(err, repository.ErrInvalidResetCode) {
+		t.Errorf("Expected a verification code not to reset the password, got %v", err)
+	}
+	if err := repo.ResetPassword(user.Email, "222222", "new hash"); err != nil {
+		t.Fatalf("Expected the latest code to reset the password, got %v", err)
+	}
+	if u, _ := repo.Get(user.ID); u.Password != "new hash" {
+		t.Error("Expected the password to be updated")
+	}
+}
+
+func TestOneTimeCodeExpires(t *testing.T) {
+	repo, user := newCodeTestUser(t)
+	repo.SetOneTimeCode(user.ID, models.CodePurposeVerifyEmail, "123456", time.Now().UTC().Add(-time.Second))
+
+	if err := repo.VerifyEmail(user.Email, "123456"); !errors.Is(err, repository.ErrVerificationCodeExpired) {
+		t.Errorf("Expected an expired code, got %v", err)
+	}
+	if err := repo.SetOneTimeCode("missing", models.CodePurposeVerifyEmail, "123456", time.Now()); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown user, got %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"altoai_mvp/internal/repository"
+	"altoai_mvp/internal/services"
+)
+
+func TestListSessionsGroupsRotatedTokens(t *testing.T) {
+	svc, repo, user := newRefreshTestService(t)
+	laptopCtx := services.WithClientInfo(context.Background(), services.ClientInfo{
+		UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0 Safari/537.36",
+		IP:        "198.51.100.1",
+	})
+	phoneCtx := services.WithClientInfo(context.Background(), services.ClientInfo{
+		UserAgent: "Mozilla/5.0 (Linux; Android 14) Chrome/120.0 Mobile Safari/537.36",
+		IP:        "198.51.100.2",
+	})
+
+	_, laptop, _ := svc.IssueTokens(laptopCtx, user, "")
+	_, laptop, _ = svc.RefreshToken(laptopCtx, laptop)
+	_, phone, _ := svc.IssueTokens(phoneCtx, user, "")
+
+	sessions, err := svc.ListSessions(context.Background(), user.ID, phone)
+	if err != nil {
+		t.Fatalf("ListSessions failed: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("Expected 2 devices, got %d", len(sessions))
+	}
+	current := 0
+	for _, s := range sessions {
+		if s.Current {
+			current++
+			if s.Device != "Chrome on Android" || s.IP != "198.51.100.2" {
+				t.Errorf("Current session should be the phone, got %+v", s)
+			}
+		}
+	}
+	if current != 1 {
+		t.Errorf("Expected exactly one current session, got %d", current)
+	}
+
+	// Another user can't revoke these sessions
+	other, _ := repo.Create("other@example.com", "Other", "")
+	if err := svc.RevokeSession(context.Background(), other.ID, sessions[0].ID); err != repository.ErrNotFound {
+		t.Errorf("Expected ErrNotFound for someone else's session, got %v", err)
+	}
+
+	for _, s := range sessions {
+		if !s.Current {
+			if err := svc.RevokeSession(context.Background(), user.ID, s.ID); err != nil {
+				t.Fatalf("RevokeSession failed: %v", err)
+			}
+		}
+	}
+	if _, _, err := svc.RefreshToken(context.Background(), laptop); err == nil {
+		t.Error("Revoked device should not refresh")
+	}
+	sessions, _ = svc.ListSessions(context.Background(), user.ID, "")
+	if len(sessions) != 1 {
+		t.Errorf("Expected 1 remaining device, got %d", len(sessions))
+	}
+}
+
+func TestLogoutAllRevokesEverything(t *testing.T) {
+	svc, repo, user := newRefreshTestService(t)
+	ctx := context.Background()
+
+	_, first, _ := svc.IssueTokens(ctx, user, "")
+	_, second, _ := svc.IssueTokens(ctx, user, "")
+
+	if err := svc.LogoutAll(ctx, user.ID); err != nil {
+		t.Fatalf("LogoutAll failed: %v", err)
+	}
+	for _, token := range []string{first, second} {
+		if _, _, err := svc.RefreshToken(ctx, token); err == nil {
+			t.Error("Refresh tokens should be revoked after logging out everywhere")
+		}
+	}
+	updated, _ := repo.Get(user.ID)
+	if updated.TokenVersion != user.TokenVersion+1 {
+		t.Errorf("Expected token version %d, got %d", user.TokenVersion+1, updated.TokenVersion)
+	}
+	if sessions, _ := svc.ListSessions(ctx, user.ID, ""); len(sessions) != 0 {
+		t.Errorf("Expected no sessions, got %d", len(sessions))
+	}
+}
@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"altoai_mvp/internal/handlers"
+	"altoai_mvp/internal/middleware"
+	"altoai_mvp/internal/models"
+	"altoai_mvp/internal/repository"
+	"altoai_mvp/internal/services"
+	"altoai_mvp/internal/token"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newEmailChangeTestService(t *testing.T) (services.AuthService, repository.UserRepo, *recordingEmail, *token.Service, models.User) {
+	t.Helper()
+	repo := repository.NewUserMemoryRepo()
+	hash, _ := bcrypt.GenerateFromPassword([]byte(lockoutPassword), bcrypt.MinCost)
+	user, _ := repo.Create("old@example.com", "Mover", string(hash))
+	repo.MarkEmailVerified(user.Email)
+	tokens := newTestTokens(t)
+	email := &recordingEmail{}
+	return services.NewAuthServiceWithEmail(repo, tokens, email), repo, email, tokens, user
+}
+
+func TestEmailChangeConfirmedByNewAddress(t *testing.T) {
+	svc, repo, email, tokens, user := newEmailChangeTestService(t)
+	repo.Create("taken@example.com", "Other", "")
+
+	cases := map[string]struct {
+		dto  models.ChangeEmailDTO
+		want error
+	}{
+		"wrong password": {models.ChangeEmailDTO{NewEmail: "new@example.com", CurrentPassword: "wrong"}, services.ErrWrongPassword},
+		"same email":     {models.ChangeEmailDTO{NewEmail: "OLD@example.com", CurrentPassword: lockoutPassword}, services.ErrSameEmail},
+		"taken email":    {models.ChangeEmailDTO{NewEmail: "taken@example.com", CurrentPassword: lockoutPassword}, services.ErrEmailTaken},
+	}
+	for name, tc := range cases {
+		if err := svc.RequestEmailChange(clientCtx(""), user.ID, tc.dto); !errors.Is(err, tc.want) {
+			t.Errorf("%s: expected %v, got %v", name, tc.want, err)
+		}
+	}
+
+	dto := models.ChangeEmailDTO{NewEmail: "new@example.com", CurrentPassword: lockoutPassword}
+	if err := svc.RequestEmailChange(clientCtx(""), user.ID, dto); err != nil {
+		t.Fatalf("RequestEmailChange failed: %v", err)
+	}
+	code := email.code("new@example.com")
+	if code == "" {
+		t.Fatal("Expected a code at the new address")
+	}
+	if u, _ := repo.Get(user.ID); u.Email != user.Email {
+		t.Errorf("Expected the email to stay %s until confirmed, got %s", user.Email, u.Email)
+	}
+
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+	if _, _, _, err := svc.ConfirmEmailChange(clientCtx(""), user.ID, models.ConfirmEmailChangeDTO{Code: wrong}); err == nil {
+		t.Fatal("Expected a wrong code to be rejected")
+	}
+	access, _, got, err := svc.ConfirmEmailChange(clientCtx(""), user.ID, models.ConfirmEmailChangeDTO{Code: code})
+	if err != nil {
+		t.Fatalf("ConfirmEmailChange failed: %v", err)
+	}
+	if got.ID != user.ID || got.Email != "new@example.com" || !got.EmailVerified {
+		t.Errorf("Expected the same account at the new, verified email, got %+v", got)
+	}
+	if claims, err := tokens.ParseAccess(access); err != nil || claims.Email != "new@example.com" {
+		t.Errorf("Expected the new access token to carry the new email, got %v", err)
+	}
+	if email.code("old@example.com") != "new@example.com" {
+		t.Error("Expected the old address to be told about the change")
+	}
+	if err := login(svc, clientCtx(""), "new@example.com", lockoutPassword); err != nil {
+		t.Errorf("Expected to log in with the new email, got %v", err)
+	}
+	if _, _, _, err := svc.ConfirmEmailChange(clientCtx(""), user.ID, models.ConfirmEmailChangeDTO{Code: code}); err == nil {
+		t.Error("Expected the code to work only once")
+	}
+}
+
+func TestEmailChangeKeepsCodeWhenAddressTakenMeanwhile(t *testing.T) {
+	svc, repo, email, _, user := newEmailChangeTestService(t)
+	dto := models.ChangeEmailDTO{NewEmail: "new@example.com", CurrentPassword: lockoutPassword}
+	if err := svc.RequestEmailChange(clientCtx(""), user.ID, dto); err != nil {
+		t.Fatal(err)
+	}
+	code := email.code("new@example.com")
+
+	other, _ := repo.Create("New@Example.com", "Quicker", "")
+	if _, _, _, err := svc.ConfirmEmailChange(clientCtx(""), user.ID, models.ConfirmEmailChangeDTO{Code: code}); !errors.Is(err, services.ErrEmailTaken) {
+		t.Fatalf("Expected ErrEmailTaken, got %v", err)
+	}
+	repo.Delete(other.ID)
+	if _, _, _, err := svc.ConfirmEmailChange(clientCtx(""), user.ID, models.ConfirmEmailChangeDTO{Code: code}); err != nil {
+		t.Errorf("Expected the kept code to work once the address is free, got %v", err)
+	}
+}
+
+func TestUpdateProfileFindsUserByIDAndRefusesEmail(t *testing.T) {
+	repo := repository.NewUserMemoryRepo()
+	user, _ := repo.Create("new@example.com", "Mover", "")
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.PUT("/users/me/profile", func(c *gin.Context) {
+		// A token issued before the email changed still names the old address
+		c.Set("user", &middleware.MyClaims{Email: "old@example.com", RegisteredClaims: jwt.RegisteredClaims{Subject: user.ID}})
+	}, handlers.NewUserHandler(services.NewUserService(repo)).UpdateProfile)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/users/me/profile", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := put(`{"college": "MIT"}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected the profile to update through an older token, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := put(`{"email": "elsewhere@example.com"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected an email change to be refused, got %d", w.Code)
+	}
+	if u, _ := repo.Get(user.ID); u.Email != "new@example.com" || u.College != "MIT" {
+		t.Errorf("Expected only the college to change, got %+v", u)
+	}
+}
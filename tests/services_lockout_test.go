@@ -0,0 +1,210 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"altoai_mvp/internal/handlers"
+	"altoai_mvp/internal/models"
+	"altoai_mvp/internal/repository"
+	"altoai_mvp/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const lockoutPassword = "correct horse"
+
+// newLockoutTestService returns an auth service with one verified password account
+func newLockoutTestService(t *testing.T) (services.AuthService, repository.UserRepo, models.User) {
+	t.Helper()
+	repo := repository.NewUserMemoryRepo()
+	hash, _ := bcrypt.GenerateFromPassword([]byte(lockoutPassword), bcrypt.MinCost)
+	user, err := repo.Create("locked@example.com", "Locked", string(hash))
+	if err != nil {
+		t.Fatalf("Create user failed: %v", err)
+	}
+	repo.MarkEmailVerified(user.Email)
+	return services.NewAuthService(repo, newTestTokens(t)), repo, user
+}
+
+func clientCtx(ip string) context.Context {
+	return services.WithClientInfo(context.Background(), services.ClientInfo{IP: ip})
+}
+
+func login(svc services.AuthService, ctx context.Context, email, password string) error {
+	_, _, _, err := svc.Login(ctx, models.LoginDTO{Email: email, Password: password})
+	return err
+}
+
+func hasAuditEvent(t *testing.T, repo repository.UserRepo, typ string) bool {
+	t.Helper()
+	events, err := repo.ListAuditEvents(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range events {
+		if e.Type == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func TestLoginLocksAccountAfterRepeatedFailures(t *testing.T) {
+	svc, repo, user := newLockoutTestService(t)
+	ctx := clientCtx("198.51.100.1")
+
+	for i := 1; i <= 4; i++ {
+		if err := login(svc, ctx, user.Email, "wrong"); err == nil || errors.Is(err, services.ErrTooManyAttempts) {
+			t.Fatalf("Attempt %d: expected invalid credentials, got %v", i, err)
+		}
+	}
+	err := login(svc, ctx, user.Email, "wrong")
+	var lockout *services.LockoutError
+	if !errors.As(err, &lockout) {
+		t.Fatalf("Fifth failure: expected a lockout, got %v", err)
+	}
+	if d := time.Until(lockout.Until); d < 55*time.Second || d > time.Minute {
+		t.Errorf("Expected a one-minute first lockout, got %v", d)
+	}
+
+	// The right password doesn't help while locked, from any address
+	if err := login(svc, clientCtx("198.51.100.2"), user.Email, lockoutPassword); !errors.Is(err, services.ErrTooManyAttempts) {
+		t.Errorf("Expected the locked account to reject the correct password, got %v", err)
+	}
+	if !hasAuditEvent(t, repo, "login.account_locked") {
+		t.Error("Expected an audit event for the lockout")
+	}
+}
+
+func TestLoginLockoutsGrowExponentially(t *testing.T) {
+	svc, repo, user := newLockoutTestService(t)
+	key := "login:account:" + user.Email
+	ctx := clientCtx("198.51.100.1")
+
+	for i := 0; i < 5; i++ {
+		login(svc, ctx, user.Email, "wrong")
+	}
+	// Let the first lockout lapse; that counts a second lockout, so the next one is 4x
+	now := time.Now().UTC()
+	if _, err := repo.LockAttempts(key, now, now.Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	var err error
+	for i := 0; i < 5; i++ {
+		err = login(svc, ctx, user.Email, "wrong")
+	}
+	var lockout *services.LockoutError
+	if !errors.As(err, &lockout) {
+		t.Fatalf("Expected a second lockout, got %v", err)
+	}
+	if d := time.Until(lockout.Until); d < 235*time.Second || d > 4*time.Minute {
+		t.Errorf("Expected a four-minute lockout, got %v", d)
+	}
+}
+
+func TestSuccessfulLoginClearsAccountFailures(t *testing.T) {
+	svc, _, user := newLockoutTestService(t)
+	ctx := clientCtx("198.51.100.1")
+
+	for round := 0; round < 2; round++ {
+		for i := 0; i < 4; i++ {
+			login(svc, ctx, user.Email, "wrong")
+		}
+		if err := login(svc, ctx, user.Email, lockoutPassword); err != nil {
+			t.Fatalf("Round %d: expected the correct password to log in, got %v", round, err)
+		}
+	}
+}
+
+func TestLoginLocksIPAcrossAccounts(t *testing.T) {
+	svc, repo, user := newLockoutTestService(t)
+	ctx := clientCtx("203.0.113.50")
+
+	// Spraying one password across many accounts trips the per-IP limit
+	for i := 0; i < 20; i++ {
+		login(svc, ctx, fmt.Sprintf("victim%d@example.com", i), "password1")
+	}
+	if err := login(svc, ctx, user.Email, lockoutPassword); !errors.Is(err, services.ErrTooManyAttempts) {
+		t.Errorf("Expected the IP to be locked, got %v", err)
+	}
+	if err := login(svc, clientCtx("203.0.113.51"), user.Email, lockoutPassword); err != nil {
+		t.Errorf("Expected other addresses to be unaffected, got %v", err)
+	}
+	if !hasAuditEvent(t, repo, "login.ip_locked") {
+		t.Error("Expected an audit event for the IP lockout")
+	}
+}
+
+func TestVerificationCodeInvalidatedAfterWrongGuesses(t *testing.T) {
+	svc, repo, _ := newLockoutTestService(t)
+	user, _ := repo.Create("unverified@example.com", "New", "")
+	repo.SetOneTimeCode(user.ID, models.CodePurposeVerifyEmail, "123456", time.Now().UTC().Add(15*time.Minute))
+	ctx := clientCtx("198.51.100.9")
+
+	var err error
+	for i := 0; i < 5; i++ {
+		_, _, _, err = svc.VerifyEmail(ctx, models.VerifyEmailDTO{Email: user.Email, Code: fmt.Sprintf("00000%d", i)})
+	}
+	if !errors.Is(err, services.ErrTooManyAttempts) {
+		t.Fatalf("Expected the fifth wrong code to lock verification, got %v", err)
+	}
+
+	// Even once the lockout lapses, the guessed-at code is gone
+	now := time.Now().UTC()
+	repo.LockAttempts("verify_email:account:"+user.Email, now, now.Add(-time.Second))
+	_, _, _, err = svc.VerifyEmail(ctx, models.VerifyEmailDTO{Email: user.Email, Code: "123456"})
+	if !errors.Is(err, repository.ErrInvalidVerificationCode) {
+		t.Errorf("Expected the code to be invalidated, got %v", err)
+	}
+	if !hasAuditEvent(t, repo, "verify_email.code_invalidated") {
+		t.Error("Expected an audit event for the invalidated code")
+	}
+}
+
+func TestResetCodeLocksAfterWrongGuesses(t *testing.T) {
+	svc, repo, user := newLockoutTestService(t)
+	repo.SetOneTimeCode(user.ID, models.CodePurposeResetPassword, "654321", time.Now().UTC().Add(15*time.Minute))
+	ctx := clientCtx("198.51.100.9")
+
+	var err error
+	for i := 0; i < 5; i++ {
+		err = svc.ResetPassword(ctx, models.ResetPasswordDTO{Email: user.Email, Code: "111111", Password: "new password"})
+	}
+	if !errors.Is(err, services.ErrTooManyAttempts) {
+		t.Fatalf("Expected the fifth wrong code to lock password reset, got %v", err)
+	}
+	err = svc.ResetPassword(ctx, models.ResetPasswordDTO{Email: user.Email, Code: "654321", Password: "new password"})
+	if !errors.Is(err, services.ErrTooManyAttempts) {
+		t.Errorf("Expected the correct code to be refused while locked, got %v", err)
+	}
+}
+
+func TestLoginHandlerReturnsRetryAfter(t *testing.T) {
+	svc, _, user := newLockoutTestService(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/auth/login", handlers.NewAuthHandler(svc).Login)
+
+	var w *httptest.ResponseRecorder
+	for i := 0; i < 6; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email":"`+user.Email+`","password":"wrong"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+	}
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 once locked, got %d: %s", w.Code, w.Body.String())
+	}
+	if secs, err := strconv.Atoi(w.Header().Get("Retry-After")); err != nil || secs < 1 || secs > 60 {
+		t.Errorf("Expected Retry-After within the lockout, got %q", w.Header().Get("Retry-After"))
+	}
+}
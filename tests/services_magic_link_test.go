@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"altoai_mvp/internal/handlers"
+	"altoai_mvp/internal/models"
+	"altoai_mvp/internal/repository"
+	"altoai_mvp/internal/services"
+	"altoai_mvp/internal/token"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newMagicLinkTestService(t *testing.T) (services.AuthService, repository.UserRepo, *recordingEmail, *token.Service) {
+	t.Helper()
+	repo := repository.NewUserMemoryRepo()
+	tokens := newTestTokens(t)
+	email := &recordingEmail{}
+	return services.NewAuthServiceWithEmail(repo, tokens, email), repo, email, tokens
+}
+
+// linkToken pulls the signed token out of the last link emailed to addr
+func linkToken(t *testing.T, email *recordingEmail, addr string) string {
+	t.Helper()
+	link, err := url.Parse(email.code(addr))
+	if err != nil || link.Query().Get("token") == "" {
+		t.Fatalf("Expected a sign-in link for %s, got %q", addr, email.code(addr))
+	}
+	return link.Query().Get("token")
+}
+
+func TestMagicLinkLogsInOnce(t *testing.T) {
+	svc, repo, email, tokens := newMagicLinkTestService(t)
+	user, _ := repo.Create("phone@example.com", "Phone", "")
+
+	if err := svc.RequestMagicLink(clientCtx("198.51.100.1"), models.MagicLinkDTO{Email: user.Email}); err != nil {
+		t.Fatalf("RequestMagicLink failed: %v", err)
+	}
+	link := linkToken(t, email, user.Email)
+
+	access, refresh, got, err := svc.LoginWithMagicLink(clientCtx("198.51.100.1"), models.MagicLinkLoginDTO{Token: link})
+	if err != nil {
+		t.Fatalf("Expected the link to log in, got %v", err)
+	}
+	if access == "" || refresh == "" || got.ID != user.ID {
+		t.Fatalf("Expected tokens for %s, got %q, %q, %+v", user.ID, access, refresh, got)
+	}
+	if claims, err := tokens.ParseAccess(access); err != nil || claims.Subject != user.ID {
+		t.Errorf("Expected a valid access token, got %v", err)
+	}
+	if u, _ := repo.Get(user.ID); !u.EmailVerified {
+		t.Error("Expected following the link to verify the email")
+	}
+
+	if _, _, _, err := svc.LoginWithMagicLink(clientCtx("198.51.100.1"), models.MagicLinkLoginDTO{Token: link}); !errors.Is(err, services.ErrMagicLinkInvalid) {
+		t.Errorf("Expected a used link to be rejected, got %v", err)
+	}
+}
+
+func TestMagicLinkDropsWhatAnUnverifiedRegistrantSet(t *testing.T) {
+	svc, repo, email, _ := newMagicLinkTestService(t)
+	// Someone registered the address first without being able to verify it
+	squatter, _ := repo.Create("owner@example.com", "Squatter", "squatter-hash")
+	repo.SetOneTimeCode(squatter.ID, models.CodePurposeVerifyEmail, "123456", time.Now().Add(time.Hour))
+	repo.CreateRefreshToken(models.RefreshToken{ID: "squatter-token", UserID: squatter.ID, FamilyID: "squatter-family", TokenHash: "squatter-hash", ExpiresAt: time.Now().Add(time.Hour)})
+
+	if err := svc.RequestMagicLink(clientCtx(""), models.MagicLinkDTO{Email: squatter.Email}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := svc.LoginWithMagicLink(clientCtx(""), models.MagicLinkLoginDTO{Token: linkToken(t, email, squatter.Email)}); err != nil {
+		t.Fatalf("Expected the owner to log in, got %v", err)
+	}
+
+	u, _ := repo.Get(squatter.ID)
+	if !u.EmailVerified || u.Password != "" || u.TokenVersion == squatter.TokenVersion {
+		t.Errorf("Expected a verified email, no password and a new token version, got %+v", u)
+	}
+	if rt, _ := repo.GetRefreshToken("squatter-hash"); rt.RevokedAt == nil {
+		t.Error("Expected the registrant's refresh token to be revoked")
+	}
+	if err := repo.VerifyEmail(squatter.Email, "123456"); err == nil {
+		t.Error("Expected the registrant's verification code to be gone")
+	}
+}
+
+func TestMagicLinkReplacedByNewerLink(t *testing.T) {
+	svc, repo, email, _ := newMagicLinkTestService(t)
+	user, _ := repo.Create("phone@example.com", "Phone", "")
+
+	svc.RequestMagicLink(clientCtx(""), models.MagicLinkDTO{Email: user.Email})
+	first := linkToken(t, email, user.Email)
+	svc.RequestMagicLink(clientCtx(""), models.MagicLinkDTO{Email: user.Email})
+	second := linkToken(t, email, user.Email)
+
+	if _, _, _, err := svc.LoginWithMagicLink(clientCtx(""), models.MagicLinkLoginDTO{Token: first}); !errors.Is(err, services.ErrMagicLinkInvalid) {
+		t.Errorf("Expected the older link to stop working, got %v", err)
+	}
+	if _, _, _, err := svc.LoginWithMagicLink(clientCtx(""), models.MagicLinkLoginDTO{Token: second}); err != nil {
+		t.Errorf("Expected the newest link to work, got %v", err)
+	}
+}
+
+func TestMagicLinkRejectsForgedAndOtherTokens(t *testing.T) {
+	svc, repo, _, tokens := newMagicLinkTestService(t)
+	user, _ := repo.Create("phone@example.com", "Phone", "")
+
+	// A well-signed link whose nonce was never issued
+	forged, _, _ := tokens.IssueMagicLink(token.Claims{
+		Email:            user.Email,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: user.ID, ID: "guessed"},
+	}, time.Minute)
+	// An access token can't stand in for a link
+	access, _, _ := tokens.IssueAccess(token.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: user.ID}})
+
+	for name, tok := range map[string]string{"unissued nonce": forged, "access token": access, "garbage": "not-a-token"} {
+		if _, _, _, err := svc.LoginWithMagicLink(clientCtx(""), models.MagicLinkLoginDTO{Token: tok}); !errors.Is(err, services.ErrMagicLinkInvalid) {
+			t.Errorf("%s: expected ErrMagicLinkInvalid, got %v", name, err)
+		}
+	}
+}
+
+func TestMagicLinkDoesNotRevealAccounts(t *testing.T) {
+	svc, repo, email, _ := newMagicLinkTestService(t)
+	repo.Create("phone@example.com", "Phone", "")
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/auth/magic-link", handlers.NewAuthHandler(svc).RequestMagicLink)
+
+	bodies := map[string]string{}
+	for _, addr := range []string{"phone@example.com", "nobody@example.com"} {
+		req := httptest.NewRequest(http.MethodPost, "/auth/magic-link", strings.NewReader(`{"email":"`+addr+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", addr, w.Code)
+		}
+		bodies[addr] = w.Body.String()
+	}
+	if bodies["phone@example.com"] != bodies["nobody@example.com"] {
+		t.Errorf("Expected identical responses, got %q and %q", bodies["phone@example.com"], bodies["nobody@example.com"])
+	}
+	if email.code("nobody@example.com") != "" {
+		t.Error("Expected no email for an unknown address")
+	}
+}
@@ -0,0 +1,193 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"altoai_mvp/internal/handlers"
+	"altoai_mvp/internal/models"
+	"altoai_mvp/internal/services"
+	"altoai_mvp/internal/totp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// totpCode is the authenticator app's code offset steps from now
+func totpCode(t *testing.T, secret string, offset int64) string {
+	t.Helper()
+	code, err := totp.Code(secret, totp.Step(time.Now())+offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+// enrollMFA turns MFA on for user and returns the secret and recovery codes
+func enrollMFA(t *testing.T, svc services.AuthService, user models.User) (string, []string) {
+	t.Helper()
+	enrollment, err := svc.BeginTOTPEnrollment(clientCtx(""), user.ID)
+	if err != nil {
+		t.Fatalf("BeginTOTPEnrollment failed: %v", err)
+	}
+	codes, err := svc.ConfirmTOTPEnrollment(clientCtx(""), user.ID, totpCode(t, enrollment.Secret, 0))
+	if err != nil {
+		t.Fatalf("ConfirmTOTPEnrollment failed: %v", err)
+	}
+	return enrollment.Secret, codes
+}
+
+// loginChallenge logs in with the right password and returns the MFA challenge
+func loginChallenge(t *testing.T, svc services.AuthService, user models.User) *services.MFAChallengeError {
+	t.Helper()
+	err := login(svc, clientCtx("198.51.100.1"), user.Email, lockoutPassword)
+	var challenge *services.MFAChallengeError
+	if !errors.As(err, &challenge) {
+		t.Fatalf("Expected an MFA challenge, got %v", err)
+	}
+	return challenge
+}
+
+func TestMFALoginNeedsSecondFactor(t *testing.T) {
+	svc, _, user := newLockoutTestService(t)
+	secret, recovery := enrollMFA(t, svc, user)
+	if len(recovery) != 10 {
+		t.Fatalf("Expected 10 recovery codes, got %d", len(recovery))
+	}
+
+	challenge := loginChallenge(t, svc, user)
+	if challenge.SetupRequired {
+		t.Error("Expected an enrolled user not to be asked to set up MFA")
+	}
+	if _, _, _, err := svc.VerifyMFA(clientCtx(""), models.MFAChallengeDTO{MFAToken: challenge.Token, Code: "000000"}); !errors.Is(err, services.ErrInvalidMFACode) {
+		t.Errorf("Expected a wrong code to be rejected, got %v", err)
+	}
+
+	// The enrolment used the current step, so the next one is the first fresh code
+	code := totpCode(t, secret, 1)
+	access, _, got, err := svc.VerifyMFA(clientCtx(""), models.MFAChallengeDTO{MFAToken: challenge.Token, Code: code})
+	if err != nil || access == "" || got.ID != user.ID {
+		t.Fatalf("Expected the TOTP code to complete the login, got %v", err)
+	}
+	if _, _, _, err := svc.VerifyMFA(clientCtx(""), models.MFAChallengeDTO{MFAToken: challenge.Token, Code: code}); !errors.Is(err, services.ErrInvalidMFACode) {
+		t.Errorf("Expected a replayed code to be rejected, got %v", err)
+	}
+}
+
+func TestMFARecoveryCodesWorkOnce(t *testing.T) {
+	svc, _, user := newLockoutTestService(t)
+	_, recovery := enrollMFA(t, svc, user)
+	challenge := loginChallenge(t, svc, user)
+
+	// Recovery codes can be typed in any case, without dashes
+	typed := strings.ToUpper(strings.ReplaceAll(recovery[0], "-", ""))
+	if _, _, _, err := svc.VerifyMFA(clientCtx(""), models.MFAChallengeDTO{MFAToken: challenge.Token, Code: typed}); err != nil {
+		t.Fatalf("Expected the recovery code to complete the login, got %v", err)
+	}
+	if _, _, _, err := svc.VerifyMFA(clientCtx(""), models.MFAChallengeDTO{MFAToken: challenge.Token, Code: recovery[0]}); !errors.Is(err, services.ErrInvalidMFACode) {
+		t.Errorf("Expected a used recovery code to be rejected, got %v", err)
+	}
+	if status, _ := svc.MFAStatus(clientCtx(""), user.ID); !status.Enabled || status.RecoveryCodesLeft != 9 {
+		t.Errorf("Expected MFA on with 9 recovery codes left, got %+v", status)
+	}
+}
+
+func TestMFAWrongCodesLockOut(t *testing.T) {
+	svc, _, user := newLockoutTestService(t)
+	enrollMFA(t, svc, user)
+	challenge := loginChallenge(t, svc, user)
+
+	var err error
+	for i := 0; i < 5; i++ {
+		_, _, _, err = svc.VerifyMFA(clientCtx("198.51.100.1"), models.MFAChallengeDTO{MFAToken: challenge.Token, Code: "000000"})
+	}
+	if !errors.Is(err, services.ErrTooManyAttempts) {
+		t.Errorf("Expected the fifth wrong code to lock MFA, got %v", err)
+	}
+}
+
+func TestMFARequiredByRole(t *testing.T) {
+	svc, repo, user := newLockoutTestService(t)
+	userSvc := services.NewUserService(repo)
+	user, _ = repo.SetRole(user.ID, models.RoleCounsellor)
+
+	if err := login(svc, clientCtx(""), user.Email, lockoutPassword); err != nil {
+		t.Fatalf("Expected a plain login before MFA is required, got %v", err)
+	}
+	if _, err := userSvc.SetMFAPolicy(clientCtx(""), []models.Role{models.RoleCounsellor, models.RoleAdmin, models.RoleCounsellor}); err != nil {
+		t.Fatal(err)
+	}
+	if roles, _ := userSvc.MFAPolicy(clientCtx("")); len(roles) != 2 {
+		t.Errorf("Expected duplicate roles to be dropped, got %v", roles)
+	}
+
+	challenge := loginChallenge(t, svc, user)
+	if !challenge.SetupRequired {
+		t.Fatal("Expected the counsellor to be asked to set up MFA")
+	}
+	if _, _, _, err := svc.VerifyMFA(clientCtx(""), models.MFAChallengeDTO{MFAToken: challenge.Token, Code: "000000"}); !errors.Is(err, services.ErrMFAChallengeInvalid) {
+		t.Errorf("Expected no way past the challenge before setup, got %v", err)
+	}
+
+	// Setup goes through the challenge token
+	challenged, err := svc.MFAChallengeUser(clientCtx(""), challenge.Token)
+	if err != nil || challenged.ID != user.ID {
+		t.Fatalf("Expected the challenge to name the user, got %v", err)
+	}
+	secret, _ := enrollMFA(t, svc, challenged)
+
+	if _, err := svc.BeginTOTPEnrollment(clientCtx(""), user.ID); !errors.Is(err, services.ErrMFAAlreadyEnabled) {
+		t.Errorf("Expected a second enrolment to be refused, got %v", err)
+	}
+	if err := svc.DisableMFA(clientCtx(""), user.ID, totpCode(t, secret, 1)); !errors.Is(err, services.ErrMFARequiredByRole) {
+		t.Errorf("Expected the role to keep MFA on, got %v", err)
+	}
+}
+
+func TestMFADisable(t *testing.T) {
+	svc, _, user := newLockoutTestService(t)
+	secret, _ := enrollMFA(t, svc, user)
+
+	if err := svc.DisableMFA(clientCtx(""), user.ID, "000000"); !errors.Is(err, services.ErrInvalidMFACode) {
+		t.Errorf("Expected a wrong code to keep MFA on, got %v", err)
+	}
+	if err := svc.DisableMFA(clientCtx(""), user.ID, totpCode(t, secret, 1)); err != nil {
+		t.Fatalf("DisableMFA failed: %v", err)
+	}
+	if err := login(svc, clientCtx(""), user.Email, lockoutPassword); err != nil {
+		t.Errorf("Expected a plain login once MFA is off, got %v", err)
+	}
+}
+
+func TestLoginHandlerReturnsMFAChallenge(t *testing.T) {
+	svc, _, user := newLockoutTestService(t)
+	enrollMFA(t, svc, user)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/auth/login", handlers.NewAuthHandler(svc).Login)
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email":"`+user.Email+`","password":"`+lockoutPassword+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var body struct {
+		Data map[string]any `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if w.Code != http.StatusOK || body.Data["mfa_required"] != true || body.Data["mfa_token"] == "" {
+		t.Fatalf("Expected an MFA challenge, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, ok := body.Data["access_token"]; ok {
+		t.Error("Expected no access token before the second factor")
+	}
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "refresh_token" {
+			t.Error("Expected no refresh cookie before the second factor")
+		}
+	}
+}
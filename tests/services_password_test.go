@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"altoai_mvp/internal/handlers"
+	"altoai_mvp/internal/middleware"
+	"altoai_mvp/internal/models"
+	"altoai_mvp/internal/repository"
+	"altoai_mvp/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const strongPassword = "Tr1cky-Pangolin"
+
+func TestPasswordPolicy(t *testing.T) {
+	policy := services.PasswordPolicy{MinLength: 10, MinClasses: 2}
+	cases := map[string]bool{
+		strongPassword:           true,
+		"correct horse":          true,
+		"short1A":                false, // too short
+		"alllowercaseletters":    false, // one class
+		"qwertyuiop":             false, // common
+		"Password2024!":          false, // common with a suffix
+		"Monkey!!!!99":           false,
+		"ada lovelace":           false, // the name
+		"ada.lovelace@uni.edu":   false, // the email
+		"ADA.LOVELACE":           false, // the email's local part
+		strings.Repeat("aB", 40): false, // longer than bcrypt reads
+	}
+	for password, ok := range cases {
+		err := policy.Check(password, "ada.lovelace@uni.edu", "Ada Lovelace")
+		if ok && err != nil {
+			t.Errorf("%q: expected to pass, got %v", password, err)
+		}
+		if !ok && !errors.Is(err, services.ErrWeakPassword) {
+			t.Errorf("%q: expected ErrWeakPassword, got %v", password, err)
+		}
+	}
+
+	var policyErr *services.PasswordPolicyError
+	if err := policy.Check("monkey", "", ""); !errors.As(err, &policyErr) || len(policyErr.Problems) != 3 {
+		t.Errorf("Expected every problem to be listed, got %v", err)
+	}
+}
+
+func TestPasswordPolicyFromEnv(t *testing.T) {
+	t.Setenv("PASSWORD_MIN_LENGTH", "14")
+	t.Setenv("PASSWORD_MIN_CLASSES", "3")
+	policy, err := services.PasswordPolicyFromEnv()
+	if err != nil || policy.MinLength != 14 || policy.MinClasses != 3 {
+		t.Fatalf("Expected 14 characters and 3 classes, got %+v, %v", policy, err)
+	}
+
+	t.Setenv("PASSWORD_MIN_CLASSES", "5")
+	if _, err := services.PasswordPolicyFromEnv(); err == nil {
+		t.Error("Expected 5 character classes to be rejected")
+	}
+}
+
+func TestRegisterEnforcesPasswordPolicy(t *testing.T) {
+	repo := repository.NewUserMemoryRepo()
+	svc := services.NewAuthServiceWithEmail(repo, newTestTokens(t), &recordingEmail{})
+
+	err := svc.Register(clientCtx(""), models.CreateUserDTO{Email: "new@example.com", Name: "New", Password: "password1"})
+	if !errors.Is(err, services.ErrWeakPassword) {
+		t.Fatalf("Expected a weak password to be refused, got %v", err)
+	}
+	if _, err := repo.GetByEmail("new@example.com"); err != repository.ErrNotFound {
+		t.Error("Expected no account to be created")
+	}
+	if err := svc.Register(clientCtx(""), models.CreateUserDTO{Email: "new@example.com", Name: "New", Password: strongPassword}); err != nil {
+		t.Errorf("Expected a strong password to register, got %v", err)
+	}
+}
+
+func TestChangePasswordSignsOutOtherDevices(t *testing.T) {
+	svc, repo, user := newLockoutTestService(t)
+	_, otherDevice, _, err := svc.Login(clientCtx(""), models.LoginDTO{Email: user.Email, Password: lockoutPassword})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dto := models.ChangePasswordDTO{CurrentPassword: "wrong", NewPassword: strongPassword}
+	if _, _, _, err := svc.ChangePassword(clientCtx(""), user.ID, dto); !errors.Is(err, services.ErrWrongPassword) {
+		t.Fatalf("Expected the wrong current password to be refused, got %v", err)
+	}
+	dto = models.ChangePasswordDTO{CurrentPassword: lockoutPassword, NewPassword: lockoutPassword}
+	if _, _, _, err := svc.ChangePassword(clientCtx(""), user.ID, dto); !errors.Is(err, services.ErrSamePassword) {
+		t.Errorf("Expected the same password to be refused, got %v", err)
+	}
+
+	dto = models.ChangePasswordDTO{CurrentPassword: lockoutPassword, NewPassword: strongPassword}
+	access, refresh, _, err := svc.ChangePassword(clientCtx(""), user.ID, dto)
+	if err != nil || access == "" || refresh == "" {
+		t.Fatalf("Expected new tokens, got %v", err)
+	}
+	if _, _, err := svc.RefreshToken(clientCtx(""), otherDevice); err == nil {
+		t.Error("Expected the other device to be signed out")
+	}
+	if _, _, err := svc.RefreshToken(clientCtx(""), refresh); err != nil {
+		t.Errorf("Expected this device to stay signed in, got %v", err)
+	}
+	if err := login(svc, clientCtx(""), user.Email, strongPassword); err != nil {
+		t.Errorf("Expected the new password to log in, got %v", err)
+	}
+	if !hasAuditEvent(t, repo, "password.changed") {
+		t.Error("Expected a password.changed audit event")
+	}
+}
+
+func TestChangePasswordLetsGoogleUsersSetOne(t *testing.T) {
+	svc, repo, _ := newLockoutTestService(t)
+	user, _ := repo.Create("google@example.com", "Google User", "")
+	repo.MarkEmailVerified(user.Email)
+
+	if _, _, _, err := svc.ChangePassword(clientCtx(""), user.ID, models.ChangePasswordDTO{NewPassword: strongPassword}); err != nil {
+		t.Fatalf("Expected a password to be set without a current one, got %v", err)
+	}
+	if err := login(svc, clientCtx(""), user.Email, strongPassword); err != nil {
+		t.Errorf("Expected the new password to log in, got %v", err)
+	}
+	if !hasAuditEvent(t, repo, "password.set") {
+		t.Error("Expected a password.set audit event")
+	}
+}
+
+func TestChangePasswordHandlerReportsPolicy(t *testing.T) {
+	svc, _, user := newLockoutTestService(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/auth/change-password", func(c *gin.Context) {
+		c.Set("user", &middleware.MyClaims{RegisteredClaims: jwt.RegisteredClaims{Subject: user.ID}})
+	}, handlers.NewAuthHandler(svc).ChangePassword)
+
+	body := `{"current_password":"` + lockoutPassword + `","new_password":"letmein"}`
+	req := httptest.NewRequest(http.MethodPost, "/auth/change-password", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var resp struct {
+		Details map[string]string `json:"details"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusBadRequest || resp.Details["new_password"] == "" {
+		t.Fatalf("Expected a validation error on new_password, got %d: %s", w.Code, w.Body.String())
+	}
+}
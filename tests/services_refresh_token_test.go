@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"altoai_mvp/internal/models"
+	"altoai_mvp/internal/repository"
+	"altoai_mvp/internal/services"
+)
+
+func newRefreshTestService(t *testing.T) (services.AuthService, repository.UserRepo, models.User) {
+	t.Helper()
+	repo := repository.NewUserMemoryRepo()
+	user, err := repo.Create("rotate@example.com", "Rotate", "")
+	if err != nil {
+		t.Fatalf("Create user failed: %v", err)
+	}
+	return services.NewAuthService(repo, newTestTokens(t)), repo, user
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	svc, repo, user := newRefreshTestService(t)
+	ctx := services.WithClientInfo(context.Background(), services.ClientInfo{
+		UserAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 Chrome/120.0 Safari/537.36",
+		IP:        "203.0.113.7",
+	})
+
+	_, first, err := svc.IssueTokens(ctx, user, "")
+	if err != nil {
+		t.Fatalf("IssueTokens failed: %v", err)
+	}
+
+	_, second, err := svc.RefreshToken(ctx, first)
+	if err != nil {
+		t.Fatalf("RefreshToken failed: %v", err)
+	}
+	if second == first {
+		t.Fatal("Refresh should issue a new refresh token")
+	}
+	if _, third, err := svc.RefreshToken(ctx, second); err != nil || third == "" {
+		t.Fatalf("Rotated token should refresh, got %v", err)
+	}
+
+	// Stored records are keyed by hash, never by the token itself
+	if _, err := repo.GetRefreshToken(first); err == nil {
+		t.Error("Refresh tokens must not be stored in plain text")
+	}
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	svc, _, user := newRefreshTestService(t)
+	ctx := context.Background()
+
+	_, stolen, _ := svc.IssueTokens(ctx, user, "")
+	_, current, err := svc.RefreshToken(ctx, stolen)
+	if err != nil {
+		t.Fatalf("RefreshToken failed: %v", err)
+	}
+
+	// The attacker replays the rotated token
+	if _, _, err := svc.RefreshToken(ctx, stolen); err != services.ErrRefreshTokenReused {
+		t.Fatalf("Expected ErrRefreshTokenReused, got %v", err)
+	}
+	// ...which signs out the legitimate holder too
+	if _, _, err := svc.RefreshToken(ctx, current); err == nil {
+		t.Error("Tokens in a revoked family must not refresh")
+	}
+}
+
+func TestLogoutRevokesOnlyItsFamily(t *testing.T) {
+	svc, _, user := newRefreshTestService(t)
+	ctx := context.Background()
+
+	_, laptop, _ := svc.IssueTokens(ctx, user, "")
+	_, phone, _ := svc.IssueTokens(ctx, user, "")
+
+	if err := svc.Logout(ctx, laptop); err != nil {
+		t.Fatalf("Logout failed: %v", err)
+	}
+	if _, _, err := svc.RefreshToken(ctx, laptop); err == nil {
+		t.Error("Logged-out token should not refresh")
+	}
+	if _, _, err := svc.RefreshToken(ctx, phone); err != nil {
+		t.Errorf("Other devices should stay signed in, got %v", err)
+	}
+	if err := svc.Logout(ctx, "not-a-token"); err != nil {
+		t.Errorf("Unknown tokens should be ignored on logout, got %v", err)
+	}
+}
+
+func TestDeviceLabel(t *testing.T) {
+	cases := map[string]string{
+		"": "Unknown device",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Version/17.0 Mobile/15E148 Safari/604.1": "Safari on iOS",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0 Safari/537.36 Edg/120.0":                   "Edge on Windows",
+		"Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0":                                              "Firefox on Linux",
+	}
+	for ua, want := range cases {
+		if got := services.DeviceLabel(ua); got != want {
+			t.Errorf("DeviceLabel(%q) = %q, want %q", ua, got, want)
+		}
+	}
+}
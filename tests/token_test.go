@@ -0,0 +1,301 @@
+package tests
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"altoai_mvp/internal/token"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestTokens(t *testing.T) *token.Service {
+	t.Helper()
+	tokens, err := token.New(token.Config{
+		Issuer:     "altoai_mvp",
+		Audience:   "altoai_mvp",
+		AccessTTL:  time.Minute,
+		RefreshTTL: time.Hour,
+		SigningKey: token.HMACKey("k1", []byte("test-secret")),
+	})
+	if err != nil {
+		t.Fatalf("token.New failed: %v", err)
+	}
+	return tokens
+}
+
+func TestTokenRoundTrip(t *testing.T) {
+	tokens := newTestTokens(t)
+	raw, expiresAt, err := tokens.IssueAccess(token.Claims{
+		Email:            "a@example.com",
+		Version:          3,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "user-1"},
+	})
+	if err != nil {
+		t.Fatalf("IssueAccess failed: %v", err)
+	}
+	if d := time.Until(expiresAt); d <= 0 || d > time.Minute {
+		t.Errorf("Expected the access TTL, got expiry in %v", d)
+	}
+
+	claims, err := tokens.ParseAccess(raw)
+	if err != nil {
+		t.Fatalf("ParseAccess failed: %v", err)
+	}
+	if claims.Subject != "user-1" || claims.Email != "a@example.com" || claims.Version != 3 {
+		t.Errorf("Unexpected claims: %+v", claims)
+	}
+	if _, err := tokens.ParseRefresh(raw); !errors.Is(err, token.ErrWrongType) {
+		t.Errorf("Expected an access token to be refused as a refresh token, got %v", err)
+	}
+}
+
+func TestTokenRejectsForeignTokens(t *testing.T) {
+	tokens := newTestTokens(t)
+	base := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub": "user-1", "type": "access", "iss": "altoai_mvp", "aud": "altoai_mvp",
+			"iat": time.Now().Unix(), "exp": time.Now().Add(time.Minute).Unix(),
+		}
+	}
+	sign := func(method jwt.SigningMethod, kid string, claims jwt.MapClaims) string {
+		tok := jwt.NewWithClaims(method, claims)
+		if kid != "" {
+			tok.Header["kid"] = kid
+		}
+		raw, err := tok.SignedString([]byte("test-secret"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+
+	if _, err := tokens.ParseAccess(sign(jwt.SigningMethodHS256, "k1", base())); err != nil {
+		t.Fatalf("Expected a well-formed token to pass, got %v", err)
+	}
+
+	wrongIssuer := base()
+	wrongIssuer["iss"] = "someone-else"
+	wrongAudience := base()
+	wrongAudience["aud"] = "another-api"
+	noExpiry := base()
+	delete(noExpiry, "exp")
+	none, _ := jwt.NewWithClaims(jwt.SigningMethodNone, base()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+
+	cases := map[string]string{
+		"missing kid":     sign(jwt.SigningMethodHS256, "", base()),
+		"unknown kid":     sign(jwt.SigningMethodHS256, "k9", base()),
+		"other algorithm": sign(jwt.SigningMethodHS512, "k1", base()),
+		"alg none":        none,
+		"wrong issuer":    sign(jwt.SigningMethodHS256, "k1", wrongIssuer),
+		"wrong audience":  sign(jwt.SigningMethodHS256, "k1", wrongAudience),
+		"no expiry":       sign(jwt.SigningMethodHS256, "k1", noExpiry),
+	}
+	for name, raw := range cases {
+		if _, err := tokens.ParseAccess(raw); !errors.Is(err, token.ErrInvalidToken) {
+			t.Errorf("%s: expected ErrInvalidToken, got %v", name, err)
+		}
+	}
+}
+
+func TestTokenKeyRotation(t *testing.T) {
+	old := newTestTokens(t)
+	raw, _, err := old.IssueRefresh(token.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "user-1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := token.Config{
+		Issuer:           "altoai_mvp",
+		Audience:         "altoai_mvp",
+		AccessTTL:        time.Minute,
+		RefreshTTL:       time.Hour,
+		SigningKey:       token.HMACKey("k2", []byte("new-secret")),
+		VerificationKeys: []token.Key{token.HMACKey("k1", []byte("test-secret"))},
+	}
+	rotated, err := token.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rotated.ParseRefresh(raw); err != nil {
+		t.Errorf("Expected tokens signed by the previous key to stay valid, got %v", err)
+	}
+	fresh, _, _ := rotated.IssueRefresh(token.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "user-1"}})
+	if _, err := old.ParseRefresh(fresh); err == nil {
+		t.Error("Expected the old service not to know the new key")
+	}
+
+	cfg.VerificationKeys = nil
+	retired, _ := token.New(cfg)
+	if _, err := retired.ParseRefresh(raw); err == nil {
+		t.Error("Expected tokens from a retired key to be rejected")
+	}
+}
+
+func TestTokenFromEnv(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("ACCESS_TOKEN_EXPIRY", "90s")
+	t.Setenv("JWT_PREVIOUS_KEYS", "old:old-secret")
+	tokens, err := token.FromEnv()
+	if err != nil {
+		t.Fatalf("FromEnv failed: %v", err)
+	}
+	_, expiresAt, _ := tokens.IssueAccess(token.Claims{})
+	if d := time.Until(expiresAt); d <= time.Minute || d > 90*time.Second {
+		t.Errorf("Expected a 90s access token, expires in %v", d)
+	}
+
+	t.Setenv("ACCESS_TOKEN_EXPIRY", "30d")
+	if _, err := token.FromEnv(); err == nil {
+		t.Error("Expected an invalid duration to be reported")
+	}
+	t.Setenv("ACCESS_TOKEN_EXPIRY", "")
+	t.Setenv("JWT_PREVIOUS_KEYS", "no-separator")
+	if _, err := token.FromEnv(); err == nil {
+		t.Error("Expected a malformed JWT_PREVIOUS_KEYS to be reported")
+	}
+}
+
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTokenRS256WithPublishedJWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("JWT_SIGNING_KEY_FILE", writePEM(t, "PRIVATE KEY", der))
+
+	tokens, err := token.FromEnv()
+	if err != nil {
+		t.Fatalf("FromEnv failed: %v", err)
+	}
+	raw, _, err := tokens.IssueAccess(token.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "user-1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tokens.ParseAccess(raw); err != nil {
+		t.Fatalf("ParseAccess failed: %v", err)
+	}
+
+	set := tokens.JWKS()
+	if len(set.Keys) != 1 || set.Keys[0].Kty != "RSA" || set.Keys[0].Alg != "RS256" {
+		t.Fatalf("Expected one RS256 key in the JWKS, got %+v", set.Keys)
+	}
+	if set.Keys[0].Kid == "" || set.Keys[0].Kid == "default" {
+		t.Errorf("Expected the kid to default to the key thumbprint, got %q", set.Keys[0].Kid)
+	}
+
+	// Another service verifies with nothing but the published key
+	jwk := set.Keys[0]
+	n, _ := base64.RawURLEncoding.DecodeString(jwk.N)
+	e, _ := base64.RawURLEncoding.DecodeString(jwk.E)
+	pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	parsed, err := jwt.Parse(raw, func(t *jwt.Token) (any, error) {
+		if t.Header["kid"] != jwk.Kid {
+			return nil, errors.New("kid mismatch")
+		}
+		return pub, nil
+	}, jwt.WithValidMethods([]string{"RS256"}))
+	if err != nil || !parsed.Valid {
+		t.Errorf("Expected the token to verify against the JWKS, got %v", err)
+	}
+
+	// The shared secret no longer mints accepted tokens, and can't pass as the RSA key
+	hs := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "user-1", "type": "access", "iss": "altoai_mvp", "aud": "altoai_mvp",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	})
+	hs.Header["kid"] = "default"
+	forged, _ := hs.SignedString([]byte("test-secret"))
+	if _, err := tokens.ParseAccess(forged); err == nil {
+		t.Error("Expected HS256 tokens to be rejected after moving to RS256")
+	}
+	hs.Header["kid"] = jwk.Kid
+	confused, _ := hs.SignedString(x509.MarshalPKCS1PublicKey(&key.PublicKey))
+	if _, err := tokens.ParseAccess(confused); err == nil {
+		t.Error("Expected an HS256 token under the RSA kid to be rejected")
+	}
+
+	t.Setenv("JWT_ACCEPT_HS256", "true")
+	migrating, _ := token.FromEnv()
+	if _, err := migrating.ParseAccess(forged); err != nil {
+		t.Errorf("Expected JWT_ACCEPT_HS256 to keep old tokens valid, got %v", err)
+	}
+	if len(migrating.JWKS().Keys) != 1 {
+		t.Error("Expected the HMAC secret never to be published")
+	}
+}
+
+func TestTokenEdDSAWithVerificationOnlyKey(t *testing.T) {
+	oldPub, oldPriv, _ := ed25519.GenerateKey(rand.Reader)
+	_, newPriv, _ := ed25519.GenerateKey(rand.Reader)
+
+	oldDER, _ := x509.MarshalPKCS8PrivateKey(oldPriv)
+	oldKey, err := token.ParseKeyPEM("old", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: oldDER}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := token.New(token.Config{Issuer: "altoai_mvp", Audience: "altoai_mvp", AccessTTL: time.Minute, RefreshTTL: time.Hour, SigningKey: oldKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _, _ := old.IssueAccess(token.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "user-1"}})
+
+	newDER, _ := x509.MarshalPKCS8PrivateKey(newPriv)
+	pubDER, _ := x509.MarshalPKIXPublicKey(oldPub)
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("JWT_SIGNING_KEY_FILE", writePEM(t, "PRIVATE KEY", newDER))
+	t.Setenv("JWT_SIGNING_KEY_ID", "new")
+	t.Setenv("JWT_VERIFICATION_KEY_FILES", "old="+writePEM(t, "PUBLIC KEY", pubDER))
+	tokens, err := token.FromEnv()
+	if err != nil {
+		t.Fatalf("FromEnv failed: %v", err)
+	}
+
+	if _, err := tokens.ParseAccess(raw); err != nil {
+		t.Errorf("Expected a token from the retired key to verify, got %v", err)
+	}
+	fresh, _, _ := tokens.IssueAccess(token.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "user-1"}})
+	if _, err := tokens.ParseAccess(fresh); err != nil {
+		t.Errorf("Expected an EdDSA token to verify, got %v", err)
+	}
+	set := tokens.JWKS()
+	if len(set.Keys) != 2 || set.Keys[0].Kid != "new" || set.Keys[0].Crv != "Ed25519" || set.Keys[1].Kid != "old" {
+		t.Errorf("Expected the new and retired Ed25519 keys in the JWKS, got %+v", set.Keys)
+	}
+
+	// A public key alone can't be the signing key
+	t.Setenv("JWT_SIGNING_KEY_FILE", writePEM(t, "PUBLIC KEY", pubDER))
+	if _, err := token.FromEnv(); err == nil {
+		t.Error("Expected a public signing key file to be refused")
+	}
+}
+
+func TestTokenRejectsWeakRSAKeys(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 1024)
+	if _, err := token.ParseKeyPEM("", pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})); err == nil {
+		t.Error("Expected a 1024-bit RSA key to be refused")
+	}
+	if len(newTestTokens(t).JWKS().Keys) != 0 {
+		t.Error("Expected an HMAC-only service to publish no keys")
+	}
+}
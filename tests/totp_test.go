@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"altoai_mvp/internal/totp"
+)
+
+// rfc6238Secret is the RFC 6238 SHA-1 test key "12345678901234567890", base32 encoded
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPMatchesRFC6238Vectors(t *testing.T) {
+	// The RFC lists 8-digit codes; six-digit codes are their last six digits
+	for unix, want := range map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	} {
+		got, err := totp.Code(rfc6238Secret, totp.Step(time.Unix(unix, 0)))
+		if err != nil || got != want {
+			t.Errorf("At %d: expected %s, got %s (%v)", unix, want, got, err)
+		}
+	}
+}
+
+func TestTOTPValidateAllowsOneStepOfDrift(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := totp.Step(now)
+	for offset, ok := range map[int64]bool{-2: false, -1: true, 0: true, 1: true, 2: false} {
+		code, _ := totp.Code(rfc6238Secret, step+offset)
+		matched, valid := totp.Validate(rfc6238Secret, code, now)
+		if valid != ok || (ok && matched != step+offset) {
+			t.Errorf("Offset %d: expected valid=%v, got valid=%v at step %d", offset, ok, valid, matched)
+		}
+	}
+	if _, valid := totp.Validate(rfc6238Secret, "12345", now); valid {
+		t.Error("Expected a short code to be rejected")
+	}
+}
+
+func TestTOTPSecretAndURI(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	if err != nil || len(secret) != 32 {
+		t.Fatalf("Expected a 32-character base32 secret, got %q (%v)", secret, err)
+	}
+	if _, err := totp.Code(secret, 1); err != nil {
+		t.Errorf("Expected the generated secret to be usable, got %v", err)
+	}
+
+	uri, err := url.Parse(totp.URI("AI Interviewer", "staff@example.com", secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || !strings.HasSuffix(uri.Path, "AI Interviewer:staff@example.com") {
+		t.Errorf("Unexpected URI %s", uri)
+	}
+	if uri.Query().Get("secret") != secret || uri.Query().Get("issuer") != "AI Interviewer" {
+		t.Errorf("Expected the secret and issuer in the query, got %s", uri.RawQuery)
+	}
+}